@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+func aesGCM() {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	nonce := []byte("unique nonce")
+	for _, n := range []int{16, 24, 32} {
+		block, err := aes.NewCipher(key[:n])
+		if err != nil {
+			panic(err)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+		sealed := gcm.Seal(nil, nonce, []byte("hello, llgo"), []byte("header"))
+		fmt.Printf("AES-%d-GCM: %x\n", n*8, sealed)
+		plain, err := gcm.Open(nil, nonce, sealed, []byte("header"))
+		fmt.Printf("%q %v\n", plain, err)
+		empty := gcm.Seal(nil, nonce, nil, nil)
+		fmt.Printf("empty: %x\n", empty)
+		sealed[0] ^= 1
+		_, err = gcm.Open(nil, nonce, sealed, []byte("header"))
+		fmt.Println(err)
+	}
+	if _, err := aes.NewCipher(key[:10]); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func ecdsaCurve(name string, curve elliptic.Curve) {
+	d, _ := new(big.Int).SetString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721", 16)
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.Curve = curve
+	priv.X, priv.Y = curve.ScalarBaseMult(d.Bytes())
+	hash := sha256.Sum256([]byte("sample"))
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, hash[:])
+	fmt.Println(name, "sign:", err)
+	fmt.Println(name, "verify:", ecdsa.VerifyASN1(&priv.PublicKey, hash[:], sig))
+	hash[0] ^= 1
+	fmt.Println(name, "verify tampered:", ecdsa.VerifyASN1(&priv.PublicKey, hash[:], sig))
+	fmt.Println(name, "verify garbage:", ecdsa.VerifyASN1(&priv.PublicKey, hash[:], []byte{0x30, 0}))
+}
+
+func ecdsaDemo() {
+	ecdsaCurve("P-256", elliptic.P256())
+	ecdsaCurve("P-384", elliptic.P384())
+
+	// The parameters of P-256 under another name: a curve that is not one of
+	// the NIST curves.
+	p := *elliptic.P256().Params()
+	p.Name = "custom"
+	ecdsaCurve("custom", &p)
+
+	// A signature made by the Go implementation.
+	x, _ := new(big.Int).SetString("60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6", 16)
+	y, _ := new(big.Int).SetString("7903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299", 16)
+	sig, _ := hex.DecodeString("3046022100c7666f7de1e04219589619d88b58f401ffd7f4fe40b4d97fb48233ac70debd7e022100d81d2e2e6961a5adbd4c9a895be20a8fc0b4a57f74b03e590647465463793b54")
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	hash := sha256.Sum256([]byte("sample"))
+	fmt.Println("Go signature:", ecdsa.VerifyASN1(pub, hash[:], sig))
+	pub.Curve = &p
+	fmt.Println("Go signature, custom curve:", ecdsa.VerifyASN1(pub, hash[:], sig))
+}
+
+func ed25519Demo() {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	pub := priv.Public().(ed25519.PublicKey)
+	fmt.Printf("public key: %x\n", pub)
+	msg := []byte("hello, llgo")
+	sig := ed25519.Sign(priv, msg)
+	fmt.Printf("Ed25519: %x\n", sig)
+	fmt.Println(ed25519.Verify(pub, msg, sig), ed25519.Verify(pub, msg[1:], sig), ed25519.Verify(pub, msg, sig[1:]))
+	sig2, err := priv.Sign(nil, msg, crypto.Hash(0))
+	fmt.Println(hex.EncodeToString(sig2) == hex.EncodeToString(sig), err)
+	fmt.Println(ed25519.Sign(priv, nil)[:8])
+
+	digest := sha512.Sum512(msg)
+	opts := []*ed25519.Options{
+		{Hash: crypto.SHA512},
+		{Hash: crypto.SHA512, Context: "ph context"},
+		{Context: "ctx context"},
+	}
+	for _, opt := range opts {
+		m := msg
+		if opt.Hash == crypto.SHA512 {
+			m = digest[:]
+		}
+		sig, err := priv.Sign(nil, m, opt)
+		fmt.Printf("%d %q: %x %v\n", opt.Hash, opt.Context, sig, err)
+		fmt.Println(ed25519.VerifyWithOptions(pub, m, sig, opt))
+		fmt.Println(ed25519.VerifyWithOptions(pub, m, sig, &ed25519.Options{}) != nil)
+	}
+}
+
+func main() {
+	aesGCM()
+	ecdsaDemo()
+	ed25519Demo()
+}
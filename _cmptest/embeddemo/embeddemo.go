@@ -0,0 +1,31 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed hello.txt
+var hello string
+
+//go:embed hello.txt
+var helloBytes []byte
+
+//go:embed static
+var static embed.FS
+
+func main() {
+	fmt.Print(hello)
+	fmt.Println(len(helloBytes))
+
+	fs.WalkDir(static, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Println(path, d.IsDir())
+		return nil
+	})
+	data, err := static.ReadFile("static/css/site.css")
+	fmt.Print(string(data), err, "\n")
+}
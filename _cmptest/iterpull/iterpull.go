@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"runtime"
+	"slices"
+)
+
+func count(n int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		defer fmt.Println("count: done")
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func main() {
+	next, stop := iter.Pull(count(3))
+	for {
+		v, ok := next()
+		if !ok {
+			break
+		}
+		fmt.Println("next:", v)
+	}
+	stop()
+
+	next, stop = iter.Pull(count(10))
+	fmt.Println(next())
+	stop()
+	fmt.Println(next())
+
+	next2, stop2 := iter.Pull2(maps.All(map[string]int{"a": 1}))
+	fmt.Println(next2())
+	fmt.Println(next2())
+	stop2()
+
+	next, stop = iter.Pull(func(yield func(int) bool) {
+		yield(1)
+		panic("boom")
+	})
+	fmt.Println(next())
+	func() {
+		defer func() { fmt.Println("recovered:", recover()) }()
+		next()
+	}()
+	stop()
+
+	// runtime.Goexit in seq exits the goroutine calling next or stop
+	goexit(func(next func() (int, bool), stop func()) {
+		fmt.Println(next())
+		next()
+	}, func(yield func(int) bool) {
+		yield(1)
+		runtime.Goexit()
+	})
+	goexit(func(next func() (int, bool), stop func()) {
+		fmt.Println(next())
+		stop()
+	}, func(yield func(int) bool) {
+		defer fmt.Println("seq: deferred")
+		for i := 0; yield(i); i++ {
+		}
+		runtime.Goexit()
+	})
+
+	fmt.Println(slices.Collect(count(4)))
+}
+
+func goexit(pull func(next func() (int, bool), stop func()), seq iter.Seq[int]) {
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		defer fmt.Println("goexit: deferred")
+		next, stop := iter.Pull(seq)
+		defer stop()
+		pull(next, stop)
+		fmt.Println("goexit: not reached")
+	}()
+	<-done
+}
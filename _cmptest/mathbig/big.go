@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+)
+
+func ints() {
+	a, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	b := big.NewInt(-987654321)
+	fmt.Println(new(big.Int).Add(a, b))
+	fmt.Println(new(big.Int).Sub(a, b))
+	fmt.Println(new(big.Int).Mul(a, b))
+	q, m := new(big.Int).QuoRem(a, b, new(big.Int))
+	fmt.Println(q, m)
+	d, r := new(big.Int).DivMod(a, b, new(big.Int))
+	fmt.Println(d, r)
+	fmt.Println(new(big.Int).Exp(big.NewInt(3), big.NewInt(100), nil))
+	fmt.Println(new(big.Int).Exp(big.NewInt(3), big.NewInt(100), big.NewInt(1000000007)))
+	fmt.Println(new(big.Int).GCD(nil, nil, big.NewInt(462), big.NewInt(1071)))
+	fmt.Println(new(big.Int).ModInverse(big.NewInt(3), big.NewInt(11)))
+	fmt.Println(new(big.Int).Sqrt(a))
+	fmt.Println(new(big.Int).Lsh(a, 70), new(big.Int).Rsh(a, 30))
+	fmt.Println(new(big.Int).And(a, b), new(big.Int).Or(a, b), new(big.Int).Xor(a, b), new(big.Int).Not(b))
+	fmt.Println(a.Cmp(b), b.Sign(), a.BitLen(), a.Bit(3), a.Text(16), b.Text(36))
+	fmt.Printf("%x %X %o %b %d\n", b, a, big.NewInt(64), big.NewInt(5), a)
+	fmt.Println(big.NewInt(1000000007).ProbablyPrime(20), big.NewInt(1000000008).ProbablyPrime(20))
+	fmt.Println(new(big.Int).SetBytes([]byte{1, 2, 3, 4}), a.Bytes()[:4])
+	fmt.Println(new(big.Int).MulRange(1, 30))
+	fmt.Println(new(big.Int).Binomial(50, 25))
+}
+
+func rats() {
+	x := big.NewRat(3, 4)
+	y := big.NewRat(-5, 6)
+	fmt.Println(new(big.Rat).Add(x, y), new(big.Rat).Sub(x, y))
+	fmt.Println(new(big.Rat).Mul(x, y), new(big.Rat).Quo(x, y))
+	fmt.Println(x.Cmp(y), y.Sign(), new(big.Rat).Neg(y), new(big.Rat).Inv(y), new(big.Rat).Abs(y))
+	z, _ := new(big.Rat).SetString("22/7")
+	fmt.Println(z.FloatString(10), z.Num(), z.Denom(), z.IsInt())
+	f, exact := z.Float64()
+	fmt.Println(f, exact)
+	w, _ := new(big.Rat).SetString("1.25e-3")
+	fmt.Println(w, w.RatString())
+	fmt.Println(new(big.Rat).SetFrac(big.NewInt(10), big.NewInt(4)))
+	fmt.Println(new(big.Rat).SetFloat64(0.1))
+}
+
+func floats() {
+	x := new(big.Float).SetPrec(200).SetInt64(2)
+	s := new(big.Float).SetPrec(200).Sqrt(x)
+	fmt.Println(s.Text('g', 50))
+	fmt.Println(s.Text('e', 30), s.Prec(), s.MinPrec() > 0)
+	y, _, err := big.ParseFloat("1.5e100", 10, 100, big.ToNearestEven)
+	fmt.Println(y, err)
+	fmt.Println(new(big.Float).Add(x, y).Text('g', 20))
+	fmt.Println(new(big.Float).Sub(x, y).Text('g', 20))
+	fmt.Println(new(big.Float).Mul(s, s).Text('g', 40))
+	fmt.Println(new(big.Float).Quo(big.NewFloat(1), big.NewFloat(3)).Text('f', 20))
+	fmt.Println(x.Cmp(y), y.Sign(), y.IsInt(), new(big.Float).Neg(y).Signbit())
+	i, acc := big.NewFloat(12345.678).Int(nil)
+	fmt.Println(i, acc)
+	f, acc := s.Float64()
+	fmt.Println(f, acc)
+	mant := new(big.Float)
+	exp := big.NewFloat(40).MantExp(mant)
+	fmt.Println(mant, exp)
+	fmt.Printf("%.10f %g %e\n", s, y, big.NewFloat(0.000123))
+	r, _ := big.NewFloat(0.375).Rat(nil)
+	fmt.Println(r)
+	z := new(big.Float).SetMode(big.ToZero).SetPrec(10)
+	z.SetFloat64(1.0 / 3)
+	fmt.Println(z, z.Mode(), z.Acc())
+	inf := new(big.Float).SetInf(true)
+	fmt.Println(inf, inf.IsInf())
+}
+
+func main() {
+	ints()
+	rats()
+	floats()
+}
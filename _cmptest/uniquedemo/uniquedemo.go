@@ -0,0 +1,99 @@
+//go:build go1.24
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unique"
+	"unsafe"
+	"weak"
+)
+
+type point struct {
+	x, y int
+	name string
+}
+
+func main() {
+	a := unique.Make("hello")
+	b := unique.Make(strings.ToLower("HELLO"))
+	c := unique.Make("world")
+	fmt.Println(a == b, a == c, a.Value(), c.Value())
+
+	p := unique.Make(point{1, 2, "p"})
+	q := unique.Make(point{1, 2, "p"})
+	fmt.Println(p == q, p.Value())
+
+	fmt.Println(unique.Make(struct{}{}) == unique.Make(struct{}{}))
+
+	v := new(point)
+	v.name = "v"
+	w1, w2 := weak.Make(v), weak.Make(v)
+	fmt.Println(w1 == w2, w1.Value() == v, w1.Value().name)
+	fmt.Println(weak.Make(&v.y) == weak.Make(&v.y), weak.Make(&v.y).Value() == &v.y)
+	fmt.Println(weak.Make(&v.x) == weak.Make(&v.y), weak.Make[point](nil) == weak.Pointer[point]{})
+	fmt.Println(weak.Pointer[point]{}.Value() == nil)
+	runtime.KeepAlive(v)
+
+	runtime.GC()
+	fmt.Println(unique.Make("hello") == a)
+
+	// Objects only reachable from weak pointers, the unique values included,
+	// are reclaimed. The collector may find stale pointers to a few of them,
+	// so most of them and not all are expected to be gone.
+	ws, vs := makeGarbage()
+	clearStack()
+	runtime.GC()
+	fmt.Println(countNil(ws) > n/2, countNil(vs) > n/2)
+
+	// The entries of the reclaimed values were dropped: making them again
+	// gives new handles of equal values.
+	ok := true
+	for i := range n {
+		h := unique.Make(key(i))
+		ok = ok && h.Value() == key(i) && h == unique.Make(key(i))
+	}
+	fmt.Println(ok)
+}
+
+const n = 100
+
+func key(i int) string {
+	return fmt.Sprint("key-", i)
+}
+
+// makeGarbage returns weak pointers to n new objects and to the values of n
+// new unique handles, which are all unreachable.
+//
+//go:noinline
+func makeGarbage() (ws []weak.Pointer[point], vs []weak.Pointer[string]) {
+	for i := range n {
+		ws = append(ws, weak.Make(&point{i, i, key(i)}))
+		h := unique.Make(key(i))
+		// A Handle holds the pointer to the unique value.
+		vs = append(vs, weak.Make(*(**string)(unsafe.Pointer(&h))))
+	}
+	return
+}
+
+// clearStack overwrites the stack left by makeGarbage.
+//
+//go:noinline
+func clearStack() {
+	var buf [16 << 10]byte
+	for i := range buf {
+		buf[i] = 0
+	}
+	runtime.KeepAlive(&buf)
+}
+
+func countNil[T any](ws []weak.Pointer[T]) (k int) {
+	for _, w := range ws {
+		if w.Value() == nil {
+			k++
+		}
+	}
+	return
+}
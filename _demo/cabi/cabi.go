@@ -0,0 +1,77 @@
+package main
+
+import (
+	"unsafe"
+
+	"github.com/goplus/lib/c"
+)
+
+// the structs are passed and returned in registers, or by pointer to a copy,
+// as the C ABI of the target says
+type DivT struct {
+	Quot, Rem c.Int
+}
+
+type LdivT struct {
+	Quot, Rem c.Long
+}
+
+type InAddr struct {
+	SAddr uint32
+}
+
+//go:linkname div C.div
+func div(num, denom c.Int) DivT
+
+//go:linkname ldiv C.ldiv
+func ldiv(num, denom c.Long) LdivT
+
+//go:linkname inetNtoa C.inet_ntoa
+func inetNtoa(in InAddr) *c.Char
+
+type Point struct {
+	X, Y float64
+}
+
+type Rect struct {
+	Min, Max Point
+}
+
+type Triple struct {
+	A, B, C int64
+}
+
+//llgo:type C
+type Transform func(p Point, r Rect, t Triple, n c.Int) Point
+
+func transform(p Point, r Rect, t Triple, n c.Int) Point {
+	return Point{p.X*float64(n) + r.Min.X + r.Max.X, p.Y + r.Min.Y + r.Max.Y + float64(t.A+t.B+t.C)}
+}
+
+func main() {
+	q := div(17, 5)
+	c.Printf(c.Str("div(17, 5) = {%d, %d}\n"), q.Quot, q.Rem)
+	lq := ldiv(-1<<40, 7)
+	c.Printf(c.Str("ldiv(-1<<40, 7) = {%ld, %ld}\n"), lq.Quot, lq.Rem)
+	addr := inetNtoa(InAddr{0x0100007f})
+	c.Printf(c.Str("inet_ntoa = %s\n"), addr)
+	if q != (DivT{3, 2}) || lq != (LdivT{-157073089682, -2}) || c.GoString(addr) != "127.0.0.1" {
+		panic("C functions taking or returning structs failed")
+	}
+
+	// a Go function called as a C function pointer
+	fn := c.Func(transform)
+	f := *(*Transform)(unsafe.Pointer(&fn))
+	p := f(Point{1, 2}, Rect{Point{3, 4}, Point{5, 6}}, Triple{7, 8, 9}, 10)
+	c.Printf(c.Str("transform = {%g, %g}\n"), p.X, p.Y)
+	if p != (Point{18, 36}) {
+		panic("C function pointer taking or returning structs failed")
+	}
+}
+
+/* Expected output:
+div(17, 5) = {3, 2}
+ldiv(-1<<40, 7) = {-157073089682, -2}
+inet_ntoa = 127.0.0.1
+transform = {18, 36}
+*/
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/goplus/lib/py"
+	"github.com/goplus/lib/py/std"
+	"github.com/goplus/llgo/_pydemo/marshal/stat"
+)
+
+type point struct {
+	X, Y int
+}
+
+func main() {
+	fmt.Println("mean:", stat.Mean([]float64{1, 2, 3, 4, 4}))
+	fmt.Println("mode:", stat.Mode([]string{"go", "c", "go", "python"}))
+	fmt.Println("quartiles:", stat.Quantiles([]int{1, 2, 3, 4, 5, 6, 7, 8}))
+
+	std.Print(py.List(1, "two", 3.0, true, []byte("bytes"), map[string]int{"a": 1}, point{1, 2}))
+}
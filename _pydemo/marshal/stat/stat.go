@@ -0,0 +1,21 @@
+package stat
+
+import (
+	_ "unsafe"
+)
+
+const (
+	LLGoPackage = "py.statistics"
+)
+
+// Go values passed to these functions are converted to Python objects, and
+// their results back to Go values.
+
+//go:linkname Mean py.mean
+func Mean(data []float64) float64
+
+//go:linkname Mode py.mode
+func Mode(data []string) string
+
+//go:linkname Quantiles py.quantiles
+func Quantiles(data []int) []float64
@@ -35,22 +35,21 @@ func main() {
 	root := cjson.Object()
 	root.SetItem(c.Str("name"), cjson.String(pyLib))
 
-	items := cjson.Array()
 	mod := py.ImportModule(pyLib)
+	if file := mod.GetAttrString(c.Str("__file__")); file != nil {
+		root.SetItem(c.Str("file"), cjson.String(file.CStr()))
+	} else {
+		py.ErrClear()
+	}
+
+	items := cjson.Array()
 	keys := mod.ModuleGetDict().DictKeys()
 	for i, n := 0, keys.ListLen(); i < n; i++ {
 		key := keys.ListItem(i)
 		val := mod.GetAttr(key)
-		doc := val.GetAttrString(c.Str("__doc__"))
-		sym := cjson.Object()
-		sym.SetItem(c.Str("type"), cjson.String(val.Type().TypeName().CStr()))
-		sym.SetItem(c.Str("name"), cjson.String(key.CStr()))
-		if doc != nil {
-			sym.SetItem(c.Str("doc"), cjson.String(doc.CStr()))
-		}
-		if val.Callable() != 0 {
-			sig := inspect.Signature(val)
-			sym.SetItem(c.Str("sig"), cjson.String(sig.Str().CStr()))
+		sym := dumpSymbol(key, val)
+		if inspect.Isclass(val).IsTrue() != 0 {
+			sym.SetItem(c.Str("members"), dumpMembers(val))
 		}
 		items.AddItem(sym)
 	}
@@ -58,3 +57,39 @@ func main() {
 
 	c.Printf(c.Str("%s\n"), root.CStr())
 }
+
+// dumpSymbol dumps the type, name, doc and signature of val named key.
+func dumpSymbol(key, val *py.Object) *cjson.JSON {
+	doc := val.GetAttrString(c.Str("__doc__"))
+	sym := cjson.Object()
+	sym.SetItem(c.Str("type"), cjson.String(val.Type().TypeName().CStr()))
+	sym.SetItem(c.Str("name"), cjson.String(key.CStr()))
+	if doc != nil {
+		sym.SetItem(c.Str("doc"), cjson.String(doc.CStr()))
+	}
+	if val.Callable() != 0 {
+		sig := inspect.Signature(val)
+		sym.SetItem(c.Str("sig"), cjson.String(sig.Str().CStr()))
+	}
+	return sym
+}
+
+// dumpMembers dumps the public members of the class cls, inherited ones
+// included.
+func dumpMembers(cls *py.Object) *cjson.JSON {
+	items := cjson.Array()
+	members := inspect.Getmembers(cls, nil)
+	if members == nil {
+		py.ErrClear()
+		return items
+	}
+	for i, n := 0, members.ListLen(); i < n; i++ {
+		member := members.ListItem(i)
+		key := member.TupleItem(0)
+		if name := key.CStr(); c.Strlen(name) == 0 || *name == '_' {
+			continue
+		}
+		items.AddItem(dumpSymbol(key, member.TupleItem(1)))
+	}
+	return items
+}
@@ -0,0 +1,299 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"log"
+	"strings"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llgo/chore/llpyg/pysig"
+)
+
+// declClass declares the Go type of the Python class sym, a struct embedding
+// py.Object, so that the signatures of the module can refer to it.
+func (ctx *context) declClass(pkg *gogen.Package, sym *symbol) {
+	name := sym.Name
+	if len(name) == 0 || name[0] == '_' {
+		return
+	}
+	goName := genName(name, -1)
+	if pkg.Types.Scope().Lookup(goName) != nil {
+		log.Println("skip class", name+":", goName, "redeclared")
+		return
+	}
+	defs := pkg.NewTypeDefs()
+	if list := ctx.genDoc(sym.Doc); len(list) > 0 {
+		defs.SetComments(&ast.CommentGroup{List: list})
+	}
+	decl := defs.NewType(goName)
+	obj := types.NewField(token.NoPos, pkg.Types, "Object", ctx.obj, true)
+	decl.InitType(pkg, types.NewStruct([]*types.Var{obj}, nil))
+	ctx.classes[name] = decl.Type()
+}
+
+// genClass generates the class object, the constructor, the methods and the
+// properties of the Python class sym:
+//
+//	//go:linkname pyTensor py.Tensor
+//	var pyTensor *py.Object
+//
+//	func NewTensor(data []float64, kwargs *py.Object) *Tensor
+//	func (o *Tensor) Sum(kwargs *py.Object) *Tensor
+//	func (o *Tensor) Shape() *py.Object
+func (ctx *context) genClass(pkg *gogen.Package, sym *symbol) {
+	t, ok := ctx.classes[sym.Name]
+	if !ok {
+		return
+	}
+	scope := pkg.Types.Scope()
+	goName := t.Obj().Name()
+	clsName := "py" + goName
+	if scope.Lookup(clsName) != nil {
+		log.Println("skip class", sym.Name+":", clsName, "redeclared")
+		return
+	}
+	link := &ast.Comment{Text: "//go:linkname " + clsName + " py." + sym.Name}
+	pkg.NewVarDefs(scope).SetComments(&ast.CommentGroup{List: []*ast.Comment{link}}).
+		New(token.NoPos, ctx.objPtr, clsName)
+	cls := scope.Lookup(clsName)
+
+	if ctorName := "New" + goName; scope.Lookup(ctorName) == nil {
+		sig, ok := ctx.stubs[sym.Name+".__init__"]
+		if !ok {
+			sig = sym.Sig
+		}
+		doc := []*ast.Comment{{Text: "// " + ctorName + " creates an instance of the class " + sym.Name + "."}}
+		ctx.genCall(pkg, nil, ctorName, doc, sig, types.NewPointer(t), func(cb *gogen.CodeBuilder) {
+			cb.Val(cls)
+		})
+	}
+	for _, m := range sym.Members {
+		name := m.Name
+		if len(name) == 0 || name[0] == '_' {
+			continue
+		}
+		mName := genName(name, -1)
+		if hasMethod(t, mName) {
+			continue
+		}
+		sig, ok := ctx.stubs[sym.Name+"."+name]
+		if !ok {
+			sig = m.Sig
+		}
+		recv := pkg.NewParam(token.NoPos, "o", types.NewPointer(t))
+		switch m.Type {
+		case "property", "getset_descriptor", "member_descriptor", "cached_property":
+			ctx.genProperty(pkg, recv, mName, m, sig)
+		case "function", "method", "builtin_function_or_method", "method_descriptor",
+			"wrapper_descriptor", "classmethod_descriptor":
+			ctx.genCall(pkg, recv, mName, ctx.genDoc(m.Doc), sig, ctx.resultType(sig), func(cb *gogen.CodeBuilder) {
+				ctx.getAttr(cb, recv, name)
+			})
+		}
+	}
+}
+
+// genProperty generates the getter of the property m of the receiver recv.
+func (ctx *context) genProperty(pkg *gogen.Package, recv *types.Var, name string, m *symbol, sig string) {
+	ret := ctx.resultType(sig)
+	results := types.NewTuple(pkg.NewParam(token.NoPos, "", ret))
+	fn := pkg.NewFunc(recv, name, nil, results, false)
+	if list := ctx.genDoc(m.Doc); len(list) > 0 {
+		fn.SetComments(pkg, &ast.CommentGroup{List: list})
+	}
+	cb := fn.BodyStart(pkg)
+	ctx.genCast(cb, ret, func() {
+		ctx.getAttr(cb, recv, m.Name)
+	})
+	cb.Return(1).End()
+}
+
+// genCall generates the function name, or the method of recv if it is not
+// nil, calling the Python object pushed by fn with the arguments of the
+// Python signature sig.
+//
+// The required parameters are typed after their type hints and passed by
+// position. The optional and keyword-only ones are passed by name in a dict,
+// the trailing kwargs parameter, nil for none. A signature that is unknown,
+// has *args or optional positional-only parameters is called with a tuple
+// args and a dict kwargs.
+func (ctx *context) genCall(pkg *gogen.Package, recv *types.Var, name string, doc []*ast.Comment, sig string, ret types.Type, fn func(cb *gogen.CodeBuilder)) {
+	params, keywords, ok := ctx.genArgs(pkg, sig)
+	var args, kwargs *types.Var
+	if !ok {
+		args = pkg.NewParam(token.NoPos, "args", ctx.objPtr)
+		kwargs = pkg.NewParam(token.NoPos, "kwargs", ctx.objPtr)
+		params = []*types.Var{args, kwargs}
+		if len(doc) > 0 {
+			doc = append(doc, emptyCommentLine)
+		}
+		doc = append(doc, &ast.Comment{Text: "// args is a tuple of the positional arguments and kwargs a dict of the keyword ones, or nil."})
+	} else if keywords != nil {
+		kwargs = pkg.NewParam(token.NoPos, "kwargs", ctx.objPtr)
+		params = append(params, kwargs)
+		if len(doc) > 0 {
+			doc = append(doc, emptyCommentLine)
+		}
+		doc = append(doc, &ast.Comment{Text: "// kwargs is a dict of the optional and keyword-only arguments, or nil: " + strings.Join(keywords, ", ") + "."})
+	}
+	var newRef types.Object
+	if args == nil {
+		newRef = ctx.newRef(pkg)
+	}
+	results := types.NewTuple(pkg.NewParam(token.NoPos, "", ret))
+	f := pkg.NewFunc(recv, name, types.NewTuple(params...), results, false)
+	if len(doc) > 0 {
+		f.SetComments(pkg, &ast.CommentGroup{List: doc})
+	}
+	cb := f.BodyStart(pkg)
+	ctx.genCast(cb, ret, func() {
+		fn(cb)
+		cb.MemberVal("Call")
+		if args != nil {
+			cb.Val(args)
+		} else {
+			cb.Val(ctx.py.Ref("Tuple"))
+			n := 0
+			for _, param := range params {
+				if param == kwargs {
+					continue
+				}
+				// the tuple steals the references to the objects
+				switch t := param.Type(); {
+				case t == ctx.objPtr:
+					cb.Val(newRef).Val(param).Call(1)
+				case ctx.classType(t) != ctx.objPtr:
+					cb.Val(newRef).Typ(ctx.objPtr).Typ(types.Typ[types.UnsafePointer]).Val(param).Call(1).Call(1).Call(1)
+				default:
+					cb.Val(param)
+				}
+				n++
+			}
+			cb.Call(n)
+		}
+		if kwargs != nil {
+			cb.Val(kwargs)
+		} else {
+			cb.Val(nil)
+		}
+		cb.Call(2)
+	})
+	cb.Return(1).End()
+}
+
+// genArgs returns the required parameters of the Python signature sig and
+// the names of the optional and keyword-only ones. ok is false if sig cannot
+// be called that way. The self parameter of a method is skipped.
+func (ctx *context) genArgs(pkg *gogen.Package, sig string) (params []*types.Var, keywords []string, ok bool) {
+	if sig == "" || sig == "<NULL>" {
+		return
+	}
+	kwonly := false
+	for i, arg := range pysig.Parse(sig) {
+		name := arg.Name
+		switch {
+		case name == "" || i == 0 && name == "self":
+		case name == "/":
+			if keywords != nil { // optional positional-only parameters
+				return nil, nil, false
+			}
+		case name == "*" || name == "\\*":
+			kwonly = true
+		case strings.HasPrefix(name, "**"):
+			keywords = append(keywords, name)
+		case strings.HasPrefix(name, "*"):
+			return nil, nil, false
+		case kwonly || arg.DefVal != "":
+			keywords = append(keywords, name)
+		default:
+			name = genName(name, 0)
+			switch name {
+			case "o", "args", "kwargs":
+				name += "_"
+			}
+			params = append(params, pkg.NewParam(token.NoPos, name, ctx.goType(arg.Type)))
+		}
+	}
+	return params, keywords, true
+}
+
+// resultType returns *C if the Python signature sig returns an instance of
+// the class C of the module, or *py.Object.
+func (ctx *context) resultType(sig string) types.Type {
+	return ctx.classType(ctx.goType(pysig.Result(sig)))
+}
+
+// classType returns t if it is *C for a class C of the module, or
+// *py.Object.
+func (ctx *context) classType(t types.Type) types.Type {
+	if t, ok := t.(*types.Pointer); ok && t != ctx.objPtr {
+		if _, ok := t.Elem().Underlying().(*types.Struct); ok {
+			return t
+		}
+	}
+	return ctx.objPtr
+}
+
+// newRef returns the function declared as
+//
+//	//go:linkname newRef C.Py_NewRef
+//	func newRef(o *py.Object) *py.Object
+func (ctx *context) newRef(pkg *gogen.Package) types.Object {
+	const name = "newRef"
+	if obj := pkg.Types.Scope().Lookup(name); obj != nil {
+		return obj
+	}
+	params := types.NewTuple(pkg.NewParam(token.NoPos, "o", ctx.objPtr))
+	fn := pkg.NewFuncDecl(token.NoPos, name, types.NewSignatureType(nil, nil, nil, params, ctx.ret, false))
+	fn.SetComments(pkg, &ast.CommentGroup{List: []*ast.Comment{{Text: "//go:linkname " + name + " C.Py_NewRef"}}})
+	return fn.Func
+}
+
+// genCast pushes the *py.Object pushed by fn, converted to ret if it is the
+// pointer to a class: (*C)(unsafe.Pointer(obj)).
+func (ctx *context) genCast(cb *gogen.CodeBuilder, ret types.Type, fn func()) {
+	if ret == ctx.objPtr {
+		fn()
+		return
+	}
+	cb.Typ(ret).Typ(types.Typ[types.UnsafePointer])
+	fn()
+	cb.Call(1).Call(1)
+}
+
+// getAttr pushes the attribute name of the receiver recv:
+// o.Object.GetAttrString(c.Str(name)).
+func (ctx *context) getAttr(cb *gogen.CodeBuilder, recv *types.Var, name string) {
+	cb.Val(recv).MemberVal("Object").MemberVal("GetAttrString").
+		Val(ctx.c.Ref("Str")).Val(name).Call(1).Call(1)
+}
+
+func hasMethod(t *types.Named, name string) bool {
+	if name == "Object" {
+		return true
+	}
+	for i := 0; i < t.NumMethods(); i++ {
+		if t.Method(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
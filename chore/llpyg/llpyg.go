@@ -19,6 +19,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/token"
@@ -26,6 +27,8 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/goplus/gogen"
@@ -34,15 +37,17 @@ import (
 )
 
 type symbol struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
-	Doc  string `json:"doc"`
-	Sig  string `json:"sig"`
-	URL  string `json:"url"`
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Doc     string    `json:"doc"`
+	Sig     string    `json:"sig"`
+	URL     string    `json:"url"`
+	Members []*symbol `json:"members"` // non-nil for a class
 }
 
 type module struct {
 	Name  string    `json:"name"`
+	File  string    `json:"file"`
 	Items []*symbol `json:"items"`
 }
 
@@ -69,34 +74,26 @@ func pysigfetch(pyLib string, names []string) (mod module) {
 	return
 }
 
+var (
+	stubFile = flag.String("stub", "", "Python stub file (.pyi) of the module, instead of the one next to it")
+)
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "Usage: llpyg <pythonLibPath>")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: llpyg [-stub file.pyi] <pythonLibPath>")
 		return
 	}
-	pyLib := os.Args[1]
+	pyLib := flag.Arg(0)
 
 	mod := pydump(pyLib)
 	if mod.Name != pyLib {
 		log.Printf("import module %s failed\n", pyLib)
 		os.Exit(1)
 	}
+	stubs := loadStubs(*stubFile, mod.File)
 	pkg := gogen.NewPackage("", pkgName(pyLib), nil)
-	pkg.Import("unsafe").MarkForceUsed(pkg)      // import _ "unsafe"
-	py := pkg.Import("github.com/goplus/lib/py") // import "github.com/goplus/lib/py"
-
-	f := func(cb *gogen.CodeBuilder) int {
-		cb.Val("py." + mod.Name)
-		return 1
-	}
-	defs := pkg.NewConstDefs(pkg.Types.Scope())
-	defs.New(f, 0, 0, nil, "LLGoPackage")
-
-	obj := py.Ref("Object").(*types.TypeName).Type().(*types.Named)
-	objPtr := types.NewPointer(obj)
-	ret := types.NewTuple(pkg.NewParam(0, "", objPtr))
-
-	ctx := &context{pkg, obj, objPtr, ret, nil, py}
+	ctx := newContext(pkg, pyLib, stubs)
 	ctx.genMod(pkg, &mod)
 	skips := ctx.skips
 	if n := len(skips); n > 0 {
@@ -115,6 +112,30 @@ func main() {
 	pkg.WriteTo(os.Stdout)
 }
 
+// loadStubs returns the signatures of the stub file, or of the stub file
+// next to the module file modFile if it is "".
+func loadStubs(file, modFile string) map[string]string {
+	if file == "" {
+		if modFile == "" {
+			return nil
+		}
+		// foo.py, foo.cpython-312-x86_64-linux-gnu.so => foo.pyi
+		dir, name := filepath.Split(modFile)
+		if pos := strings.IndexByte(name, '.'); pos >= 0 {
+			name = name[:pos]
+		}
+		file = filepath.Join(dir, name+".pyi")
+		if _, err := os.Stat(file); err != nil {
+			return nil
+		}
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		log.Panicln(err)
+	}
+	return pysig.ParseStub(string(src))
+}
+
 func pkgName(pyLib string) string {
 	if pos := strings.LastIndexByte(pyLib, '.'); pos >= 0 {
 		return pyLib[pos+1:]
@@ -123,16 +144,50 @@ func pkgName(pyLib string) string {
 }
 
 type context struct {
-	pkg    *gogen.Package
-	obj    *types.Named
-	objPtr *types.Pointer
-	ret    *types.Tuple
-	skips  []string
-	py     gogen.PkgRef
+	pkg     *gogen.Package
+	obj     *types.Named
+	objPtr  *types.Pointer
+	ret     *types.Tuple
+	skips   []string
+	py      gogen.PkgRef
+	c       gogen.PkgRef
+	mod     string
+	stubs   map[string]string       // name or Class.name => signature
+	classes map[string]*types.Named // Python class => Go type
+}
+
+func newContext(pkg *gogen.Package, mod string, stubs map[string]string) *context {
+	pkg.Import("unsafe").MarkForceUsed(pkg)      // import _ "unsafe"
+	py := pkg.Import("github.com/goplus/lib/py") // import "github.com/goplus/lib/py"
+
+	f := func(cb *gogen.CodeBuilder) int {
+		cb.Val("py." + mod)
+		return 1
+	}
+	defs := pkg.NewConstDefs(pkg.Types.Scope())
+	defs.New(f, 0, 0, nil, "LLGoPackage")
+
+	obj := py.Ref("Object").(*types.TypeName).Type().(*types.Named)
+	objPtr := types.NewPointer(obj)
+	ret := types.NewTuple(pkg.NewParam(0, "", objPtr))
+	return &context{
+		pkg: pkg, obj: obj, objPtr: objPtr, ret: ret, py: py,
+		c: pkg.Import("github.com/goplus/lib/c"), mod: mod, stubs: stubs,
+		classes: make(map[string]*types.Named),
+	}
 }
 
 func (ctx *context) genMod(pkg *gogen.Package, mod *module) {
 	for _, sym := range mod.Items {
+		if sym.Members != nil {
+			ctx.declClass(pkg, sym)
+		}
+	}
+	for _, sym := range mod.Items {
+		if sym.Members != nil {
+			ctx.genClass(pkg, sym)
+			continue
+		}
 		switch sym.Type {
 		case "builtin_function_or_method", "function", "method", "ufunc", "method-wrapper":
 			ctx.genFunc(pkg, sym)
@@ -155,13 +210,16 @@ func (ctx *context) genFunc(pkg *gogen.Package, sym *symbol) {
 	if len(name) == 0 || name[0] == '_' {
 		return
 	}
+	if sig, ok := ctx.stubs[name]; ok {
+		symSig = sig
+	}
 	if symSig == "<NULL>" {
 		ctx.skips = append(ctx.skips, name)
 		return
 	}
 	params, variadic := ctx.genParams(pkg, symSig)
 	name = genName(name, -1)
-	sig := types.NewSignatureType(nil, nil, nil, params, ctx.ret, variadic)
+	sig := types.NewSignatureType(nil, nil, nil, params, ctx.genResults(pkg, symSig), variadic)
 	fn := pkg.NewFuncDecl(token.NoPos, name, sig)
 	list := ctx.genDoc(sym.Doc)
 	if sym.URL != "" {
@@ -178,6 +236,9 @@ func (ctx *context) genFunc(pkg *gogen.Package, sym *symbol) {
 	// fn.BodyStart(pkg).End()
 }
 
+// genParams returns the parameters of a module function: the required ones
+// are typed after their type hints, the optional ones are *py.Object, nil for
+// their default value.
 func (ctx *context) genParams(pkg *gogen.Package, sig string) (*types.Tuple, bool) {
 	args := pysig.Parse(sig)
 	if len(args) == 0 {
@@ -201,11 +262,83 @@ func (ctx *context) genParams(pkg *gogen.Package, sig string) (*types.Tuple, boo
 			}
 			return types.NewTuple(list...), false
 		}
-		list = append(list, pkg.NewParam(0, genName(name, 0), objPtr))
+		typ := types.Type(objPtr)
+		if args[i].DefVal == "" {
+			typ = ctx.goType(args[i].Type)
+		}
+		list = append(list, pkg.NewParam(0, genName(name, 0), typ))
 	}
 	return types.NewTuple(list...), false
 }
 
+// genResults returns the result of a module function, typed after its type
+// hint.
+func (ctx *context) genResults(pkg *gogen.Package, sig string) *types.Tuple {
+	if typ := ctx.goType(pysig.Result(sig)); typ != ctx.objPtr {
+		return types.NewTuple(pkg.NewParam(0, "", typ))
+	}
+	return ctx.ret
+}
+
+// goType returns the Go type of the Python type hint typ, or *py.Object if
+// it has none: int, float, complex, str, bool and bytes, lists and dicts of
+// them, and the classes of the module.
+func (ctx *context) goType(typ string) types.Type {
+	typ = strings.Trim(strings.TrimSpace(typ), `'"`)
+	switch typ {
+	case "int":
+		return types.Typ[types.Int]
+	case "float":
+		return types.Typ[types.Float64]
+	case "complex":
+		return types.Typ[types.Complex128]
+	case "str":
+		return types.Typ[types.String]
+	case "bool":
+		return types.Typ[types.Bool]
+	case "bytes":
+		return types.NewSlice(types.Typ[types.Byte])
+	}
+	if t, ok := ctx.classes[strings.TrimPrefix(typ, ctx.mod+".")]; ok {
+		return types.NewPointer(t)
+	}
+	if targs, ok := typeArgs(typ, "list", "List"); ok && len(targs) == 1 {
+		if elem := ctx.goType(targs[0]); elem != ctx.objPtr {
+			return types.NewSlice(elem)
+		}
+	} else if targs, ok := typeArgs(typ, "dict", "Dict"); ok && len(targs) == 2 {
+		key, elem := ctx.goType(targs[0]), ctx.goType(targs[1])
+		if _, ok := key.(*types.Basic); ok && elem != ctx.objPtr {
+			return types.NewMap(key, elem)
+		}
+	}
+	return ctx.objPtr
+}
+
+// typeArgs returns the type arguments of typ if it is a generic type of one
+// of names, such as list[int].
+func typeArgs(typ string, names ...string) (targs []string, ok bool) {
+	pos := strings.IndexByte(typ, '[')
+	if pos < 0 || !strings.HasSuffix(typ, "]") || !slices.Contains(names, typ[:pos]) {
+		return nil, false
+	}
+	depth, start := 0, pos+1
+	for i := start; i < len(typ)-1; i++ {
+		switch typ[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				targs = append(targs, typ[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(targs, typ[start:len(typ)-1]), true
+}
+
 func genName(name string, idxDontTitle int) string {
 	parts := strings.Split(name, "_")
 	for i, part := range parts {
@@ -217,8 +350,7 @@ func genName(name string, idxDontTitle int) string {
 		}
 	}
 	name = strings.Join(parts, "")
-	switch name {
-	case "default", "func", "var", "range", "":
+	if name == "" || token.IsKeyword(name) {
 		name += "_"
 	}
 	return name
@@ -0,0 +1,116 @@
+//go:build !llgo
+// +build !llgo
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llgo/chore/llpyg/pysig"
+)
+
+func TestGenClass(t *testing.T) {
+	mod := &module{Name: "torch", Items: []*symbol{
+		{Name: "Tensor", Type: "_TensorMeta", Doc: "A tensor.", Sig: "(*args, **kwargs)", Members: []*symbol{
+			{Name: "_private", Type: "function", Sig: "(self)"},
+			{Name: "sum", Type: "method_descriptor", Doc: "Sums the elements.", Sig: "<NULL>"},
+			{Name: "reshape", Type: "method_descriptor", Sig: "<NULL>"},
+			{Name: "add", Type: "function", Sig: "(self, other, *, alpha=1)"},
+			{Name: "mul", Type: "method_descriptor", Sig: "<NULL>"},
+			{Name: "shape", Type: "getset_descriptor", Doc: "The shape."},
+			{Name: "T", Type: "getset_descriptor"},
+		}},
+		{Name: "zeros", Type: "builtin_function_or_method", Sig: "<NULL>"},
+		{Name: "numel", Type: "builtin_function_or_method", Sig: "(input: Tensor, scale: float = 1.0) -> int"},
+	}}
+	stubs := pysig.ParseStub(`
+def zeros(n: int, names: list[str]) -> Tensor: ...
+
+class Tensor:
+    def __init__(self, data: list[float], *, requires_grad: bool = False) -> None: ...
+    def reshape(self, shape: list[int]) -> Tensor: ...
+    def mul(self, other: Tensor) -> Tensor: ...
+    @property
+    def T(self) -> Tensor: ...
+`)
+	pkg := gogen.NewPackage("", "torch", nil)
+	ctx := newContext(pkg, mod.Name, stubs)
+	ctx.genMod(pkg, mod)
+	var buf bytes.Buffer
+	if err := pkg.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != genClassOut {
+		t.Fatalf("genMod:\n%s\nwant:\n%s", got, genClassOut)
+	}
+}
+
+const genClassOut = `package torch
+
+import (
+	"github.com/goplus/lib/c"
+	"github.com/goplus/lib/py"
+	"unsafe"
+)
+
+const LLGoPackage = "py.torch"
+// A tensor.
+type Tensor struct {
+	py.Object
+}
+//go:linkname pyTensor py.Tensor
+var pyTensor *py.Object
+//go:linkname newRef C.Py_NewRef
+func newRef(o *py.Object) *py.Object
+// NewTensor creates an instance of the class Tensor.
+//
+// kwargs is a dict of the optional and keyword-only arguments, or nil: requires_grad.
+func NewTensor(data []float64, kwargs *py.Object) *Tensor {
+	return (*Tensor)(unsafe.Pointer(pyTensor.Call(py.Tuple(data), kwargs)))
+}
+// Sums the elements.
+//
+// args is a tuple of the positional arguments and kwargs a dict of the keyword ones, or nil.
+func (o *Tensor) Sum(args *py.Object, kwargs *py.Object) *py.Object {
+	return o.Object.GetAttrString(c.Str("sum")).Call(args, kwargs)
+}
+func (o *Tensor) Reshape(shape []int) *Tensor {
+	return (*Tensor)(unsafe.Pointer(o.Object.GetAttrString(c.Str("reshape")).Call(py.Tuple(shape), nil)))
+}
+// kwargs is a dict of the optional and keyword-only arguments, or nil: alpha.
+func (o *Tensor) Add(other *py.Object, kwargs *py.Object) *py.Object {
+	return o.Object.GetAttrString(c.Str("add")).Call(py.Tuple(newRef(other)), kwargs)
+}
+func (o *Tensor) Mul(other *Tensor) *Tensor {
+	return (*Tensor)(unsafe.Pointer(o.Object.GetAttrString(c.Str("mul")).Call(py.Tuple(newRef((*py.Object)(unsafe.Pointer(other)))), nil)))
+}
+// The shape.
+func (o *Tensor) Shape() *py.Object {
+	return o.Object.GetAttrString(c.Str("shape"))
+}
+func (o *Tensor) T() *Tensor {
+	return (*Tensor)(unsafe.Pointer(o.Object.GetAttrString(c.Str("T"))))
+}
+//go:linkname Zeros py.zeros
+func Zeros(n int, names []string) *Tensor
+//go:linkname Numel py.numel
+func Numel(input *Tensor, scale *py.Object) int
+`
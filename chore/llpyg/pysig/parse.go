@@ -55,6 +55,20 @@ func Parse(sig string) (args []*Arg) {
 	}
 }
 
+// Result returns the return annotation of a Python function signature, or
+// "" if it has none.
+func Result(sig string) string {
+	left := parseText(strings.TrimPrefix(sig, "("), ")"+allSpecials)
+	if left == "" {
+		return ""
+	}
+	ret, ok := strings.CutPrefix(strings.TrimSpace(left[1:]), "->")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(ret), ":"))
+}
+
 const (
 	allSpecials = "([<'\""
 )
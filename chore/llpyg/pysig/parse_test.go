@@ -53,3 +53,20 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestResult(t *testing.T) {
+	cases := []struct {
+		sig, ret string
+	}{
+		{"(start=None, *, unit: 'str | None' = None) -> 'TimedeltaIndex'", "'TimedeltaIndex'"},
+		{"(a: int)", ""},
+		{"(a = <1>) -> list[int]", "list[int]"},
+		{"(a: 'Suffixes' = ('_x', '_y')) -> dict[str, float]:", "dict[str, float]"},
+		{"(a", ""},
+	}
+	for _, c := range cases {
+		if ret := Result(c.sig); ret != c.ret {
+			t.Fatalf("Result(%s) = %q, want %q", c.sig, ret, c.ret)
+		}
+	}
+}
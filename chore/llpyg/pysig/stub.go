@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pysig
+
+import (
+	"strings"
+)
+
+// ParseStub parses the source of a Python stub file (.pyi) and returns the
+// signatures of its functions, keyed by name, and of the methods of its
+// top-level classes, keyed by "Class.name". Only the first of overloaded
+// signatures is kept.
+func ParseStub(src string) map[string]string {
+	sigs := make(map[string]string)
+	class, member := "", 0 // member is the indent of the class members
+	lines := strings.Split(src, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t\r")
+		text := strings.TrimLeft(line, " \t")
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		indent := len(line) - len(text)
+		if indent == 0 {
+			class, member = "", 0
+			if name, ok := strings.CutPrefix(text, "class "); ok {
+				if pos := strings.IndexAny(name, "(:"); pos >= 0 {
+					class = strings.TrimSpace(name[:pos])
+				}
+				continue
+			}
+		} else if class == "" {
+			continue
+		} else if member == 0 {
+			member = indent
+		} else if indent != member {
+			continue
+		}
+		text = strings.TrimPrefix(text, "async ")
+		def, ok := strings.CutPrefix(text, "def ")
+		if !ok {
+			continue
+		}
+		pos := strings.IndexByte(def, '(')
+		if pos < 0 {
+			continue
+		}
+		name, sig := strings.TrimSpace(def[:pos]), def[pos:]
+		for !sigEnded(sig) && i+1 < len(lines) {
+			i++
+			sig = joinLine(sig, strings.TrimSpace(lines[i]))
+		}
+		if indent > 0 {
+			name = class + "." + name
+		}
+		if _, ok := sigs[name]; !ok {
+			sigs[name] = stubSig(sig)
+		}
+	}
+	return sigs
+}
+
+// sigEnded reports whether the parameters of sig, which starts with '(',
+// are closed.
+func sigEnded(sig string) bool {
+	left := parseText(sig[1:], ")"+allSpecials)
+	return strings.HasPrefix(left, ")")
+}
+
+// joinLine joins the next line of a signature split over lines to sig.
+func joinLine(sig, next string) string {
+	if strings.HasPrefix(next, ")") {
+		return strings.TrimSuffix(sig, ",") + next
+	}
+	if strings.HasSuffix(sig, "(") {
+		return sig + next
+	}
+	return sig + " " + next
+}
+
+// stubSig returns sig, the rest of a def line, without the trailing ':' and
+// the body following it, such as "...".
+func stubSig(sig string) string {
+	left := parseText(sig[1:], ")"+allSpecials)
+	params := sig[:len(sig)-len(left)+1]
+	ret := strings.TrimSpace(left[1:])
+	if ret, ok := strings.CutPrefix(ret, "->"); ok {
+		if pos := strings.LastIndexByte(ret, ':'); pos >= 0 {
+			ret = ret[:pos]
+		}
+		return params + " -> " + strings.TrimSpace(ret)
+	}
+	return params
+}
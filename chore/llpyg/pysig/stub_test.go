@@ -0,0 +1,67 @@
+//go:build !llgo
+// +build !llgo
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pysig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStub(t *testing.T) {
+	const src = `from typing import overload
+
+# a comment
+def add(a: int, b: int) -> int: ...
+async def fetch(url: str) -> bytes: ...
+
+class Tensor(object):
+    """A tensor."""
+    shape: tuple[int, ...]
+
+    def __init__(self, data: list[float], *, requires_grad: bool = ...) -> None: ...
+    @property
+    def ndim(self) -> int: ...
+    @overload
+    def sum(self) -> Tensor: ...
+    @overload
+    def sum(self, dim: int) -> Tensor: ...
+    def reshape(
+        self,
+        shape: tuple[int, ...],
+    ) -> Tensor:
+        ...
+    class Inner:
+        def skipped(self) -> None: ...
+
+def zeros(n: int) -> Tensor: ...
+`
+	want := map[string]string{
+		"add":             "(a: int, b: int) -> int",
+		"fetch":           "(url: str) -> bytes",
+		"Tensor.__init__": "(self, data: list[float], *, requires_grad: bool = ...) -> None",
+		"Tensor.ndim":     "(self) -> int",
+		"Tensor.sum":      "(self) -> Tensor",
+		"Tensor.reshape":  "(self, shape: tuple[int, ...]) -> Tensor",
+		"zeros":           "(n: int) -> Tensor",
+	}
+	if sigs := ParseStub(src); !reflect.DeepEqual(sigs, want) {
+		t.Fatalf("ParseStub:\n%v\nwant:\n%v", sigs, want)
+	}
+}
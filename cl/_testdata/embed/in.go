@@ -0,0 +1,18 @@
+package main
+
+import "embed"
+
+//go:embed hello.txt
+var hello string
+
+//go:embed hello.txt
+var data []byte
+
+//go:embed static
+var static embed.FS
+
+func main() {
+	println(hello, len(data))
+	b, _ := static.ReadFile("static/sub/b.txt")
+	println(string(b))
+}
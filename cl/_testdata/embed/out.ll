@@ -0,0 +1,68 @@
+; ModuleID = 'github.com/goplus/llgo/cl/_testdata/embed'
+source_filename = "github.com/goplus/llgo/cl/_testdata/embed"
+
+%"github.com/goplus/llgo/runtime/internal/runtime.Slice" = type { ptr, i64, i64 }
+%"github.com/goplus/llgo/runtime/internal/runtime.String" = type { ptr, i64 }
+%embed.FS = type { ptr }
+%embed.file = type { %"github.com/goplus/llgo/runtime/internal/runtime.String", %"github.com/goplus/llgo/runtime/internal/runtime.String", [16 x i8] }
+%"github.com/goplus/llgo/runtime/internal/runtime.iface" = type { ptr, ptr }
+
+@"github.com/goplus/llgo/cl/_testdata/embed.data" = global %"github.com/goplus/llgo/runtime/internal/runtime.Slice" { ptr @0, i64 6, i64 6 }, align 8
+@0 = private global [6 x i8] c"hello\0A", align 1
+@"github.com/goplus/llgo/cl/_testdata/embed.hello" = global %"github.com/goplus/llgo/runtime/internal/runtime.String" { ptr @1, i64 6 }, align 8
+@1 = private unnamed_addr constant [6 x i8] c"hello\0A", align 1
+@"github.com/goplus/llgo/cl/_testdata/embed.init$guard" = global i1 false, align 1
+@"github.com/goplus/llgo/cl/_testdata/embed.static" = global %embed.FS { ptr @9 }, align 8
+@2 = private unnamed_addr constant [7 x i8] c"static/", align 1
+@3 = private unnamed_addr constant [12 x i8] c"static/a.txt", align 1
+@4 = private unnamed_addr constant [1 x i8] c"a", align 1
+@5 = private unnamed_addr constant [11 x i8] c"static/sub/", align 1
+@6 = private unnamed_addr constant [16 x i8] c"static/sub/b.txt", align 1
+@7 = private unnamed_addr constant [1 x i8] c"b", align 1
+@8 = private constant [4 x %embed.file] [%embed.file { %"github.com/goplus/llgo/runtime/internal/runtime.String" { ptr @2, i64 7 }, %"github.com/goplus/llgo/runtime/internal/runtime.String" zeroinitializer, [16 x i8] zeroinitializer }, %embed.file { %"github.com/goplus/llgo/runtime/internal/runtime.String" { ptr @3, i64 12 }, %"github.com/goplus/llgo/runtime/internal/runtime.String" { ptr @4, i64 1 }, [16 x i8] c"\CA\97\81\12\CA\1B\BD\CA\FA\C21\B3\9A#\DCM" }, %embed.file { %"github.com/goplus/llgo/runtime/internal/runtime.String" { ptr @5, i64 11 }, %"github.com/goplus/llgo/runtime/internal/runtime.String" zeroinitializer, [16 x i8] zeroinitializer }, %embed.file { %"github.com/goplus/llgo/runtime/internal/runtime.String" { ptr @6, i64 16 }, %"github.com/goplus/llgo/runtime/internal/runtime.String" { ptr @7, i64 1 }, [16 x i8] c">#\E8\16\009YJ3\89Oed\E1\B14" }]
+@9 = private constant %"github.com/goplus/llgo/runtime/internal/runtime.Slice" { ptr @8, i64 4, i64 4 }
+
+define void @"github.com/goplus/llgo/cl/_testdata/embed.init"() {
+_llgo_0:
+  %0 = load i1, ptr @"github.com/goplus/llgo/cl/_testdata/embed.init$guard", align 1
+  br i1 %0, label %_llgo_2, label %_llgo_1
+
+_llgo_1:                                          ; preds = %_llgo_0
+  store i1 true, ptr @"github.com/goplus/llgo/cl/_testdata/embed.init$guard", align 1
+  call void @embed.init()
+  br label %_llgo_2
+
+_llgo_2:                                          ; preds = %_llgo_1, %_llgo_0
+  ret void
+}
+
+define void @"github.com/goplus/llgo/cl/_testdata/embed.main"() {
+_llgo_0:
+  %0 = load %"github.com/goplus/llgo/runtime/internal/runtime.String", ptr @"github.com/goplus/llgo/cl/_testdata/embed.hello", align 8
+  %1 = load %"github.com/goplus/llgo/runtime/internal/runtime.Slice", ptr @"github.com/goplus/llgo/cl/_testdata/embed.data", align 8
+  %2 = extractvalue %"github.com/goplus/llgo/runtime/internal/runtime.Slice" %1, 1
+  call void @"github.com/goplus/llgo/runtime/internal/runtime.PrintString"(%"github.com/goplus/llgo/runtime/internal/runtime.String" %0)
+  call void @"github.com/goplus/llgo/runtime/internal/runtime.PrintByte"(i8 32)
+  call void @"github.com/goplus/llgo/runtime/internal/runtime.PrintInt"(i64 %2)
+  call void @"github.com/goplus/llgo/runtime/internal/runtime.PrintByte"(i8 10)
+  %3 = load %embed.FS, ptr @"github.com/goplus/llgo/cl/_testdata/embed.static", align 8
+  %4 = call { %"github.com/goplus/llgo/runtime/internal/runtime.Slice", %"github.com/goplus/llgo/runtime/internal/runtime.iface" } @embed.FS.ReadFile(%embed.FS %3, %"github.com/goplus/llgo/runtime/internal/runtime.String" { ptr @6, i64 16 })
+  %5 = extractvalue { %"github.com/goplus/llgo/runtime/internal/runtime.Slice", %"github.com/goplus/llgo/runtime/internal/runtime.iface" } %4, 0
+  %6 = extractvalue { %"github.com/goplus/llgo/runtime/internal/runtime.Slice", %"github.com/goplus/llgo/runtime/internal/runtime.iface" } %4, 1
+  %7 = call %"github.com/goplus/llgo/runtime/internal/runtime.String" @"github.com/goplus/llgo/runtime/internal/runtime.StringFromBytes"(%"github.com/goplus/llgo/runtime/internal/runtime.Slice" %5)
+  call void @"github.com/goplus/llgo/runtime/internal/runtime.PrintString"(%"github.com/goplus/llgo/runtime/internal/runtime.String" %7)
+  call void @"github.com/goplus/llgo/runtime/internal/runtime.PrintByte"(i8 10)
+  ret void
+}
+
+declare void @embed.init()
+
+declare void @"github.com/goplus/llgo/runtime/internal/runtime.PrintString"(%"github.com/goplus/llgo/runtime/internal/runtime.String")
+
+declare void @"github.com/goplus/llgo/runtime/internal/runtime.PrintByte"(i8)
+
+declare void @"github.com/goplus/llgo/runtime/internal/runtime.PrintInt"(i64)
+
+declare { %"github.com/goplus/llgo/runtime/internal/runtime.Slice", %"github.com/goplus/llgo/runtime/internal/runtime.iface" } @embed.FS.ReadFile(%embed.FS, %"github.com/goplus/llgo/runtime/internal/runtime.String")
+
+declare %"github.com/goplus/llgo/runtime/internal/runtime.String" @"github.com/goplus/llgo/runtime/internal/runtime.StringFromBytes"(%"github.com/goplus/llgo/runtime/internal/runtime.Slice")
@@ -23,6 +23,7 @@ import (
 	"go/ast"
 	"go/constant"
 	"go/types"
+	"sort"
 	"strings"
 	"testing"
 	"unsafe"
@@ -363,6 +364,92 @@ func TestErrInitLinkname(t *testing.T) {
 	})
 }
 
+func TestWasmDirective(t *testing.T) {
+	ctx := &context{
+		wasmImports: make(map[string]wasmImport),
+		wasmExports: make(map[string]string),
+	}
+	doc := &ast.CommentGroup{List: []*ast.Comment{
+		{Text: "// add adds two numbers."},
+		{Text: "//go:wasmimport env add"},
+	}}
+	ctx.initLinknameByDoc(doc, "foo.add", "add", false)
+	if imp := ctx.wasmImports["foo.add"]; imp != (wasmImport{"env", "add"}) {
+		t.Errorf("wasmimport = %v", imp)
+	}
+	doc = &ast.CommentGroup{List: []*ast.Comment{
+		{Text: "//go:wasmexport run"},
+	}}
+	ctx.initLinknameByDoc(doc, "foo.Run", "Run", false)
+	if name := ctx.wasmExports["foo.Run"]; name != "run" {
+		t.Errorf("wasmexport = %q", name)
+	}
+	if ctx.initWasmDirective("//go:wasmimport add", "foo.bad") {
+		if _, ok := ctx.wasmImports["foo.bad"]; ok {
+			t.Error("wasmimport without module accepted")
+		}
+	}
+	if ctx.initWasmDirective("//go:linkname add add", "foo.add") {
+		t.Error("initWasmDirective accepts //go:linkname")
+	}
+}
+
+func TestEmbedPatterns(t *testing.T) {
+	doc := &ast.CommentGroup{List: []*ast.Comment{
+		{Text: "// files are the static files."},
+		{Text: "//go:embed a.txt  static/*.html"},
+		{Text: "//go:embed \"with space.txt\" `all:dir`"},
+	}}
+	patterns, err := embedPatterns(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(patterns, "|"); got != "a.txt|static/*.html|with space.txt|all:dir" {
+		t.Errorf("embedPatterns = %q", got)
+	}
+	doc = &ast.CommentGroup{List: []*ast.Comment{{Text: "//go:embed \"a.txt"}}}
+	if _, err = embedPatterns(doc); err == nil {
+		t.Error("embedPatterns accepts an unterminated string")
+	}
+	if patterns, _ = embedPatterns(nil); patterns != nil {
+		t.Errorf("embedPatterns(nil) = %v", patterns)
+	}
+}
+
+func TestEmbedMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"a.txt", "a.txt", true},
+		{"*.txt", "a.txt", true},
+		{"*.txt", "dir/a.txt", false},
+		{"dir", "dir/a.txt", true},
+		{"dir", "dir/sub/a.txt", true},
+		{"dir", "dir/.hidden", false},
+		{"dir", "dir/_sub/a.txt", false},
+		{"all:dir", "dir/_sub/a.txt", true},
+		{"dir/*", "dir/.hidden", true},
+		{"d*", "dir/a.txt", true},
+		{"dir", "dir2/a.txt", false},
+	}
+	for _, tt := range tests {
+		if got := embedMatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("embedMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEmbedFileLess(t *testing.T) {
+	names := []string{"p", "q/", "q/r", "q/s/", "q/s/t", "q/s/u", "q/v", "w"}
+	sort.Slice(names, func(i, j int) bool {
+		return embedFileLess(names[i], names[j])
+	})
+	if got := strings.Join(names, " "); got != "p q/ w q/r q/s/ q/v q/s/t q/s/u" {
+		t.Errorf("sorted files = %s", got)
+	}
+}
+
 func TestErrVarOf(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -127,6 +127,16 @@ type context struct {
 	cgoRet     llssa.Expr
 	cgoSymbols []string
 	cgoExports map[string]string
+
+	wasmImports map[string]wasmImport // fullName => //go:wasmimport module name
+	wasmExports map[string]string     // fullName => //go:wasmexport name
+
+	embeds     map[string]embedVar // fullName => //go:embed patterns
+	embedFiles []string            // files embedded by the package
+}
+
+type wasmImport struct {
+	module, name string
 }
 
 type pkgState byte
@@ -177,7 +187,11 @@ func (p *context) compileGlobal(pkg llssa.Package, gbl *ssa.Global) {
 	}
 	g := pkg.NewVar(name, typ, llssa.Background(vtype))
 	if define {
-		g.InitNil()
+		if v, ok := p.embeds[name]; ok {
+			p.initEmbed(pkg, g, gbl, v)
+		} else {
+			g.InitNil()
+		}
 	}
 }
 
@@ -971,12 +985,13 @@ type Patches = map[string]Patch
 
 // NewPackage compiles a Go package to LLVM IR package.
 func NewPackage(prog llssa.Program, pkg *ssa.Package, files []*ast.File) (ret llssa.Package, err error) {
-	ret, _, err = NewPackageEx(prog, nil, pkg, files)
+	ret, _, err = NewPackageEx(prog, nil, pkg, files, nil)
 	return
 }
 
-// NewPackageEx compiles a Go package to LLVM IR package.
-func NewPackageEx(prog llssa.Program, patches Patches, pkg *ssa.Package, files []*ast.File) (ret llssa.Package, externs []string, err error) {
+// NewPackageEx compiles a Go package to LLVM IR package. embedFiles are the
+// absolute paths of the files embedded by its //go:embed directives.
+func NewPackageEx(prog llssa.Program, patches Patches, pkg *ssa.Package, files []*ast.File, embedFiles []string) (ret llssa.Package, externs []string, err error) {
 	pkgProg := pkg.Prog
 	pkgTypes := pkg.Pkg
 	oldTypes := pkgTypes
@@ -1010,6 +1025,12 @@ func NewPackageEx(prog llssa.Program, patches Patches, pkg *ssa.Package, files [
 		},
 		cgoExports: make(map[string]string),
 		cgoSymbols: make([]string, 0, 128),
+
+		wasmImports: make(map[string]wasmImport),
+		wasmExports: make(map[string]string),
+
+		embeds:     make(map[string]embedVar),
+		embedFiles: embedFiles,
 	}
 	ctx.initPyModule()
 	ctx.initFiles(pkgPath, files)
@@ -1047,7 +1068,17 @@ func NewPackageEx(prog llssa.Program, patches Patches, pkg *ssa.Package, files [
 	for fnName, exportName := range ctx.cgoExports {
 		fn := ret.FuncOf(fnName)
 		if fn != nil {
-			fn.SetName(exportName)
+			fn.CgoExport(exportName)
+		}
+	}
+	for fnName, imp := range ctx.wasmImports {
+		if fn := ret.FuncOf(fnName); fn != nil {
+			fn.WasmImport(imp.module, imp.name)
+		}
+	}
+	for fnName, exportName := range ctx.wasmExports {
+		if fn := ret.FuncOf(fnName); fn != nil {
+			fn.WasmExport(exportName)
 		}
 	}
 	return
@@ -0,0 +1,208 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// embedVar is a variable declared with //go:embed directives.
+type embedVar struct {
+	dir      string   // directory of the file declaring the variable
+	patterns []string // patterns of the directives, relative to dir
+}
+
+// initEmbeds collects the //go:embed patterns of the variables declared by
+// decl in file.
+func (p *context) initEmbeds(pkgPath string, file *ast.File, decl *ast.GenDecl) {
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.ValueSpec)
+		doc := spec.Doc
+		if doc == nil && !decl.Lparen.IsValid() {
+			doc = decl.Doc
+		}
+		patterns, err := embedPatterns(doc)
+		if err != nil {
+			panic(fmt.Sprintf("%v: %v", p.fset.Position(spec.Pos()), err))
+		}
+		if patterns == nil {
+			continue
+		}
+		if len(spec.Names) != 1 {
+			panic(fmt.Sprintf("%v: go:embed cannot apply to multiple vars", p.fset.Position(spec.Pos())))
+		}
+		dir := filepath.Dir(p.fset.Position(file.Package).Filename)
+		p.embeds[pkgPath+"."+spec.Names[0].Name] = embedVar{dir, patterns}
+	}
+}
+
+// embedPatterns returns the patterns of the //go:embed directives of doc.
+func embedPatterns(doc *ast.CommentGroup) (patterns []string, err error) {
+	const embed = "//go:embed "
+	if doc == nil {
+		return
+	}
+	for _, c := range doc.List {
+		if !strings.HasPrefix(c.Text, embed) {
+			continue
+		}
+		args := strings.TrimSpace(c.Text[len(embed):])
+		for args != "" {
+			var pattern string
+			switch args[0] {
+			case '"', '`':
+				quote := strings.IndexByte(args[1:], args[0])
+				if quote < 0 {
+					return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+				}
+				if pattern, err = strconv.Unquote(args[:quote+2]); err != nil {
+					return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+				}
+				args = args[quote+2:]
+			default:
+				n := strings.IndexAny(args, " \t")
+				if n < 0 {
+					n = len(args)
+				}
+				pattern, args = args[:n], args[n:]
+			}
+			patterns = append(patterns, pattern)
+			args = strings.TrimLeft(args, " \t")
+		}
+	}
+	return
+}
+
+// embedMatch reports whether the file name, relative to the package
+// directory, is embedded by pattern: it matches the pattern, or is in a
+// directory which matches it and, without the "all:" prefix, has no path
+// element under the directory starting with '.' or '_'.
+func embedMatch(pattern, name string) bool {
+	pattern, all := strings.CutPrefix(pattern, "all:")
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if ok, _ := path.Match(pattern, dir); ok {
+			if all {
+				return true
+			}
+			for _, elem := range strings.Split(name[len(dir)+1:], "/") {
+				if elem[0] == '.' || elem[0] == '_' {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// embedFileLess reports whether the file a sorts before b in an embed.FS:
+// by directory, then by name.
+func embedFileLess(a, b string) bool {
+	adir, aelem := embedSplit(a)
+	bdir, belem := embedSplit(b)
+	return adir < bdir || adir == bdir && aelem < belem
+}
+
+func embedSplit(name string) (dir, elem string) {
+	name = strings.TrimSuffix(name, "/")
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return ".", name
+}
+
+func isEmbedFS(t types.Type) bool {
+	if t, ok := types.Unalias(t).(*types.Named); ok {
+		obj := t.Obj()
+		return obj.Name() == "FS" && obj.Pkg() != nil && obj.Pkg().Path() == "embed"
+	}
+	return false
+}
+
+// initEmbed initializes the global variable g, declared by gbl with
+// //go:embed directives, with the files p.embedFiles it matches.
+func (p *context) initEmbed(pkg llssa.Package, g llssa.Global, gbl *ssa.Global, v embedVar) {
+	pos := p.fset.Position(gbl.Pos())
+	var names []string
+	paths := make(map[string]string) // name => path
+	for _, file := range p.embedFiles {
+		rel, err := filepath.Rel(v.dir, file)
+		if err != nil {
+			continue
+		}
+		name := filepath.ToSlash(rel)
+		if strings.HasPrefix(name, "../") {
+			continue
+		}
+		for _, pattern := range v.patterns {
+			if embedMatch(pattern, name) {
+				names = append(names, name)
+				paths[name] = file
+				break
+			}
+		}
+	}
+	if typ := gbl.Type().(*types.Pointer).Elem(); !isEmbedFS(typ) {
+		if len(names) != 1 {
+			panic(fmt.Sprintf("%v: invalid go:embed: %d files for %v, want 1", pos, len(names), typ))
+		}
+	} else {
+		dirs := make(map[string]bool)
+		for _, name := range names {
+			for dir := path.Dir(name); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+				dirs[dir] = true
+			}
+		}
+		for dir := range dirs {
+			names = append(names, dir+"/")
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return embedFileLess(names[i], names[j])
+		})
+	}
+	files := make([]llssa.EmbedFile, len(names))
+	for i, name := range names {
+		files[i].Name = name
+		if file, ok := paths[name]; ok {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				panic(fmt.Sprintf("%v: go:embed: %v", pos, err))
+			}
+			files[i].Data = data
+		}
+	}
+	pkg.InitEmbed(g, files)
+}
+
+// -----------------------------------------------------------------------------
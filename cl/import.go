@@ -184,6 +184,7 @@ func (p *context) initFiles(pkgPath string, files []*ast.File) {
 			case *ast.GenDecl:
 				switch decl.Tok {
 				case token.VAR:
+					p.initEmbeds(pkgPath, file, decl)
 					if len(decl.Specs) == 1 {
 						if names := decl.Specs[0].(*ast.ValueSpec).Names; len(names) == 1 {
 							inPkgName := names[0].Name
@@ -270,6 +271,9 @@ func (p *context) initLinknameByDoc(doc *ast.CommentGroup, fullName, inPkgName s
 	if doc != nil {
 		for n := len(doc.List) - 1; n >= 0; n-- {
 			line := doc.List[n].Text
+			if !isVar && p.initWasmDirective(line, fullName) {
+				continue
+			}
 			found := p.initLinkname(line, func(name string) (_ string, _, ok bool) {
 				return fullName, isVar, name == inPkgName
 			})
@@ -307,6 +311,35 @@ func (p *context) initLinkname(line string, f func(inPkgName string) (fullName s
 	return false
 }
 
+// initWasmDirective handles the directives of a WebAssembly import or export:
+//
+//	//go:wasmimport module name
+//	//go:wasmexport name
+func (p *context) initWasmDirective(line, fullName string) bool {
+	const (
+		wasmimport = "//go:wasmimport "
+		wasmexport = "//go:wasmexport "
+	)
+	if strings.HasPrefix(line, wasmimport) {
+		if args := strings.Fields(line[len(wasmimport):]); len(args) == 2 {
+			p.wasmImports[fullName] = wasmImport{args[0], args[1]}
+		} else {
+			fmt.Fprintln(os.Stderr, "==>", line)
+			fmt.Fprintln(os.Stderr, "llgo: usage: //go:wasmimport module name")
+		}
+		return true
+	} else if strings.HasPrefix(line, wasmexport) {
+		if args := strings.Fields(line[len(wasmexport):]); len(args) == 1 {
+			p.wasmExports[fullName] = args[0]
+		} else {
+			fmt.Fprintln(os.Stderr, "==>", line)
+			fmt.Fprintln(os.Stderr, "llgo: usage: //go:wasmexport name")
+		}
+		return true
+	}
+	return false
+}
+
 func (p *context) initCgoExport(line string, prefix int, f func(inPkgName string) (fullName string, isVar, ok bool)) {
 	name := strings.TrimSpace(line[prefix:])
 	if fullName, _, ok := f(name); ok {
@@ -457,7 +490,8 @@ const (
 	llgoSigsetjmp  = llgoInstrBase + 0xc
 	llgoSiglongjmp = llgoInstrBase + 0xd
 
-	llgoFuncAddr = llgoInstrBase + 0xe
+	llgoFuncAddr      = llgoInstrBase + 0xe
+	llgoReturnAddress = llgoInstrBase + 0xf
 
 	llgoPyList  = llgoInstrBase + 0x10
 	llgoPyStr   = llgoInstrBase + 0x11
@@ -214,7 +214,7 @@ func (p *context) funcAddr(b llssa.Builder, args []ssa.Value) llssa.Expr {
 			switch f := fn.X.(type) {
 			case *ssa.Function:
 				if aFn, _, _ := p.compileFunction(f); aFn != nil {
-					return aFn.Expr
+					return b.CFuncAddr(aFn.Expr)
 				}
 			default:
 				v := p.compileValue(b, f)
@@ -286,24 +286,25 @@ func (p *context) atomicCmpXchg(b llssa.Builder, args []ssa.Value) llssa.Expr {
 // -----------------------------------------------------------------------------
 
 var llgoInstrs = map[string]int{
-	"cstr":        llgoCstr,
-	"advance":     llgoAdvance,
-	"index":       llgoIndex,
-	"alloca":      llgoAlloca,
-	"allocCStr":   llgoAllocCStr,
-	"allocaCStr":  llgoAllocaCStr,
-	"allocaCStrs": llgoAllocaCStrs,
-	"string":      llgoString,
-	"stringData":  llgoStringData,
-	"funcAddr":    llgoFuncAddr,
-	"pystr":       llgoPyStr,
-	"pyList":      llgoPyList,
-	"pyTuple":     llgoPyTuple,
-	"sigjmpbuf":   llgoSigjmpbuf,
-	"sigsetjmp":   llgoSigsetjmp,
-	"siglongjmp":  llgoSiglongjmp,
-	"deferData":   llgoDeferData,
-	"unreachable": llgoUnreachable,
+	"cstr":          llgoCstr,
+	"advance":       llgoAdvance,
+	"index":         llgoIndex,
+	"alloca":        llgoAlloca,
+	"allocCStr":     llgoAllocCStr,
+	"allocaCStr":    llgoAllocaCStr,
+	"allocaCStrs":   llgoAllocaCStrs,
+	"string":        llgoString,
+	"stringData":    llgoStringData,
+	"funcAddr":      llgoFuncAddr,
+	"pystr":         llgoPyStr,
+	"pyList":        llgoPyList,
+	"pyTuple":       llgoPyTuple,
+	"sigjmpbuf":     llgoSigjmpbuf,
+	"sigsetjmp":     llgoSigsetjmp,
+	"siglongjmp":    llgoSiglongjmp,
+	"deferData":     llgoDeferData,
+	"returnAddress": llgoReturnAddress,
+	"unreachable":   llgoUnreachable,
 
 	"atomicLoad":    llgoAtomicLoad,
 	"atomicStore":   llgoAtomicStore,
@@ -366,6 +367,21 @@ func (p *context) funcOf(fn *ssa.Function) (aFn llssa.Function, pyFn llssa.PyObj
 	return
 }
 
+// pyNoGIL are the functions of the Python C API called before the interpreter
+// is initialized, so without the GIL.
+var pyNoGIL = map[string]bool{
+	"Py_SetProgramName": true,
+	"Py_Initialize":     true,
+	"Py_InitializeEx":   true,
+}
+
+// needPyGIL reports whether the C function aFn, declared by fn, is a function
+// of the Python C API which must hold the GIL.
+func needPyGIL(fn *ssa.Function, aFn llssa.Function) bool {
+	pkg := fn.Pkg
+	return pkg != nil && pkg.Pkg.Path() == llssa.PkgPython && !pyNoGIL[aFn.Name()]
+}
+
 // -----------------------------------------------------------------------------
 
 const (
@@ -439,6 +455,18 @@ func (p *context) call(b llssa.Builder, act llssa.DoAction, call *ssa.CallCommon
 			p.inCFunc = true
 			args := p.compileValues(b, args, kind)
 			p.inCFunc = false
+			if needPyGIL(cv, aFn) {
+				if act == llssa.Call {
+					state := b.PyGILEnsure()
+					ret = b.Call(aFn.Expr, args...)
+					if !llssa.IsPyFinalize(aFn.Name()) {
+						b.PyGILRelease(state)
+					}
+					break
+				}
+				// the GIL is acquired when the function runs
+				aFn = p.pkg.PyGILFunc(aFn, cv.Signature, args)
+			}
 			ret = b.Do(act, aFn.Expr, args...)
 		case goFunc:
 			args := p.compileValues(b, args, kind)
@@ -502,6 +530,8 @@ func (p *context) call(b llssa.Builder, act llssa.DoAction, call *ssa.CallCommon
 			ret = b.AllocaSigjmpBuf()
 		case llgoDeferData: // func deferData() *Defer
 			ret = b.DeferData()
+		case llgoReturnAddress: // func returnAddress() unsafe.Pointer
+			ret = b.ReturnAddress()
 		case llgoFuncAddr:
 			ret = p.funcAddr(b, args)
 		case llgoUnreachable: // func unreachable()
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bind implements the "llgo bind" command.
+package bind
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/internal/cbind"
+	"github.com/goplus/llgo/internal/mockable"
+	"github.com/goplus/llgo/xtool/env/llvm"
+)
+
+// llgo bind
+var Cmd = &base.Command{
+	UsageLine: "llgo bind",
+	Short:     "Generate Go packages binding foreign libraries",
+	Commands:  []*base.Command{CCmd, CppCmd},
+}
+
+// llgo bind c
+var CCmd = &base.Command{
+	UsageLine: "llgo bind c [-o file] [-p package] [-trim prefix] [-I dir] [-D name[=value]] [-L dir] [-l lib] header...",
+	Short:     "Generate the Go package binding a C library from its headers",
+}
+
+// llgo bind cpp
+var CppCmd = &base.Command{
+	UsageLine: "llgo bind cpp [-o file] [-p package] [-trim prefix] [-I dir] [-D name[=value]] [-L dir] [-l lib] header...",
+	Short:     "Generate the Go package binding a C++ library from its headers",
+}
+
+func init() {
+	CCmd.Run = runCmd
+	CppCmd.Run = runCmd
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	conf := &cbind.Config{Cpp: cmd == CppCmd}
+	output := ""
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+		if !strings.HasPrefix(arg, "-") {
+			conf.Headers = append(conf.Headers, arg)
+			continue
+		}
+		// -I, -D, -L and -l take their value joined or separated, as in clang
+		name, value := arg, ""
+		switch {
+		case len(arg) > 2 && strings.IndexByte("IDLl", arg[1]) >= 0:
+			name, value = arg[:2], arg[2:]
+		case len(args) > 0:
+			value = args[0]
+			args = args[1:]
+		default:
+			usage(cmd)
+		}
+		switch name {
+		case "-o":
+			output = value
+		case "-p":
+			conf.Package = value
+		case "-trim":
+			conf.Trim = append(conf.Trim, value)
+		case "-I", "-D":
+			conf.Flags = append(conf.Flags, name+value)
+		case "-L", "-l":
+			conf.Link = append(conf.Link, name+value)
+		default:
+			usage(cmd)
+		}
+	}
+	if len(conf.Headers) == 0 {
+		usage(cmd)
+	}
+	conf.Clang = llvm.New("").Clang()
+
+	src, err := cbind.Gen(conf)
+	if err == nil {
+		if output == "" {
+			_, err = os.Stdout.Write(src)
+		} else {
+			err = os.WriteFile(output, src, 0644)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		mockable.Exit(1)
+	}
+}
+
+func usage(cmd *base.Command) {
+	fmt.Fprintf(os.Stderr, "usage: %s\n", cmd.UsageLine)
+	mockable.Exit(2)
+}
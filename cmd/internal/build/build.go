@@ -47,7 +47,12 @@ func runCmd(cmd *base.Command, args []string) {
 
 	conf := build.NewDefaultConf(build.ModeBuild)
 	conf.Tags = flags.Tags
+	conf.Target = flags.Target
 	conf.Verbose = flags.Verbose
+	conf.OptLevel = flags.OptLevel
+	conf.LTO = flags.LTO
+	conf.PGO = flags.PGO
+	conf.PGOInstrument = flags.PGOInstrument
 	conf.OutFile = flags.OutputFile
 
 	args = cmd.Flag.Args()
@@ -42,6 +42,7 @@ func runCmd(cmd *base.Command, args []string) {
 
 	conf := build.NewDefaultConf(0)
 	conf.Tags = flags.Tags
+	conf.Target = flags.Target
 	conf.Verbose = flags.Verbose
 
 	args = cmd.Flag.Args()
@@ -13,11 +13,43 @@ func AddOutputFlags(fs *flag.FlagSet) {
 var Verbose bool
 var BuildEnv string
 var Tags string
+var Target string
+
+var OptLevel string
+var LTO string
+var PGO string
+var PGOInstrument bool
+
+// optFlag is a boolean flag such as -O2 that sets OptLevel.
+type optFlag string
+
+func (f optFlag) String() string   { return "" }
+func (f optFlag) IsBoolFlag() bool { return true }
+
+func (f optFlag) Set(v string) error {
+	if v == "true" {
+		OptLevel = string(f)
+	}
+	return nil
+}
 
 func AddBuildFlags(fs *flag.FlagSet) {
 	fs.BoolVar(&Verbose, "v", false, "Verbose mode")
 	fs.StringVar(&Tags, "tags", "", "Build tags")
 	fs.StringVar(&BuildEnv, "buildenv", "", "Build environment")
+	fs.StringVar(&Target, "target", "", "Target definition: a name in $LLGO_TARGETS or $LLGO_ROOT/targets, or a .json/.toml file")
+	for _, level := range []string{"0", "1", "2", "3", "s", "z"} {
+		fs.Var(optFlag(level), "O"+level, "Optimization level "+level)
+	}
+	fs.StringVar(&LTO, "lto", "", "Link time optimization: thin or full")
+	fs.StringVar(&PGO, "pgo", "", "Profile for profile-guided optimization: a pprof CPU profile or an LLVM .profdata file")
+	fs.BoolVar(&PGOInstrument, "pgo-instrument", false, "Instrument the program to write an LLVM profile for -pgo")
+}
+
+var Exec string
+
+func AddExecFlags(fs *flag.FlagSet) {
+	fs.StringVar(&Exec, "exec", "", "Run programs using xprog, as 'xprog program args...'")
 }
 
 var Gen bool
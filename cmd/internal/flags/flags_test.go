@@ -0,0 +1,46 @@
+//go:build !llgo
+// +build !llgo
+
+package flags
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestOptFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		opt, lto string
+	}{
+		{nil, "", ""},
+		{[]string{"-O0"}, "0", ""},
+		{[]string{"-O2"}, "2", ""},
+		{[]string{"-Oz"}, "z", ""},
+		{[]string{"-O1", "-O3"}, "3", ""},
+		{[]string{"-O2=false"}, "", ""},
+		{[]string{"-lto", "thin"}, "", "thin"},
+		{[]string{"-Os", "-lto=full"}, "s", "full"},
+	}
+	for _, tt := range tests {
+		OptLevel, LTO = "", ""
+		fs := flag.NewFlagSet("build", flag.ContinueOnError)
+		AddBuildFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if OptLevel != tt.opt || LTO != tt.lto {
+			t.Errorf("%v: OptLevel = %q, LTO = %q, want %q, %q", tt.args, OptLevel, LTO, tt.opt, tt.lto)
+		}
+	}
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	fs.SetOutput(discard{})
+	AddBuildFlags(fs)
+	if err := fs.Parse([]string{"-O4"}); err == nil {
+		t.Error("-O4: no error")
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
@@ -46,7 +46,12 @@ func runCmd(cmd *base.Command, args []string) {
 
 	conf := build.NewDefaultConf(build.ModeInstall)
 	conf.Tags = flags.Tags
+	conf.Target = flags.Target
 	conf.Verbose = flags.Verbose
+	conf.OptLevel = flags.OptLevel
+	conf.LTO = flags.LTO
+	conf.PGO = flags.PGO
+	conf.PGOInstrument = flags.PGOInstrument
 
 	args = cmd.Flag.Args()
 	_, err := build.Do(args, conf)
@@ -34,13 +34,13 @@ var (
 
 // llgo run
 var Cmd = &base.Command{
-	UsageLine: "llgo run [build flags] package [arguments...]",
+	UsageLine: "llgo run [-exec xprog] [build flags] package [arguments...]",
 	Short:     "Compile and run Go program",
 }
 
 // llgo cmptest
 var CmpTestCmd = &base.Command{
-	UsageLine: "llgo cmptest [-gen] [build flags] package [arguments...]",
+	UsageLine: "llgo cmptest [-gen] [-exec xprog] [build flags] package [arguments...]",
 	Short:     "Compile and run with llgo, compare result (stdout/stderr/exitcode) with go or llgo.expect; generate llgo.expect file if -gen is specified",
 }
 
@@ -48,7 +48,9 @@ func init() {
 	Cmd.Run = runCmd
 	CmpTestCmd.Run = runCmpTest
 	flags.AddBuildFlags(&Cmd.Flag)
+	flags.AddExecFlags(&Cmd.Flag)
 	flags.AddBuildFlags(&CmpTestCmd.Flag)
+	flags.AddExecFlags(&CmpTestCmd.Flag)
 	flags.AddCmpTestFlags(&CmpTestCmd.Flag)
 }
 
@@ -68,7 +70,13 @@ func runCmdEx(cmd *base.Command, args []string, mode build.Mode) {
 
 	conf := build.NewDefaultConf(mode)
 	conf.Tags = flags.Tags
+	conf.Target = flags.Target
 	conf.Verbose = flags.Verbose
+	conf.OptLevel = flags.OptLevel
+	conf.LTO = flags.LTO
+	conf.PGO = flags.PGO
+	conf.PGOInstrument = flags.PGOInstrument
+	conf.Exec = flags.Exec
 	conf.GenExpect = flags.Gen
 
 	args = cmd.Flag.Args()
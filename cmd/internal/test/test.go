@@ -11,13 +11,14 @@ import (
 
 // llgo test
 var Cmd = &base.Command{
-	UsageLine: "llgo test [build flags] package [arguments...]",
+	UsageLine: "llgo test [-exec xprog] [build flags] package [arguments...]",
 	Short:     "Compile and run Go test",
 }
 
 func init() {
 	Cmd.Run = runCmd
 	flags.AddBuildFlags(&Cmd.Flag)
+	flags.AddExecFlags(&Cmd.Flag)
 }
 
 func runCmd(cmd *base.Command, args []string) {
@@ -28,7 +29,13 @@ func runCmd(cmd *base.Command, args []string) {
 
 	conf := build.NewDefaultConf(build.ModeTest)
 	conf.Tags = flags.Tags
+	conf.Target = flags.Target
 	conf.Verbose = flags.Verbose
+	conf.OptLevel = flags.OptLevel
+	conf.LTO = flags.LTO
+	conf.PGO = flags.PGO
+	conf.PGOInstrument = flags.PGOInstrument
+	conf.Exec = flags.Exec
 
 	args = cmd.Flag.Args()
 	_, err := build.Do(args, conf)
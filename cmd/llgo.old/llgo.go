@@ -25,6 +25,7 @@ import (
 	"github.com/qiniu/x/log"
 
 	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/cmd/internal/bind"
 	"github.com/goplus/llgo/cmd/internal/build"
 	"github.com/goplus/llgo/cmd/internal/clean"
 	"github.com/goplus/llgo/cmd/internal/get"
@@ -50,6 +51,7 @@ func init() {
 		run.CmpTestCmd,
 		test.Cmd,
 		clean.Cmd,
+		bind.Cmd,
 		version.Cmd,
 	}
 }
@@ -5,6 +5,7 @@ package main
 import (
 	"fmt"
 	"github.com/goplus/cobra/xcmd"
+	"github.com/goplus/llgo/cmd/internal/bind"
 	"github.com/goplus/llgo/cmd/internal/build"
 	"github.com/goplus/llgo/cmd/internal/clean"
 	"github.com/goplus/llgo/cmd/internal/install"
@@ -17,6 +18,18 @@ import (
 
 const _ = true
 
+type Cmd_bind struct {
+	xcmd.Command
+	*App
+}
+type Cmd_bind_c struct {
+	xcmd.Command
+	*App
+}
+type Cmd_bind_cpp struct {
+	xcmd.Command
+	*App
+}
 type Cmd_build struct {
 	xcmd.Command
 	*App
@@ -58,15 +71,65 @@ func (this *App) MainEntry() {
 	this.Short(`llgo is a Go compiler based on LLVM in order to better integrate Go with the C ecosystem including Python.`)
 }
 func (this *App) Main() {
-	_gop_obj0 := &Cmd_build{App: this}
-	_gop_obj1 := &Cmd_clean{App: this}
-	_gop_obj2 := &Cmd_cmptest{App: this}
-	_gop_obj3 := &Cmd_get{App: this}
-	_gop_obj4 := &Cmd_install{App: this}
-	_gop_obj5 := &Cmd_run{App: this}
-	_gop_obj6 := &Cmd_test{App: this}
-	_gop_obj7 := &Cmd_version{App: this}
-	xcmd.Gopt_App_Main(this, _gop_obj0, _gop_obj1, _gop_obj2, _gop_obj3, _gop_obj4, _gop_obj5, _gop_obj6, _gop_obj7)
+	_gop_obj0 := &Cmd_bind{App: this}
+	_gop_obj1 := &Cmd_bind_c{App: this}
+	_gop_obj2 := &Cmd_bind_cpp{App: this}
+	_gop_obj3 := &Cmd_build{App: this}
+	_gop_obj4 := &Cmd_clean{App: this}
+	_gop_obj5 := &Cmd_cmptest{App: this}
+	_gop_obj6 := &Cmd_get{App: this}
+	_gop_obj7 := &Cmd_install{App: this}
+	_gop_obj8 := &Cmd_run{App: this}
+	_gop_obj9 := &Cmd_test{App: this}
+	_gop_obj10 := &Cmd_version{App: this}
+	xcmd.Gopt_App_Main(this, _gop_obj0, _gop_obj1, _gop_obj2, _gop_obj3, _gop_obj4, _gop_obj5, _gop_obj6, _gop_obj7, _gop_obj8, _gop_obj9, _gop_obj10)
+}
+//line cmd/llgo/bind_cmd.gox:16
+func (this *Cmd_bind) Main(_gop_arg0 string) {
+	this.Command.Main(_gop_arg0)
+//line cmd/llgo/bind_cmd.gox:16:1
+	this.Use("bind [command]")
+//line cmd/llgo/bind_cmd.gox:18:1
+	this.Short("Generate Go packages binding foreign libraries")
+}
+func (this *Cmd_bind) Classfname() string {
+	return "bind"
+}
+//line cmd/llgo/bind_c_cmd.gox:20
+func (this *Cmd_bind_c) Main(_gop_arg0 string) {
+	this.Command.Main(_gop_arg0)
+//line cmd/llgo/bind_c_cmd.gox:20:1
+	this.Use("c [flags] header...")
+//line cmd/llgo/bind_c_cmd.gox:22:1
+	this.Short("Generate the Go package binding a C library from its headers")
+//line cmd/llgo/bind_c_cmd.gox:24:1
+	this.FlagOff()
+//line cmd/llgo/bind_c_cmd.gox:26:1
+	this.Run__1(func(args []string) {
+//line cmd/llgo/bind_c_cmd.gox:27:1
+		bind.CCmd.Run(bind.CCmd, args)
+	})
+}
+func (this *Cmd_bind_c) Classfname() string {
+	return "bind_c"
+}
+//line cmd/llgo/bind_cpp_cmd.gox:20
+func (this *Cmd_bind_cpp) Main(_gop_arg0 string) {
+	this.Command.Main(_gop_arg0)
+//line cmd/llgo/bind_cpp_cmd.gox:20:1
+	this.Use("cpp [flags] header...")
+//line cmd/llgo/bind_cpp_cmd.gox:22:1
+	this.Short("Generate the Go package binding a C++ library from its headers")
+//line cmd/llgo/bind_cpp_cmd.gox:24:1
+	this.FlagOff()
+//line cmd/llgo/bind_cpp_cmd.gox:26:1
+	this.Run__1(func(args []string) {
+//line cmd/llgo/bind_cpp_cmd.gox:27:1
+		bind.CppCmd.Run(bind.CppCmd, args)
+	})
+}
+func (this *Cmd_bind_cpp) Classfname() string {
+	return "bind_cpp"
 }
 //line cmd/llgo/build_cmd.gox:20
 func (this *Cmd_build) Main(_gop_arg0 string) {
@@ -1,6 +1,6 @@
 module github.com/goplus/llgo
 
-go 1.23
+go 1.23.0
 
 toolchain go1.24.1
 
@@ -15,6 +15,8 @@ require (
 	golang.org/x/tools v0.30.0
 )
 
+require github.com/tetratelabs/wazero v1.10.1
+
 require (
 	golang.org/x/mod v0.23.0 // indirect
 	golang.org/x/sync v0.11.0 // indirect
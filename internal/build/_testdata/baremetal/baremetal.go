@@ -0,0 +1,14 @@
+// Package baremetal is built for a bare-metal target by TestBuildBaremetal.
+// It is not linked, as that needs the compiler-rt builtins of the target.
+package baremetal
+
+const LLGoFiles = "hal.c"
+
+func Hello() {
+	done := make(chan int)
+	go func() {
+		println("hello, bare metal")
+		done <- 1
+	}()
+	<-done
+}
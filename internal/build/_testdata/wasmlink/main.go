@@ -0,0 +1,18 @@
+package main
+
+import _ "unsafe"
+
+var callMissing bool
+
+// missing is defined neither by wasi-libc nor by //go:wasmimport: the
+// program links and runs as long as it is not called.
+//
+//go:linkname missing C.llgo_wasmlink_missing
+func missing()
+
+func main() {
+	if callMissing {
+		missing()
+	}
+	println("hello, wasip1")
+}
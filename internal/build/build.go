@@ -41,6 +41,8 @@ import (
 	"github.com/goplus/llgo/internal/env"
 	"github.com/goplus/llgo/internal/mockable"
 	"github.com/goplus/llgo/internal/packages"
+	"github.com/goplus/llgo/internal/pgo"
+	"github.com/goplus/llgo/internal/targets"
 	"github.com/goplus/llgo/internal/typepatch"
 	llvmTarget "github.com/goplus/llgo/internal/xtool/llvm"
 	"github.com/goplus/llgo/ssa/abi"
@@ -67,16 +69,22 @@ const (
 )
 
 type Config struct {
-	Goos      string
-	Goarch    string
-	BinPath   string
-	AppExt    string   // ".exe" on Windows, empty on Unix
-	OutFile   string   // only valid for ModeBuild when len(pkgs) == 1
-	RunArgs   []string // only valid for ModeRun
-	Mode      Mode
-	GenExpect bool // only valid for ModeCmpTest
-	Verbose   bool
-	Tags      string
+	Goos          string
+	Goarch        string
+	BinPath       string
+	AppExt        string   // ".exe" on Windows, empty on Unix
+	OutFile       string   // only valid for ModeBuild when len(pkgs) == 1
+	RunArgs       []string // only valid for ModeRun
+	Mode          Mode
+	GenExpect     bool // only valid for ModeCmpTest
+	Verbose       bool
+	Tags          string
+	OptLevel      string // "0", "1", "2", "3", "s" or "z"; empty means no optimization pipeline
+	LTO           string // "thin" or "full"; empty disables link time optimization
+	PGO           string // profile for profile-guided optimization; empty or "off" disables it
+	PGOInstrument bool   // instrument the program to collect an LLVM profile
+	Target        string // name or file of a target definition, see internal/targets
+	Exec          string // command to run programs with, as go test -exec; only valid for ModeRun, ModeTest and ModeCmpTest
 }
 
 func NewDefaultConf(mode Mode) *Config {
@@ -145,21 +153,38 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	if conf.Goarch == "" {
 		conf.Goarch = runtime.GOARCH
 	}
+	switch conf.OptLevel {
+	case "", "0", "1", "2", "3", "s", "z":
+	default:
+		return nil, fmt.Errorf("invalid optimization level: -O%s", conf.OptLevel)
+	}
+	switch conf.LTO {
+	case "", "thin", "full":
+	default:
+		return nil, fmt.Errorf("invalid -lto mode %q: must be thin or full", conf.LTO)
+	}
+	spec, err := loadTarget(conf)
+	if err != nil {
+		return nil, err
+	}
 	verbose := conf.Verbose
 	patterns := args
-	tags := "llgo"
-	if conf.Tags != "" {
-		tags += "," + conf.Tags
+	crypto, err := cryptoBackend(conf.Goos, spec)
+	if err != nil {
+		return nil, err
 	}
 	cfg := &packages.Config{
-		Mode:       loadSyntax | packages.NeedDeps | packages.NeedModule | packages.NeedExportFile,
-		BuildFlags: []string{"-tags=" + tags},
+		Mode:       loadSyntax | packages.NeedDeps | packages.NeedModule | packages.NeedExportFile | packages.NeedEmbedFiles,
+		BuildFlags: []string{"-tags=" + buildTags(conf, crypto)},
 		Fset:       token.NewFileSet(),
 		Tests:      conf.Mode == ModeTest,
 	}
 	if conf.Mode == ModeTest {
 		cfg.Mode |= packages.NeedForTest
 	}
+	if spec != nil {
+		cfg.Env = append(os.Environ(), "GOOS="+conf.Goos, "GOARCH="+conf.Goarch)
+	}
 
 	cfg.Overlay = make(map[string][]byte)
 	clearRuntime(cfg.Overlay, filepath.Join(env.GOROOT(), "src", "runtime"))
@@ -177,6 +202,10 @@ func Do(args []string, conf *Config) ([]Package, error) {
 		GOOS:   conf.Goos,
 		GOARCH: conf.Goarch,
 	}
+	if spec != nil {
+		target.Triple, target.CPU, target.Features = spec.Triple, spec.CPU, spec.Features
+		target.RelocModel = spec.RelocationModel
+	}
 
 	prog := llssa.NewProgram(target)
 	sizes := func(sizes types.Sizes, compiler, arch string) types.Sizes {
@@ -218,7 +247,18 @@ func Do(args []string, conf *Config) ([]Package, error) {
 		}
 	}
 
-	altPkgPaths := altPkgs(initial, llssa.PkgRuntime)
+	var mainPkgs []string
+	for _, pkg := range initial {
+		if pkg.Name == "main" {
+			mainPkgs = append(mainPkgs, pkg.PkgPath)
+		}
+	}
+	emitOpts, pgoProfile, err := emitOptions(conf, mainPkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	altPkgPaths := altPkgs(initial, crypto, llssa.PkgRuntime)
 	cfg.Dir = env.LLGoRuntimeDir()
 	altPkgs, err := packages.LoadEx(dedup, sizes, cfg, altPkgPaths...)
 	check(err)
@@ -231,6 +271,9 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	prog.SetPython(func() *types.Package {
 		return dedup.Check(llssa.PkgPython).Types
 	})
+	prog.SetPyExceptions(IsPyExceptionsEnabled())
+	prog.SetPyGIL(IsPyGILEnabled())
+	prog.SetCABI(IsCABIEnabled())
 
 	buildMode := ssaBuildMode
 	if IsDbgEnabled() {
@@ -249,10 +292,16 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	output := conf.OutFile != ""
 	export, err := crosscompile.UseCrossCompileSDK(conf.Goos, conf.Goarch, IsWasiThreadsEnabled())
 	check(err)
-	ctx := &context{env, cfg, progSSA, prog, dedup, patches, make(map[string]none), initial, mode, 0, output, make(map[*packages.Package]bool), make(map[*packages.Package]bool), conf, export}
+	ctx := &context{env, cfg, progSSA, prog, dedup, patches, make(map[string]none), initial, mode, 0, output, make(map[*packages.Package]bool), make(map[*packages.Package]bool), conf, export, newObjEmitter(target, emitOpts), spec, pgoProfile, crypto}
+	if pgoProfile != "" {
+		ctx.objs.compile = func(bitcode []byte, objFile string) error {
+			return pgoCompile(ctx, bitcode, objFile)
+		}
+	}
 	pkgs, err := buildAllPkgs(ctx, initial, verbose)
 	check(err)
 	if mode == ModeGen {
+		check(ctx.objs.wait())
 		for _, pkg := range pkgs {
 			if pkg.Package == initial[0] {
 				return []*aPackage{pkg}, nil
@@ -263,6 +312,7 @@ func Do(args []string, conf *Config) ([]Package, error) {
 
 	dpkg, err := buildAllPkgs(ctx, altPkgs[noRt:], verbose)
 	check(err)
+	check(ctx.objs.wait())
 	allPkgs := append([]*aPackage{}, pkgs...)
 	allPkgs = append(allPkgs, dpkg...)
 
@@ -332,6 +382,12 @@ type context struct {
 
 	buildConf    *Config
 	crossCompile crosscompile.Export
+
+	objs *objEmitter
+	spec *targets.Target // nil if no -target is specified
+
+	pgoProfile string // LLVM instrumentation profile of -pgo
+	crypto     string // crypto backend, cryptoOpenSSL or cryptoGo
 }
 
 func buildAllPkgs(ctx *context, initial []*packages.Package, verbose bool) (pkgs []*aPackage, err error) {
@@ -496,66 +552,64 @@ func linkMainPkg(ctx *context, pkg *packages.Package, pkgs []*aPackage, conf *Co
 		check(err)
 	}
 
+	if conf.Goos == "js" {
+		_, err = writeJSLoader(app)
+		check(err)
+	}
+
 	switch mode {
 	case ModeTest:
-		cmd := exec.Command(app, conf.RunArgs...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Run()
-		if s := cmd.ProcessState; s != nil {
-			fmt.Fprintf(os.Stderr, "%s: exit code %d\n", app, s.ExitCode())
-		}
-	case ModeRun:
-		args := make([]string, 0, len(conf.RunArgs)+1)
-		copy(args, conf.RunArgs)
-		if isWasmTarget(conf.Goos) {
-			wasmer := os.ExpandEnv(WasmRuntime())
-			wasmerArgs := strings.Split(wasmer, " ")
-			wasmerCmd := wasmerArgs[0]
-			wasmerArgs = wasmerArgs[1:]
-			switch wasmer {
-			case "wasmtime":
-				args = append(args, "--wasm", "multi-memory=true", app)
-				args = append(args, conf.RunArgs...)
-			case "iwasm":
-				args = append(args, "--stack-size=819200000", "--heap-size=800000000", app)
-				args = append(args, conf.RunArgs...)
-			default:
-				args = append(args, wasmerArgs...)
-				args = append(args, app)
-				args = append(args, conf.RunArgs...)
-			}
-			app = wasmerCmd
+		err = newRunner(conf, ctx.crossCompile).run(app, conf.RunArgs, "", nil, os.Stdout, os.Stderr)
+		if code, ok := exitCode(err); ok {
+			fmt.Fprintf(os.Stderr, "%s: exit code %d\n", app, code)
 		} else {
-			args = conf.RunArgs
+			fmt.Fprintf(os.Stderr, "%s: %v\n", app, err)
 		}
-		cmd := exec.Command(app, args...)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err = cmd.Run()
-		if err != nil {
+	case ModeRun:
+		err = newRunner(conf, ctx.crossCompile).run(app, conf.RunArgs, "", os.Stdin, os.Stdout, os.Stderr)
+		code, ok := exitCode(err)
+		if !ok {
 			panic(err)
 		}
-		if s := cmd.ProcessState; s != nil {
-			mockable.Exit(s.ExitCode())
-		}
+		mockable.Exit(code)
 	case ModeCmpTest:
-		cmpTest(filepath.Dir(pkg.GoFiles[0]), pkgPath, app, conf.GenExpect, conf.RunArgs)
+		cmpTest(newRunner(conf, ctx.crossCompile), filepath.Dir(pkg.GoFiles[0]), pkgPath, app, conf.GenExpect, conf.RunArgs)
 	}
 }
 
 func compileAndLinkLLFiles(ctx *context, app string, llFiles, linkArgs []string, verbose bool) error {
+	cmd := ctx.env.Clang()
+	cmd.Verbose = verbose
+	return cmd.Link(linkCmdArgs(ctx, app, llFiles, linkArgs, verbose)...)
+}
+
+// linkCmdArgs returns the arguments of clang to link llFiles into app.
+func linkCmdArgs(ctx *context, app string, llFiles, linkArgs []string, verbose bool) []string {
 	buildArgs := []string{"-o", app}
 	buildArgs = append(buildArgs, linkArgs...)
 
 	// Add common linker arguments based on target OS and architecture
-	targetTriple := llvmTarget.GetTargetTriple(ctx.buildConf.Goos, ctx.buildConf.Goarch)
-	buildArgs = append(buildArgs, buildLdflags(ctx.buildConf.Goos, ctx.buildConf.Goarch, targetTriple)...)
+	if ctx.spec != nil {
+		buildArgs = append(buildArgs, ctx.spec.LinkFlags()...)
+		if ctx.spec.Libc == "none" {
+			// -nostdlib drops compiler-rt too, but the C and LLVM code still
+			// call its builtins (eg. __aeabi_uldivmod).
+			if lib := builtinsLib(ctx); lib != "" {
+				buildArgs = append(buildArgs, lib)
+			}
+		}
+	} else {
+		targetTriple := llvmTarget.GetTargetTriple(ctx.buildConf.Goos, ctx.buildConf.Goarch)
+		buildArgs = append(buildArgs, buildLdflags(ctx.buildConf.Goos, ctx.buildConf.Goarch, targetTriple)...)
+	}
 
 	if IsDbgSymsEnabled() {
 		buildArgs = append(buildArgs, "-gdwarf-4")
 	}
+	buildArgs = append(buildArgs, optFlags(ctx.buildConf)...)
+	if ctx.objs.opts.PGOInstrument {
+		buildArgs = append(buildArgs, "-fprofile-generate")
+	}
 
 	buildArgs = append(buildArgs, ctx.crossCompile.CCFLAGS...)
 	buildArgs = append(buildArgs, ctx.crossCompile.LDFLAGS...)
@@ -563,10 +617,90 @@ func compileAndLinkLLFiles(ctx *context, app string, llFiles, linkArgs []string,
 	if verbose {
 		buildArgs = append(buildArgs, "-v")
 	}
+	return buildArgs
+}
 
+// builtinsLib returns the compiler-rt builtins library of the target, or ""
+// if clang has none.
+func builtinsLib(ctx *context) string {
+	var out bytes.Buffer
 	cmd := ctx.env.Clang()
-	cmd.Verbose = verbose
-	return cmd.Link(buildArgs...)
+	cmd.Stdout = &out
+	args := append(ctx.spec.CompileFlags(), "--rtlib=compiler-rt", "-print-libgcc-file-name")
+	if err := cmd.Exec(args...); err != nil {
+		return ""
+	}
+	lib := strings.TrimSpace(out.String())
+	if _, err := os.Stat(lib); err != nil {
+		return ""
+	}
+	return lib
+}
+
+// loadTarget loads the target definition of conf.Target, if any, and makes
+// conf build for it.
+func loadTarget(conf *Config) (*targets.Target, error) {
+	if conf.Target == "" {
+		return nil, nil
+	}
+	spec, err := targets.Load(conf.Target, targets.Dirs())
+	if err != nil {
+		return nil, err
+	}
+	conf.Goos, conf.Goarch = spec.GOOS, spec.GOARCH
+	if len(spec.BuildTags) > 0 {
+		tags := strings.Join(spec.BuildTags, ",")
+		if conf.Tags != "" {
+			tags += "," + conf.Tags
+		}
+		conf.Tags = tags
+	}
+	return spec, nil
+}
+
+// emitOptions returns the options of the code generation of packages, and
+// the LLVM instrumentation profile of -pgo if any. mainPkgs are the import
+// paths of the main packages, whose functions a pprof profile names "main.F".
+func emitOptions(conf *Config, mainPkgs []string) (opts llssa.EmitOptions, profile string, err error) {
+	opts = llssa.EmitOptions{OptLevel: conf.OptLevel, LTO: conf.LTO, PGOInstrument: conf.PGOInstrument}
+	if conf.PGO == "" || conf.PGO == "off" {
+		return
+	}
+	if conf.PGOInstrument {
+		err = fmt.Errorf("-pgo and -pgo-instrument are mutually exclusive")
+		return
+	}
+	file, err := filepath.Abs(conf.PGO)
+	if err != nil {
+		return
+	}
+	prof, err := pgo.Load(file, mainPkgs)
+	if err != nil {
+		return
+	}
+	switch prof.Kind {
+	case pgo.Instr:
+		profile = file
+	case pgo.Pprof:
+		opts.FuncHotness = prof.Func
+	}
+	return
+}
+
+// optFlags returns the clang optimization flags for the link. With link time
+// optimization enabled, they make the linker optimize the program as a whole.
+func optFlags(conf *Config) []string {
+	opt := conf.OptLevel
+	if conf.LTO == "" {
+		if opt == "" {
+			return nil
+		}
+		return []string{"-O" + opt}
+	}
+	if opt == "" {
+		opt = "2"
+	}
+	return []string{"-flto=" + conf.LTO, "-O" + opt}
 }
 
 func buildCflags(goos, goarch, targetTriple string) []string {
@@ -614,8 +748,11 @@ func buildLdflags(goos, goarch, targetTriple string) []string {
 			// "-ffunction-sections",
 			// "-nostdlib",
 			// "-Wl,--no-entry",
-			"-Wl,--export-all",
-			"-Wl,--allow-undefined",
+			// Functions are exported by //go:wasmexport and imported by
+			// //go:wasmimport. Other undefined symbols (eg. C functions of
+			// LLGoPackage libraries missing from wasi-libc) are reported,
+			// and linked to stubs trapping if they are ever called.
+			"-Wl,--warn-unresolved-symbols",
 			"-Wl,--import-memory,", // unknown import: `env::memory` has not been defined
 			"-Wl,--export-memory",
 			"-Wl,--initial-memory=67108864", // 64MB
@@ -685,6 +822,10 @@ func genMainModuleFile(conf *Config, rtPkgPath, mainPkgPath string, needRuntime,
 	if needPyInit {
 		pyInit = "call void @Py_Initialize()"
 		pyInitDecl = "declare void @Py_Initialize()"
+		if isPyGILReleased() {
+			pyInit += "\n  call ptr @PyEval_SaveThread()"
+			pyInitDecl += "\ndeclare ptr @PyEval_SaveThread()"
+		}
 	}
 	declSizeT := "%size_t = type i64"
 	if is32Bits(conf.Goarch) {
@@ -789,7 +930,7 @@ func buildPkg(ctx *context, aPkg *aPackage, verbose bool) error {
 		cl.SetDebug(cl.DbgFlagAll)
 	}
 
-	ret, externs, err := cl.NewPackageEx(ctx.prog, ctx.patches, aPkg.SSA, syntax)
+	ret, externs, err := cl.NewPackageEx(ctx.prog, ctx.patches, aPkg.SSA, syntax, pkg.EmbedFiles)
 	if showDetail {
 		llssa.SetDebug(0)
 		cl.SetDebug(0)
@@ -813,15 +954,20 @@ func buildPkg(ctx *context, aPkg *aPackage, verbose bool) error {
 		aPkg.LinkArgs = append(aPkg.LinkArgs, altLdflags...)
 	}
 	if pkg.ExportFile != "" {
-		pkg.ExportFile += ".ll"
-		os.WriteFile(pkg.ExportFile, []byte(ret.String()), 0644)
-		aPkg.LLFiles = append(aPkg.LLFiles, pkg.ExportFile)
+		exportFile := pkg.ExportFile
+		pkg.ExportFile += ctx.objs.ext()
+		if ctx.mode != ModeGen { // llgen only needs the IR of the package
+			ctx.objs.emit(ret, pkg.ExportFile)
+			aPkg.LLFiles = append(aPkg.LLFiles, pkg.ExportFile)
+		}
 		if debugBuild || verbose {
 			fmt.Fprintf(os.Stderr, "==> Export %s: %s\n", aPkg.PkgPath, pkg.ExportFile)
 		}
 		if IsCheckEnable() {
-			if err, msg := llcCheck(ctx.env, pkg.ExportFile); err != nil {
-				fmt.Fprintf(os.Stderr, "==> lcc %v: %v\n%v\n", pkg.PkgPath, pkg.ExportFile, msg)
+			llFile := exportFile + ".ll"
+			os.WriteFile(llFile, []byte(ret.String()), 0644)
+			if err, msg := llcCheck(ctx.env, llFile); err != nil {
+				fmt.Fprintf(os.Stderr, "==> lcc %v: %v\n%v\n", pkg.PkgPath, llFile, msg)
 			}
 		}
 	}
@@ -841,12 +987,40 @@ func llcCheck(env *llvm.Env, exportFile string) (err error, msg string) {
 
 const (
 	altPkgPathPrefix = abi.PatchPathPrefix
+
+	// defaultTags are the build tags always satisfied by llgo builds.
+	// math_big_pure_go makes math/big use its Go implementation instead of
+	// assembly.
+	defaultTags = "llgo,math_big_pure_go"
 )
 
-func altPkgs(initial []*packages.Package, alts ...string) []string {
+// buildTags returns the build tags of a build with the given config and
+// crypto backend. The Go backend compiles the crypto packages from source:
+// purego selects their Go implementations instead of assembly.
+func buildTags(conf *Config, crypto string) string {
+	tags := defaultTags
+	if crypto == cryptoGo {
+		tags += ",purego"
+	}
+	if conf.Tags != "" {
+		tags += "," + conf.Tags
+	}
+	return tags
+}
+
+// hasAltPkg reports whether the package path is replaced by its alternative
+// package with the given crypto backend.
+func hasAltPkg(crypto, path string) bool {
+	if crypto == cryptoGo && llruntime.HasOpenSSLAltPkg(path) {
+		return false
+	}
+	return llruntime.HasAltPkg(path)
+}
+
+func altPkgs(initial []*packages.Package, crypto string, alts ...string) []string {
 	packages.Visit(initial, nil, func(p *packages.Package) {
 		if p.Types != nil && !p.IllTyped {
-			if llruntime.HasAltPkg(p.PkgPath) {
+			if hasAltPkg(crypto, p.PkgPath) {
 				alts = append(alts, altPkgPathPrefix+p.PkgPath)
 			}
 		}
@@ -896,7 +1070,7 @@ func allPkgs(ctx *context, initial []*packages.Package, verbose bool) (all []*aP
 			}
 			var altPkg *packages.Cached
 			var ssaPkg = createSSAPkg(prog, p, verbose)
-			if llruntime.HasAltPkg(pkgPath) {
+			if hasAltPkg(ctx.crypto, pkgPath) {
 				if altPkg = ctx.dedup.Check(altPkgPathPrefix + pkgPath); altPkg == nil {
 					return
 				}
@@ -949,8 +1123,19 @@ const llgoRpathChange = "LLGO_RPATH_CHANGE"
 const llgoWasmRuntime = "LLGO_WASM_RUNTIME"
 const llgoWasiThreads = "LLGO_WASI_THREADS"
 const llgoStdioNobuf = "LLGO_STDIO_NOBUF"
+const llgoCrypto = "LLGO_CRYPTO"
+const llgoPyExceptions = "LLGO_PY_EXCEPTIONS"
+const llgoPyGIL = "LLGO_PY_GIL"
+const llgoCABI = "LLGO_CABI"
+
+const (
+	cryptoOpenSSL = "openssl"
+	cryptoGo      = "go"
+)
+
+const pyGILRelease = "release" // LLGO_PY_GIL value to release the GIL while Go code runs
 
-const defaultWasmRuntime = "wasmtime"
+const defaultWasmRuntime = "wasmtime" // or "builtin", or a command line
 
 func defaultEnv(env string, defVal string) string {
 	envVal := os.Getenv(env)
@@ -968,6 +1153,22 @@ func isEnvOn(env string, defVal bool) bool {
 	return envVal == "1" || envVal == "true" || envVal == "on"
 }
 
+// cryptoBackend returns the implementation of the crypto packages selected by
+// LLGO_CRYPTO: "openssl" binds them to libcrypto, "go" compiles the upstream
+// Go sources. OpenSSL is the default, except for wasm and bare-metal targets
+// which cannot link it.
+func cryptoBackend(goos string, spec *targets.Target) (string, error) {
+	backend := cryptoOpenSSL
+	if isWasmTarget(goos) || (spec != nil && slices.Contains(spec.BuildTags, "baremetal")) {
+		backend = cryptoGo
+	}
+	switch backend = defaultEnv(llgoCrypto, backend); backend {
+	case cryptoOpenSSL, cryptoGo:
+		return backend, nil
+	}
+	return "", fmt.Errorf("invalid %s=%q: must be %s or %s", llgoCrypto, backend, cryptoOpenSSL, cryptoGo)
+}
+
 func IsTraceEnabled() bool {
 	return isEnvOn(llgoTrace, false)
 }
@@ -976,6 +1177,31 @@ func IsStdioNobuf() bool {
 	return isEnvOn(llgoStdioNobuf, false)
 }
 
+// IsPyExceptionsEnabled reports whether LLGO_PY_EXCEPTIONS turns the
+// exceptions raised by Python calls without an error result into panics.
+func IsPyExceptionsEnabled() bool {
+	return isEnvOn(llgoPyExceptions, false)
+}
+
+// IsPyGILEnabled reports whether LLGO_PY_GIL makes the Python operations hold
+// the GIL, so that Python can be called from any goroutine.
+func IsPyGILEnabled() bool {
+	return isEnvOn(llgoPyGIL, false) || isPyGILReleased()
+}
+
+// IsCABIEnabled reports whether the structs passed to or returned by C
+// functions follow the C ABI of the target. It is on unless LLGO_CABI is off.
+func IsCABIEnabled() bool {
+	return isEnvOn(llgoCABI, true)
+}
+
+// isPyGILReleased reports whether LLGO_PY_GIL is "release": the main thread
+// releases the GIL once Python is initialized, so that the goroutines calling
+// Python do not wait for it to run Python code.
+func isPyGILReleased() bool {
+	return strings.ToLower(os.Getenv(llgoPyGIL)) == pyGILRelease
+}
+
 func IsDbgEnabled() bool {
 	return isEnvOn(llgoDebug, false) || isEnvOn(llgoDbgSyms, false)
 }
@@ -1042,9 +1268,15 @@ func clFiles(ctx *context, files string, pkg *packages.Package, procFile func(li
 
 func clFile(ctx *context, args []string, cFile, expFile string, procFile func(linkFile string), verbose bool) {
 	llFile := expFile + filepath.Base(cFile) + ".ll"
-	targetTriple := llvmTarget.GetTargetTriple(ctx.buildConf.Goos, ctx.buildConf.Goarch)
-	cflags := buildCflags(ctx.buildConf.Goos, ctx.buildConf.Goarch, targetTriple)
-	args = append(cflags, args...)
+	args = append(targetCflags(ctx), args...)
+	if opt := ctx.buildConf.OptLevel; opt != "" {
+		args = append(args, "-O"+opt)
+	}
+	if ctx.objs.opts.PGOInstrument {
+		args = append(args, "-fprofile-generate")
+	} else if ctx.pgoProfile != "" {
+		args = append(args, "-fprofile-use="+ctx.pgoProfile)
+	}
 	args = append(args, "-emit-llvm", "-S", "-o", llFile, "-c", cFile)
 	args = append(args, ctx.crossCompile.CCFLAGS...)
 	args = append(args, ctx.crossCompile.CFLAGS...)
@@ -1057,6 +1289,39 @@ func clFile(ctx *context, args []string, cFile, expFile string, procFile func(li
 	procFile(llFile)
 }
 
+// targetCflags returns the clang flags selecting the target.
+func targetCflags(ctx *context) []string {
+	if ctx.spec != nil {
+		return ctx.spec.CompileFlags()
+	}
+	targetTriple := llvmTarget.GetTargetTriple(ctx.buildConf.Goos, ctx.buildConf.Goarch)
+	return buildCflags(ctx.buildConf.Goos, ctx.buildConf.Goarch, targetTriple)
+}
+
+// pgoCompile compiles the bitcode of a package to objFile with clang, which
+// hands the instrumentation profile of -pgo to the pass builder of LLVM: the
+// C API of LLVM has no way to do so.
+func pgoCompile(ctx *context, bitcode []byte, objFile string) error {
+	args := pgoCompileArgs(ctx, objFile)
+	cmd := ctx.env.Clang()
+	cmd.Stdin = bytes.NewReader(bitcode)
+	return cmd.Compile(args...)
+}
+
+func pgoCompileArgs(ctx *context, objFile string) []string {
+	args := targetCflags(ctx)
+	opt := ctx.buildConf.OptLevel
+	if opt == "" {
+		opt = "2" // the profile is only used by the optimization pipeline
+	}
+	args = append(args, "-O"+opt, "-fprofile-use="+ctx.pgoProfile, "-Wno-override-module")
+	if lto := ctx.buildConf.LTO; lto != "" {
+		args = append(args, "-flto="+lto)
+	}
+	args = append(args, "-c", "-x", "ir", "-o", objFile, "-")
+	return append(args, ctx.crossCompile.CCFLAGS...)
+}
+
 func pkgExists(initial []*packages.Package, pkg *packages.Package) bool {
 	for _, v := range initial {
 		if v == pkg {
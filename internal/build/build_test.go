@@ -4,11 +4,22 @@
 package build
 
 import (
+	"bytes"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/goplus/llgo/internal/crosscompile"
 	"github.com/goplus/llgo/internal/mockable"
+	"github.com/goplus/llgo/internal/targets"
+	llssa "github.com/goplus/llgo/ssa"
 )
 
 func mockRun(args []string, cfg *Config) {
@@ -64,3 +75,453 @@ func TestTest(t *testing.T) {
 func TestCmpTest(t *testing.T) {
 	mockRun([]string{"../../cl/_testgo/runtest"}, &Config{Mode: ModeCmpTest})
 }
+
+// TestBuildBaremetal compiles the bare-metal runtime, its C files and the
+// board HAL of a package for a Cortex-M4.
+func TestBuildBaremetal(t *testing.T) {
+	mockRun([]string{"./_testdata/baremetal"}, &Config{Mode: ModeBuild, Target: "cortex-m4"})
+}
+
+func TestWasmLink(t *testing.T) {
+	args := strings.Join(buildLdflags("wasip1", "wasm", "wasm32-unknown-wasip1"), " ")
+	if !strings.Contains(args, "-Wl,--warn-unresolved-symbols") {
+		t.Errorf("undefined symbols are not allowed: %s", args)
+	}
+	for _, flag := range []string{"--allow-undefined", "--export-all"} {
+		if strings.Contains(args, flag) {
+			t.Errorf("%s is set: %s", flag, args)
+		}
+	}
+	mockRun([]string{"./_testdata/wasmlink"}, &Config{Mode: ModeRun, Goos: "wasip1", Goarch: "wasm"})
+}
+
+func TestNewRunner(t *testing.T) {
+	t.Setenv("PATH", "")
+	t.Setenv(llgoWasmRuntime, "")
+	tests := []struct {
+		conf *Config
+		want runner
+	}{
+		{&Config{Goos: runtime.GOOS, Goarch: runtime.GOARCH}, cmdRunner(nil)},
+		{&Config{Goos: runtime.GOOS, Goarch: runtime.GOARCH, Exec: "qemu-aarch64 -L /sysroot"}, cmdRunner{"qemu-aarch64", "-L", "/sysroot"}},
+		{&Config{Goos: "js", Goarch: "wasm"}, jsRunner{}},
+		{&Config{Goos: "wasip1", Goarch: "wasm"}, cmdRunner{"wasmtime", "--wasm", "multi-memory=true"}},
+	}
+	for _, tt := range tests {
+		if got := newRunner(tt.conf, crosscompile.Export{}); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("newRunner(%+v) = %#v, want %#v", tt.conf, got, tt.want)
+		}
+	}
+	t.Setenv(llgoWasmRuntime, "builtin")
+	if got := newRunner(&Config{Goos: "wasip1", Goarch: "wasm"}, crosscompile.Export{}); got != (builtinWasmRunner{}) {
+		t.Errorf("newRunner with LLGO_WASM_RUNTIME=builtin = %#v", got)
+	}
+}
+
+func TestQemuRunner(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH == "arm64" {
+		t.Skip("qemu user mode emulation of arm64 needs another Linux host")
+	}
+	bin := t.TempDir()
+	qemu := filepath.Join(bin, "qemu-aarch64")
+	if err := os.WriteFile(qemu, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	conf := &Config{Goos: "linux", Goarch: "arm64"}
+	want := cmdRunner{qemu, "-L", "/usr/aarch64-linux-gnu"}
+	if got := newRunner(conf, crosscompile.Export{Sysroot: "/usr/aarch64-linux-gnu"}); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("newRunner = %#v, want %#v", got, want)
+	}
+	conf.Exec = "echo"
+	if got := newRunner(conf, crosscompile.Export{}); fmt.Sprint(got) != fmt.Sprint(cmdRunner{"echo"}) {
+		t.Errorf("newRunner with -exec = %#v", got)
+	}
+}
+
+func TestBuiltinWasmRunner(t *testing.T) {
+	// (module
+	//   (import "wasi_snapshot_preview1" "proc_exit" (func (param i32)))
+	//   (import "env" "memory" (memory 1))
+	//   (func (export "_start") (call 0 (i32.const 3))))
+	var types, imports, funcs, exports, code []byte
+	types = append(types, 2, 0x60, 1, 0x7f, 0, 0x60, 0, 0)
+	imports = append(imports, 2)
+	imports = appendWasmName(imports, "wasi_snapshot_preview1")
+	imports = appendWasmName(imports, "proc_exit")
+	imports = append(imports, 0, 0) // func, type 0
+	imports = appendWasmName(imports, "env")
+	imports = appendWasmName(imports, "memory")
+	imports = append(imports, 2, 0, 1) // memory, min 1
+	funcs = append(funcs, 1, 1)
+	exports = appendWasmName(append(exports, 1), "_start")
+	exports = append(exports, 0, 1) // func 1
+	code = append(code, 1, 6, 0, 0x41, 3, 0x10, 0, 0x0b)
+
+	b := []byte("\x00asm\x01\x00\x00\x00")
+	b = appendWasmSection(b, 1, types)
+	b = appendWasmSection(b, 2, imports)
+	b = appendWasmSection(b, 3, funcs)
+	b = appendWasmSection(b, 7, exports)
+	b = appendWasmSection(b, 10, code)
+	app := filepath.Join(t.TempDir(), "exit.wasm")
+	if err := os.WriteFile(app, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := builtinWasmRunner{}.run(app, nil, "", nil, &stdout, &stderr)
+	if code, ok := exitCode(err); !ok || code != 3 {
+		t.Fatalf("run: %v, want exit code 3", err)
+	}
+}
+
+func TestBuiltinWasmRunnerDir(t *testing.T) {
+	// (module
+	//   (import "wasi_snapshot_preview1" "proc_exit" (func (param i32)))
+	//   (import "wasi_snapshot_preview1" "path_open" (func (param i32 i32 i32 i32 i32 i64 i64 i32 i32) (result i32)))
+	//   (import "env" "memory" (memory 1))
+	//   (func (export "_start")
+	//     (call 0 (call 1 (i32.const 3) (i32.const 0) (i32.const 0) (i32.const 9) (i32.const 0)
+	//       (i64.const 2) (i64.const 0) (i32.const 0) (i32.const 16))))
+	//   (data (i32.const 0) "hello.txt"))
+	var types, imports, funcs, exports, code, data []byte
+	types = append(types, 3, 0x60, 1, 0x7f, 0, 0x60, 0, 0)
+	types = append(types, 0x60, 9, 0x7f, 0x7f, 0x7f, 0x7f, 0x7f, 0x7e, 0x7e, 0x7f, 0x7f, 1, 0x7f)
+	imports = append(imports, 3)
+	imports = appendWasmName(imports, "wasi_snapshot_preview1")
+	imports = appendWasmName(imports, "proc_exit")
+	imports = append(imports, 0, 0) // func, type 0
+	imports = appendWasmName(imports, "wasi_snapshot_preview1")
+	imports = appendWasmName(imports, "path_open")
+	imports = append(imports, 0, 2) // func, type 2
+	imports = appendWasmName(imports, "env")
+	imports = appendWasmName(imports, "memory")
+	imports = append(imports, 2, 0, 1) // memory, min 1
+	funcs = append(funcs, 1, 1)
+	exports = appendWasmName(append(exports, 1), "_start")
+	exports = append(exports, 0, 2) // func 2
+	body := []byte{0, 0x41, 3, 0x41, 0, 0x41, 0, 0x41, 9, 0x41, 0, 0x42, 2, 0x42, 0, 0x41, 0, 0x41, 16, 0x10, 1, 0x10, 0, 0x0b}
+	code = append(append(code, 1, byte(len(body))), body...)
+	data = append(data, 1, 0, 0x41, 0, 0x0b)
+	data = appendWasmName(data, "hello.txt")
+
+	b := []byte("\x00asm\x01\x00\x00\x00")
+	b = appendWasmSection(b, 1, types)
+	b = appendWasmSection(b, 2, imports)
+	b = appendWasmSection(b, 3, funcs)
+	b = appendWasmSection(b, 7, exports)
+	b = appendWasmSection(b, 10, code)
+	b = appendWasmSection(b, 11, data)
+	app := filepath.Join(t.TempDir(), "open.wasm")
+	if err := os.WriteFile(app, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := (builtinWasmRunner{}).run(app, nil, dir, nil, &stdout, &stderr); err != nil {
+		t.Fatalf("open hello.txt in dir: %v", err)
+	}
+	// the rest of the host is not mounted
+	err := builtinWasmRunner{}.run(app, nil, t.TempDir(), nil, &stdout, &stderr)
+	if code, ok := exitCode(err); !ok || code == 0 {
+		t.Fatalf("open hello.txt out of dir: %v, want an errno", err)
+	}
+}
+
+func appendWasmName(b []byte, name string) []byte {
+	return append(append(b, byte(len(name))), name...)
+}
+
+func TestGenMainModulePyGIL(t *testing.T) {
+	conf := &Config{Goos: "linux", Goarch: "amd64"}
+	for _, env := range []string{"", "on", pyGILRelease} {
+		t.Setenv(llgoPyGIL, env)
+		file, err := genMainModuleFile(conf, "runtime", "main", false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(file)
+		os.Remove(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		save := strings.Contains(string(data), "call ptr @PyEval_SaveThread()")
+		if save != (env == pyGILRelease) {
+			t.Errorf("LLGO_PY_GIL=%q: PyEval_SaveThread called: %v", env, save)
+		}
+		if enabled := IsPyGILEnabled(); enabled != (env != "") {
+			t.Errorf("LLGO_PY_GIL=%q: IsPyGILEnabled() = %v", env, enabled)
+		}
+	}
+}
+
+func TestCgoExportHeader(t *testing.T) {
+	const src = `package foo
+
+type _Ctype_int int32
+type _Ctype_struct_point struct{ x, y _Ctype_int }
+type _Ctype_size_t = uint64
+
+//export add
+func add(a, b _Ctype_int) _Ctype_int { return a + b }
+
+//export move
+func move(p *_Ctype_struct_point, n _Ctype_size_t) {}
+
+//export count
+func count(s string, b []byte, ok bool) (n int, err error) { return }
+
+func notExported() {}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := new(types.Config).Check("foo", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exports := findCgoExports(file)
+	if len(exports) != 3 {
+		t.Fatalf("findCgoExports: %v", exports)
+	}
+	dir := t.TempDir()
+	preambles := []cgoPreamble{{goFile: "foo.go", src: "#include <stdio.h>\n", export: true}}
+	if err = writeCgoExportHeader(dir, pkg, exports, preambles, 8); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, cgoExportHeader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"typedef struct { const char *p; ptrdiff_t n; } _GoString_;",
+		"#include <stdio.h>",
+		"typedef GoInt64 GoInt;",
+		"extern int add(int a, int b);",
+		"extern void move(struct point* p, size_t n);",
+		"struct count_return {\n\tGoInt n;\n\tGoInterface err;\n};",
+		"extern struct count_return count(GoString s, GoSlice b, GoUint8 ok);",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%q not found in\n%s", want, data)
+		}
+	}
+	sig := types.NewSignatureType(nil, nil, nil, types.NewTuple(types.NewParam(0, nil, "f", types.NewSignatureType(nil, nil, nil, nil, nil, false))), nil, false)
+	if _, err := cgoExportDecl("f", sig); err == nil {
+		t.Error("cgoExportDecl: func parameter accepted")
+	}
+}
+
+func TestCryptoBackend(t *testing.T) {
+	board := &targets.Target{GOOS: "linux", GOARCH: "arm", BuildTags: []string{"baremetal"}}
+	tests := []struct {
+		env  string
+		goos string
+		spec *targets.Target
+		want string
+	}{
+		{"", "linux", nil, cryptoOpenSSL},
+		{"", "darwin", nil, cryptoOpenSSL},
+		{"", "js", nil, cryptoGo},
+		{"", "wasip1", nil, cryptoGo},
+		{"", "wasi", nil, cryptoGo},
+		{"", "linux", board, cryptoGo},
+		{cryptoGo, "linux", nil, cryptoGo},
+		{cryptoOpenSSL, "wasip1", nil, cryptoOpenSSL},
+	}
+	for _, tt := range tests {
+		t.Setenv(llgoCrypto, tt.env)
+		got, err := cryptoBackend(tt.goos, tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("LLGO_CRYPTO=%q, GOOS=%s: cryptoBackend() = %q, %v, want %q", tt.env, tt.goos, got, err, tt.want)
+		}
+	}
+	t.Setenv(llgoCrypto, "boringssl")
+	if _, err := cryptoBackend("linux", nil); err == nil {
+		t.Error("LLGO_CRYPTO=boringssl: no error")
+	}
+}
+
+func TestBuildTags(t *testing.T) {
+	tests := []struct {
+		crypto string
+		tags   string
+		want   string
+	}{
+		{cryptoOpenSSL, "", "llgo,math_big_pure_go"},
+		{cryptoGo, "", "llgo,math_big_pure_go,purego"},
+		{cryptoOpenSSL, "foo,bar", "llgo,math_big_pure_go,foo,bar"},
+		{cryptoGo, "foo", "llgo,math_big_pure_go,purego,foo"},
+	}
+	for _, tt := range tests {
+		if got := buildTags(&Config{Tags: tt.tags}, tt.crypto); got != tt.want {
+			t.Errorf("buildTags(%q, %s) = %q, want %q", tt.tags, tt.crypto, got, tt.want)
+		}
+	}
+}
+
+func TestHasAltPkg(t *testing.T) {
+	tests := []struct {
+		crypto string
+		path   string
+		want   bool
+	}{
+		{cryptoOpenSSL, "crypto/sha256", true},
+		{cryptoGo, "crypto/sha256", false},
+		{cryptoOpenSSL, "crypto/ed25519", true},
+		{cryptoGo, "crypto/ed25519", false},
+		{cryptoGo, "crypto/subtle", true},
+		{cryptoGo, "sync", true},
+		{cryptoOpenSSL, "crypto/cipher", false},
+	}
+	for _, tt := range tests {
+		if got := hasAltPkg(tt.crypto, tt.path); got != tt.want {
+			t.Errorf("hasAltPkg(%s, %q) = %v, want %v", tt.crypto, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestObjEmitter(t *testing.T) {
+	llssa.Initialize(llssa.InitAll)
+	prog := llssa.NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	// the zero value of a struct and complex constants are constant structs,
+	// which must be created in the context of the program to be emitted.
+	st := types.NewStruct([]*types.Var{
+		types.NewField(0, nil, "a", types.Typ[types.Int], false),
+		types.NewField(0, nil, "b", types.Typ[types.Complex128], false),
+	}, nil)
+	rets := types.NewTuple(types.NewVar(0, nil, "", st))
+	sig := types.NewSignatureType(nil, nil, nil, nil, rets, false)
+	pkg.NewFunc("foo/bar.zero", sig, llssa.InGo).MakeBody(1).
+		Return(prog.Zero(prog.Type(st, llssa.InGo)))
+	rets = types.NewTuple(types.NewVar(0, nil, "", types.Typ[types.Complex128]))
+	sig = types.NewSignatureType(nil, nil, nil, nil, rets, false)
+	pkg.NewFunc("foo/bar.cval", sig, llssa.InGo).MakeBody(1).
+		Return(prog.ComplexVal(1+2i, prog.Complex128()))
+
+	dir := t.TempDir()
+	for _, lto := range []string{"", "full", "thin"} {
+		objs := newObjEmitter(&llssa.Target{}, llssa.EmitOptions{OptLevel: "2", LTO: lto})
+		file := filepath.Join(dir, "bar"+lto+objs.ext())
+		objs.emit(pkg, file)
+		if err := objs.wait(); err != nil {
+			t.Fatalf("LTO=%q: emit failed: %v", lto, err)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isBC := bytes.HasPrefix(data, []byte("BC\xc0\xde")); isBC != (lto != "") {
+			t.Errorf("LTO=%q: %s is bitcode: %v", lto, file, isBC)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("unexpected files emitted: %v", entries)
+	}
+}
+
+func TestPGOProfile(t *testing.T) {
+	llssa.Initialize(llssa.InitAll)
+	file := filepath.Join(t.TempDir(), "default.profdata")
+	os.WriteFile(file, []byte("\xfflprofi\x81\x00\x00\x00\x00"), 0644)
+	conf := &Config{Goos: "wasip1", Goarch: "wasm", OptLevel: "3", LTO: "thin", PGO: file}
+	opts, profile, err := emitOptions(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile != file || opts.FuncHotness != nil {
+		t.Fatalf("emitOptions: profile = %q, FuncHotness set: %v", profile, opts.FuncHotness != nil)
+	}
+	ctx := &context{buildConf: conf, pgoProfile: profile}
+	args := strings.Join(pgoCompileArgs(ctx, "foo.bc"), " ")
+	want := "-target wasm32-unknown-wasip1 -O3 -fprofile-use=" + file + " -Wno-override-module -flto=thin -c -x ir -o foo.bc -"
+	if args != want {
+		t.Errorf("pgoCompileArgs:\n got %s\nwant %s", args, want)
+	}
+
+	// the bitcode of packages goes to clang instead of the target machine
+	prog := llssa.NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	objs := newObjEmitter(&llssa.Target{}, opts)
+	var compiled []byte
+	objs.compile = func(bitcode []byte, objFile string) error {
+		compiled = bitcode
+		return nil
+	}
+	objs.emit(pkg, filepath.Join(t.TempDir(), "bar.bc"))
+	if err := objs.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(compiled, []byte("BC\xc0\xde")) {
+		t.Errorf("compile called with %d bytes of non bitcode", len(compiled))
+	}
+}
+
+func TestOptFlags(t *testing.T) {
+	tests := []struct {
+		opt, lto string
+		want     string
+	}{
+		{"", "", ""},
+		{"0", "", "-O0"},
+		{"2", "", "-O2"},
+		{"z", "", "-Oz"},
+		{"", "thin", "-flto=thin -O2"},
+		{"3", "full", "-flto=full -O3"},
+		{"s", "thin", "-flto=thin -Os"},
+	}
+	for _, tt := range tests {
+		conf := &Config{OptLevel: tt.opt, LTO: tt.lto}
+		if got := strings.Join(optFlags(conf), " "); got != tt.want {
+			t.Errorf("optFlags(-O%s -lto=%s) = %q, want %q", tt.opt, tt.lto, got, tt.want)
+		}
+	}
+}
+
+func TestOptFlagsInvalid(t *testing.T) {
+	tests := []struct {
+		opt, lto string
+		want     string
+	}{
+		{"4", "", "invalid optimization level: -O4"},
+		{"fast", "thin", "invalid optimization level: -Ofast"},
+		{"2", "fat", `invalid -lto mode "fat": must be thin or full`},
+	}
+	for _, tt := range tests {
+		_, err := Do(nil, &Config{OptLevel: tt.opt, LTO: tt.lto})
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Do(-O%s -lto=%s): err = %v, want %s", tt.opt, tt.lto, err, tt.want)
+		}
+	}
+}
+
+func TestLinkCmdArgsSysroot(t *testing.T) {
+	cross := crosscompile.Export{
+		CCFLAGS: []string{"--sysroot=/usr/aarch64-linux-gnu", "--gcc-toolchain=/usr"},
+		LDFLAGS: []string{"-L/usr/aarch64-linux-gnu/lib"},
+		Sysroot: "/usr/aarch64-linux-gnu",
+	}
+	ctx := &context{
+		buildConf:    &Config{Goos: "linux", Goarch: "arm64"},
+		crossCompile: cross,
+		objs:         newObjEmitter(&llssa.Target{}, llssa.EmitOptions{}),
+	}
+	args := strings.Join(linkCmdArgs(ctx, "app", []string{"main.o"}, nil, false), " ")
+	for _, flag := range []string{"-target aarch64-unknown-linux", "--sysroot=/usr/aarch64-linux-gnu", "-L/usr/aarch64-linux-gnu/lib"} {
+		if !strings.Contains(args, flag) {
+			t.Errorf("link args miss %s: %s", flag, args)
+		}
+	}
+	if !strings.HasSuffix(args, " main.o") {
+		t.Errorf("link args don't end with the object files: %s", args)
+	}
+}
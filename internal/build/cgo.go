@@ -42,19 +42,33 @@ type cgoDecl struct {
 type cgoPreamble struct {
 	goFile string
 	src    string
+	export bool // the Go file exports functions to C
 }
 
 const (
+	// cgoGoStringTypedef declares the C type of Go strings, as cgo does.
+	cgoGoStringTypedef = `#ifndef GO_CGO_GOSTRING_TYPEDEF
+#define GO_CGO_GOSTRING_TYPEDEF
+typedef struct { const char *p; ptrdiff_t n; } _GoString_;
+__attribute__ ((unused))
+static size_t _GoStringLen(_GoString_ s) { return (size_t)s.n; }
+__attribute__ ((unused))
+static const char *_GoStringPtr(_GoString_ s) { return s.p; }
+#endif
+`
+
 	cgoHeader = `
+#include <stddef.h>
 #include <stdlib.h>
 static void* _Cmalloc(size_t size) {
 	return malloc(size);
 }
-`
+
+` + cgoGoStringTypedef
 )
 
 func buildCgo(ctx *context, pkg *aPackage, files []*ast.File, externs []string, verbose bool) (llfiles, cgoLdflags []string, err error) {
-	cfiles, preambles, cdecls, err := parseCgo_(pkg, files)
+	cfiles, preambles, cdecls, exports, err := parseCgo_(pkg, files)
 	if err != nil {
 		return
 	}
@@ -85,6 +99,18 @@ func buildCgo(ctx *context, pkg *aPackage, files []*ast.File, externs []string,
 			cflags = append(cflags, "-I"+dir)
 		}
 	}
+	if len(preambles) > 0 {
+		dir, err := os.MkdirTemp("", "llgo-cgo-export-")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		err = writeCgoExportHeader(dir, pkg.Types, exports, preambles, ctx.prog.PointerSize())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to write %s: %v", cgoExportHeader, err)
+		}
+		cflags = append(cflags, "-I"+dir)
+	}
 	for _, cfile := range cfiles {
 		clFile(ctx, cflags, cfile, pkg.ExportFile, func(linkFile string) {
 			llfiles = append(llfiles, linkFile)
@@ -168,13 +194,14 @@ func genExternDeclsByClang(pkg *aPackage, src string, cflags []string, cgoSymbol
 	var toRemove []string
 	for cgoName, symbolName := range cgoSymbols {
 		if strings.HasPrefix(symbolName, "__cgo_") {
+			cfuncName := symbolName[len("__cgo_"):]
 			gofuncName := strings.Replace(cgoName, ".__cgo_", ".", 1)
-			gofn := pkg.LPkg.FuncOf(gofuncName)
 			cgoVar := pkg.LPkg.VarOf(cgoName)
-			if gofn != nil {
+			if fn := pkg.LPkg.FuncOf(cfuncName); fn != nil { // exported by //export
+				cgoVar.ReplaceAllUsesWith(fn.Expr)
+			} else if gofn := pkg.LPkg.FuncOf(gofuncName); gofn != nil {
 				cgoVar.ReplaceAllUsesWith(gofn.Expr)
 			} else {
-				cfuncName := symbolName[len("__cgo_"):]
 				cfn := pkg.LPkg.NewFunc(cfuncName, types.NewSignatureType(nil, nil, nil, nil, nil, false), llssa.InC)
 				cgoVar.ReplaceAllUsesWith(cfn.Expr)
 			}
@@ -271,7 +298,7 @@ func extractFuncNames(node *clangASTNode, funcNames map[string]bool) {
 	}
 }
 
-func parseCgo_(pkg *aPackage, files []*ast.File) (cfiles []string, preambles []cgoPreamble, cdecls []cgoDecl, err error) {
+func parseCgo_(pkg *aPackage, files []*ast.File) (cfiles []string, preambles []cgoPreamble, cdecls []cgoDecl, exports []cgoExport, err error) {
 	dirs := make(map[string]none)
 	for _, file := range files {
 		pos := pkg.Fset.Position(file.Name.NamePos)
@@ -294,6 +321,8 @@ func parseCgo_(pkg *aPackage, files []*ast.File) (cfiles []string, preambles []c
 	}
 
 	for _, file := range files {
+		fileExports := findCgoExports(file)
+		exports = append(exports, fileExports...)
 		for _, decl := range file.Decls {
 			switch decl := decl.(type) {
 			case *ast.GenDecl:
@@ -306,6 +335,7 @@ func parseCgo_(pkg *aPackage, files []*ast.File) (cfiles []string, preambles []c
 							if err != nil {
 								panic(err)
 							}
+							preamble.export = len(fileExports) > 0
 							preambles = append(preambles, preamble)
 							cdecls = append(cdecls, flags...)
 						}
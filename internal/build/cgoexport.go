@@ -0,0 +1,237 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgoExport is a Go function exported to C by an //export directive.
+type cgoExport struct {
+	name string // the C name
+	fn   string // the Go name
+}
+
+const cgoExportHeader = "_cgo_export.h"
+
+// cgoExportPrologue declares the Go types of the parameters and results of
+// the exported functions, as cgo does.
+const cgoExportPrologue = `
+#ifndef GO_CGO_PROLOGUE_H
+#define GO_CGO_PROLOGUE_H
+
+typedef signed char GoInt8;
+typedef unsigned char GoUint8;
+typedef short GoInt16;
+typedef unsigned short GoUint16;
+typedef int GoInt32;
+typedef unsigned int GoUint32;
+typedef long long GoInt64;
+typedef unsigned long long GoUint64;
+typedef GoInt%[1]d GoInt;
+typedef GoUint%[1]d GoUint;
+typedef size_t GoUintptr;
+typedef float GoFloat32;
+typedef double GoFloat64;
+#ifndef __cplusplus
+typedef float _Complex GoComplex64;
+typedef double _Complex GoComplex128;
+#endif
+
+typedef char _check_for_%[1]d_bit_pointer_matching_GoInt[sizeof(void*)==%[1]d/8 ? 1:-1];
+
+#ifndef GO_CGO_GOSTRING_TYPEDEF
+typedef _GoString_ GoString;
+#endif
+typedef void *GoMap;
+typedef void *GoChan;
+typedef struct { void *t; void *v; } GoInterface;
+typedef struct { void *data; GoInt len; GoInt cap; } GoSlice;
+
+#endif
+`
+
+// findCgoExports returns the functions of file exported by //export.
+func findCgoExports(file *ast.File) (exports []cgoExport) {
+	const export = "//export "
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Doc == nil {
+			continue
+		}
+		for _, c := range fn.Doc.List {
+			if strings.HasPrefix(c.Text, export) {
+				exports = append(exports, cgoExport{strings.TrimSpace(c.Text[len(export):]), fn.Name.Name})
+			}
+		}
+	}
+	return
+}
+
+// writeCgoExportHeader writes _cgo_export.h to dir. Like the one of cgo, it
+// has the preambles of the files exporting functions, the Go types, and the
+// declarations of the exported functions, for the C files of the package.
+func writeCgoExportHeader(dir string, pkg *types.Package, exports []cgoExport, preambles []cgoPreamble, ptrSize int) error {
+	var b strings.Builder
+	b.WriteString("/* Code generated by llgo; DO NOT EDIT. */\n\n")
+	fmt.Fprintf(&b, "/* package %s */\n\n", pkg.Path())
+	b.WriteString("#include <stddef.h>\n\n")
+	b.WriteString(cgoGoStringTypedef)
+	b.WriteString("\n/* Start of preamble from import \"C\" comments.  */\n\n")
+	for _, preamble := range preambles {
+		if preamble.export {
+			b.WriteString(preamble.src)
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("/* End of preamble from import \"C\" comments.  */\n")
+	fmt.Fprintf(&b, cgoExportPrologue, ptrSize*8)
+	b.WriteString("\n#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+	for _, exp := range exports {
+		fn, ok := pkg.Scope().Lookup(exp.fn).(*types.Func)
+		if !ok {
+			continue
+		}
+		decl, err := cgoExportDecl(exp.name, fn.Type().(*types.Signature))
+		if err != nil {
+			return fmt.Errorf("export %s: %v", exp.name, err)
+		}
+		b.WriteString(decl)
+	}
+	b.WriteString("\n#ifdef __cplusplus\n}\n#endif\n")
+	return os.WriteFile(filepath.Join(dir, cgoExportHeader), []byte(b.String()), 0644)
+}
+
+// cgoExportDecl returns the C declaration of the Go function exported as
+// name. Multiple results are returned in a struct named name_return.
+func cgoExportDecl(name string, sig *types.Signature) (string, error) {
+	if sig.Variadic() {
+		return "", fmt.Errorf("variadic function not supported")
+	}
+	var b strings.Builder
+	ret := "void"
+	switch results := sig.Results(); results.Len() {
+	case 0:
+	case 1:
+		t, err := cgoCType(results.At(0).Type())
+		if err != nil {
+			return "", err
+		}
+		ret = t
+	default:
+		ret = "struct " + name + "_return"
+		fmt.Fprintf(&b, "\n%s {\n", ret)
+		for i := 0; i < results.Len(); i++ {
+			t, err := cgoCType(results.At(i).Type())
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&b, "\t%s %s;\n", t, cgoParamName(results.At(i).Name(), "r", i))
+		}
+		b.WriteString("};\n")
+	}
+	params := sig.Params()
+	args := make([]string, params.Len())
+	for i := range args {
+		t, err := cgoCType(params.At(i).Type())
+		if err != nil {
+			return "", err
+		}
+		args[i] = t + " " + cgoParamName(params.At(i).Name(), "p", i)
+	}
+	fmt.Fprintf(&b, "extern %s %s(%s);\n", ret, name, strings.Join(args, ", "))
+	return b.String(), nil
+}
+
+func cgoParamName(name, prefix string, i int) string {
+	if name == "" || name == "_" {
+		return prefix + strconv.Itoa(i)
+	}
+	return name
+}
+
+// cgoCType returns the C type of the Go type t in _cgo_export.h.
+func cgoCType(t types.Type) (string, error) {
+	if named, ok := t.(interface{ Obj() *types.TypeName }); ok { // *types.Named or *types.Alias
+		if name, ok := strings.CutPrefix(named.Obj().Name(), "_Ctype_"); ok {
+			return cgoCName(name), nil
+		}
+	}
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		switch kind := t.Kind(); kind {
+		case types.Bool:
+			return "GoUint8", nil
+		case types.String:
+			return "GoString", nil
+		case types.UnsafePointer:
+			return "void*", nil
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64, types.Uintptr,
+			types.Float32, types.Float64, types.Complex64, types.Complex128:
+			name := t.Name()
+			return "Go" + strings.ToUpper(name[:1]) + name[1:], nil
+		}
+	case *types.Pointer:
+		elem, err := cgoCType(t.Elem())
+		if err != nil {
+			elem = "void"
+		}
+		return elem + "*", nil
+	case *types.Slice:
+		return "GoSlice", nil
+	case *types.Map:
+		return "GoMap", nil
+	case *types.Chan:
+		return "GoChan", nil
+	case *types.Interface:
+		return "GoInterface", nil
+	}
+	return "", fmt.Errorf("Go type not supported in export: %v", t)
+}
+
+// cgoCName returns the C type named _Ctype_name by cgo.
+func cgoCName(name string) string {
+	for _, kw := range []string{"struct", "union", "enum"} {
+		if tag, ok := strings.CutPrefix(name, kw+"_"); ok {
+			return kw + " " + tag
+		}
+	}
+	switch name {
+	case "schar":
+		return "signed char"
+	case "uchar":
+		return "unsigned char"
+	case "ushort":
+		return "unsigned short"
+	case "uint":
+		return "unsigned int"
+	case "ulong":
+		return "unsigned long"
+	case "longlong":
+		return "long long"
+	case "ulonglong":
+		return "unsigned long long"
+	}
+	return name
+}
@@ -40,13 +40,16 @@ func Clean(patterns []string, conf *Config) {
 	if conf.Goarch == "" {
 		conf.Goarch = runtime.GOARCH
 	}
-	tags := "llgo"
-	if conf.Tags != "" {
-		tags += "," + conf.Tags
-	}
+	spec, err := loadTarget(conf)
+	check(err)
+	crypto, err := cryptoBackend(conf.Goos, spec)
+	check(err)
 	cfg := &packages.Config{
 		Mode:       loadSyntax | packages.NeedExportFile,
-		BuildFlags: []string{"-tags=" + tags},
+		BuildFlags: []string{"-tags=" + buildTags(conf, crypto)},
+	}
+	if spec != nil {
+		cfg.Env = append(os.Environ(), "GOOS="+conf.Goos, "GOARCH="+conf.Goarch)
 	}
 
 	if patterns == nil {
@@ -77,10 +80,19 @@ func cleanMainPkg(pkg *packages.Package, conf *Config, verbose bool) {
 	}
 }
 
+// exportExts are the extensions of the files built from the export file of
+// a package: the IR checked by llc, the object or bitcode file emitted by
+// objEmitter, and the temporary bitcode files left by older versions.
+var exportExts = []string{".ll", ".o", ".bc", ".o.tmp.bc", ".bc.tmp.bc"}
+
 func cleanPkgs(initial []*packages.Package, verbose bool) {
 	packages.Visit(initial, nil, func(p *packages.Package) {
-		file := p.ExportFile + ".ll"
-		removeFile(file, verbose)
+		if p.ExportFile == "" {
+			return
+		}
+		for _, ext := range exportExts {
+			removeFile(p.ExportFile+ext, verbose)
+		}
 	})
 }
 
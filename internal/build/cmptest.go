@@ -20,16 +20,14 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 )
 
-func cmpTest(dir, pkgPath, llApp string, genExpect bool, runArgs []string) {
+func cmpTest(r runner, dir, pkgPath, llApp string, genExpect bool, runArgs []string) {
 	var llgoOut, llgoErr bytes.Buffer
-	var llgoRunErr = runApp(runArgs, dir, &llgoOut, &llgoErr, llApp)
+	var llgoRunErr = r.run(llApp, runArgs, dir, nil, &llgoOut, &llgoErr)
 
 	llgoExpect := formatExpect(llgoOut.Bytes(), llgoErr.Bytes(), llgoRunErr)
 	llgoExpectFile := filepath.Join(dir, "llgo.expect")
@@ -50,7 +48,7 @@ func cmpTest(dir, pkgPath, llApp string, genExpect bool, runArgs []string) {
 	}
 
 	var goOut, goErr bytes.Buffer
-	var goRunErr = runApp(runArgs, dir, &goOut, &goErr, "go", "run", pkgPath)
+	var goRunErr = cmdRunner{"go", "run"}.run(pkgPath, runArgs, dir, nil, &goOut, &goErr)
 
 	checkEqual("output", llgoOut.Bytes(), goOut.Bytes())
 	checkEqual("stderr", llgoErr.Bytes(), goErr.Bytes())
@@ -58,15 +56,11 @@ func cmpTest(dir, pkgPath, llApp string, genExpect bool, runArgs []string) {
 }
 
 func formatExpect(stdout, stderr []byte, runErr error) []byte {
-	var exitCode int
-	if runErr != nil {
-		if ee, ok := runErr.(*exec.ExitError); ok {
-			exitCode = ee.ExitCode()
-		} else { // This should never happen, but just in case.
-			exitCode = 255
-		}
+	code, ok := exitCode(runErr)
+	if !ok { // This should never happen, but just in case.
+		code = 255
 	}
-	return []byte(fmt.Sprintf("#stdout\n%s\n#stderr\n%s\n#exit %d\n", stdout, stderr, exitCode))
+	return []byte(fmt.Sprintf("#stdout\n%s\n#stderr\n%s\n#exit %d\n", stdout, stderr, code))
 }
 
 func checkEqualRunErr(llgoRunErr, goRunErr error) {
@@ -91,21 +85,6 @@ func checkEqual(prompt string, a, expected []byte) {
 	fatal(errors.New("checkEqual: unexpected " + prompt))
 }
 
-func runApp(runArgs []string, dir string, stdout, stderr io.Writer, app string, args ...string) error {
-	if len(runArgs) > 0 {
-		if len(args) > 0 {
-			args = append(args, runArgs...)
-		} else {
-			args = runArgs
-		}
-	}
-	cmd := exec.Command(app, args...)
-	cmd.Dir = dir
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
-	return cmd.Run()
-}
-
 func fatal(err error) {
 	log.Panicln(err)
 }
@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//go:embed wasm_exec.js
+var wasmExecJS string
+
+// writeJSLoader writes app.js, the loader that runs the js/wasm program
+// app.wasm in a browser or with Node.js.
+func writeJSLoader(app string) (file string, err error) {
+	file = jsLoaderFile(app)
+	loader := strings.Replace(wasmExecJS, `"__LLGO_WASM_FILE__"`, strconv.Quote(filepath.Base(app)), 1)
+	err = os.WriteFile(file, []byte(loader), 0644)
+	return
+}
+
+// jsLoaderFile returns the name of the loader of the js/wasm program app.
+func jsLoaderFile(app string) string {
+	return strings.TrimSuffix(app, filepath.Ext(app)) + ".js"
+}
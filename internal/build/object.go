@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"runtime"
+	"sync"
+
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// objEmitter compiles packages to object files in the background. The
+// bitcode of a package is written to memory while the shared LLVM context is
+// only used by the builder; the code generation of several packages then runs
+// concurrently, each in its own LLVM context.
+type objEmitter struct {
+	target *llssa.Target
+	opts   llssa.EmitOptions
+	// compile, if not nil, compiles the bitcode of a package to objFile
+	// instead of the target machine of LLVM.
+	compile func(bitcode []byte, objFile string) error
+
+	sem   chan none
+	wg    sync.WaitGroup
+	mutex sync.Mutex
+	err   error
+}
+
+func newObjEmitter(target *llssa.Target, opts llssa.EmitOptions) *objEmitter {
+	return &objEmitter{target: target, opts: opts, sem: make(chan none, runtime.GOMAXPROCS(0))}
+}
+
+// ext returns the extension of the files emitted: bitcode files are linked
+// by the linker when link time optimization is enabled.
+func (p *objEmitter) ext() string {
+	if p.opts.LTO != "" {
+		return ".bc"
+	}
+	return ".o"
+}
+
+// emit schedules the compilation of pkg to objFile.
+func (p *objEmitter) emit(pkg llssa.Package, objFile string) {
+	bitcode := pkg.Bitcode()
+	var data []byte
+	if p.compile != nil {
+		data = append(data, bitcode.Bytes()...)
+		bitcode.Dispose()
+	}
+	p.wg.Add(1)
+	p.sem <- none{}
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		var err error
+		if p.compile != nil {
+			err = p.compile(data, objFile)
+		} else {
+			err = p.target.EmitObject(bitcode, objFile, p.opts)
+		}
+		if err != nil {
+			p.mutex.Lock()
+			if p.err == nil {
+				p.err = err
+			}
+			p.mutex.Unlock()
+		}
+	}()
+}
+
+// wait waits for all scheduled objects and returns the first error.
+func (p *objEmitter) wait() error {
+	p.wg.Wait()
+	return p.err
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llgo/internal/crosscompile"
+)
+
+// A runner runs the programs built for a target. It is used by llgo run,
+// llgo test and llgo cmptest.
+type runner interface {
+	// run runs app with args in dir and waits for it to exit. A non-zero
+	// exit status is reported as an error with an ExitCode() int method.
+	run(app string, args []string, dir string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+// newRunner returns the runner for the programs built with conf, the first
+// of:
+//   - the command given by -exec;
+//   - llgo_$GOOS_$GOARCH_exec found in $PATH, when cross compiling;
+//   - qemu-$ARCH found in $PATH, for Linux programs of another GOARCH,
+//     with the libraries of the sysroot of cross;
+//   - Node.js and the JS loader, for js/wasm;
+//   - the WASI runtime selected by $LLGO_WASM_RUNTIME, for wasi/wasip1;
+//   - the program itself.
+func newRunner(conf *Config, cross crosscompile.Export) runner {
+	if args := strings.Fields(conf.Exec); len(args) > 0 {
+		return cmdRunner(args)
+	}
+	if conf.Goos != runtime.GOOS || conf.Goarch != runtime.GOARCH {
+		if wrapper, err := exec.LookPath("llgo_" + conf.Goos + "_" + conf.Goarch + "_exec"); err == nil {
+			return cmdRunner{wrapper}
+		}
+	}
+	if conf.Goos == "linux" && runtime.GOOS == "linux" && conf.Goarch != runtime.GOARCH {
+		if qemu := findQemu(conf.Goarch); qemu != "" {
+			if cross.Sysroot != "" {
+				return cmdRunner{qemu, "-L", cross.Sysroot}
+			}
+			return cmdRunner{qemu}
+		}
+	}
+	switch conf.Goos {
+	case "js":
+		return jsRunner{}
+	case "wasi", "wasip1":
+		return wasmRunner(WasmRuntime())
+	}
+	return cmdRunner(nil)
+}
+
+// qemuArchs are the names qemu gives to the architectures with a GOARCH of
+// another name.
+var qemuArchs = map[string]string{
+	"386":      "i386",
+	"amd64":    "x86_64",
+	"arm64":    "aarch64",
+	"loong64":  "loongarch64",
+	"mipsle":   "mipsel",
+	"mips64le": "mips64el",
+}
+
+// findQemu returns the path of the qemu user mode emulator of goarch, or ""
+// if it is not installed.
+func findQemu(goarch string) string {
+	arch, ok := qemuArchs[goarch]
+	if !ok {
+		arch = goarch
+	}
+	for _, name := range []string{"qemu-" + arch, "qemu-" + arch + "-static"} {
+		if qemu, err := exec.LookPath(name); err == nil {
+			return qemu
+		}
+	}
+	return ""
+}
+
+// wasmRuntimeArgs are the flags the known WASI runtimes need to run
+// llgo programs.
+var wasmRuntimeArgs = map[string][]string{
+	"wasmtime": {"--wasm", "multi-memory=true"},
+	"iwasm":    {"--stack-size=819200000", "--heap-size=800000000"},
+}
+
+// wasmRunner returns the runner for the WASI runtime wasmer: "builtin" or
+// a command line.
+func wasmRunner(wasmer string) runner {
+	if wasmer == wasmRuntimeBuiltin {
+		return builtinWasmRunner{}
+	}
+	args := strings.Fields(os.ExpandEnv(wasmer))
+	return cmdRunner(append(args, wasmRuntimeArgs[wasmer]...))
+}
+
+// cmdRunner runs a program with a command line followed by the program and
+// its arguments. An empty cmdRunner runs the program itself.
+type cmdRunner []string
+
+func (r cmdRunner) run(app string, args []string, dir string, stdin io.Reader, stdout, stderr io.Writer) error {
+	name := app
+	if len(r) > 0 {
+		name = r[0]
+		args = append(append(r[1:len(r):len(r)], app), args...)
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+// jsRunner runs js/wasm programs with Node.js and the loader written by
+// writeJSLoader.
+type jsRunner struct{}
+
+func (jsRunner) run(app string, args []string, dir string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return cmdRunner{"node"}.run(jsLoaderFile(app), args, dir, stdin, stdout, stderr)
+}
+
+// exitError reports the non-zero exit code of a program that does not run
+// in a process of its own.
+type exitError int
+
+func (e exitError) Error() string {
+	return "exit status " + strconv.Itoa(int(e))
+}
+
+func (e exitError) ExitCode() int {
+	return int(e)
+}
+
+// exitCode returns the exit code of a program from the error returned by
+// runner.run. ok is false if the program could not be run.
+func exitCode(err error) (code int, ok bool) {
+	if err == nil {
+		return 0, true
+	}
+	var e interface{ ExitCode() int }
+	if errors.As(err, &e) {
+		return e.ExitCode(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,511 @@
+// Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Loader of a js/wasm program built by llgo.
+//
+// In a browser, add it to a page with <script src="app.js"></script>, next to
+// app.wasm. With Node.js, run it with: node app.js [args...].
+//
+// The loader provides the WASI functions used by the C library and the
+// llgo_js module imported by syscall/js and the runtime. The LLGo class is also
+// exported as globalThis.LLGo to run programs by hand:
+//
+//	const go = new LLGo();
+//	const { instance } = await WebAssembly.instantiateStreaming(fetch("app.wasm"), go.importObject);
+//	const code = await go.run(instance);
+
+"use strict";
+
+(() => {
+	const wasmFile = "__LLGO_WASM_FILE__";
+
+	const isNode = typeof process !== "undefined" && process.versions != null && process.versions.node != null;
+	const cryptoImpl = globalThis.crypto ?? (isNode ? require("crypto").webcrypto : undefined);
+
+	const encoder = new TextEncoder();
+	const decoder = new TextDecoder("utf-8");
+
+	// WASI errno values.
+	const ESUCCESS = 0;
+	const EBADF = 8;
+	const ENOSYS = 52;
+	const ESPIPE = 70;
+
+	// Rights of the standard streams: all but fd_seek and fd_tell, so that
+	// the C library treats them as terminals and flushes lines.
+	const ttyRights = ~((1n << 2n) | (1n << 5n)) & 0xFFFFFFFFFFFFFFFFn;
+
+	// Initial size of the memory, see -Wl,--initial-memory.
+	const initialPages = 1024;
+
+	// nanHead are the upper 32 bits of a ref which are set if the value is not
+	// a number, see syscall/js.
+	const nanHead = 0x7FF80000;
+
+	class ExitStatus extends Error {
+		constructor(code) {
+			super("exit status " + code);
+			this.code = code;
+		}
+	}
+
+	// park is thrown by llgo_js.park when main reaches select {}: it unwinds
+	// the program back to the event loop, which goes on running callbacks.
+	// select {} never resumes, so nothing is lost with the frames unwound.
+	const park = Symbol("llgo.park");
+
+	class LLGo {
+		constructor() {
+			this.argv = ["js"];
+			this.env = {};
+			this.exited = false;
+			this.exitCode = 0;
+			this.memory = new WebAssembly.Memory({ initial: initialPages });
+			this._pendingEvent = null;
+			this._inst = null;
+			this._depth = 0;
+			this._lines = ["", "", ""];
+			this._exitPromise = new Promise((resolve) => {
+				this._resolveExit = resolve;
+			});
+
+			const scratch = new DataView(new ArrayBuffer(8));
+			const mem = () => new DataView(this.memory.buffer);
+			const bytes = (p, n) => new Uint8Array(this.memory.buffer, p >>> 0, n >>> 0);
+			const loadString = (p, n) => decoder.decode(bytes(p, n));
+
+			// Values referenced by the program: a value has a single id,
+			// counting the refs to it handed out to the program.
+			this._values = [NaN, 0, null, true, false, globalThis, this];
+			this._refCounts = new Array(this._values.length).fill(Infinity);
+			this._ids = new Map([[0, 1], [null, 2], [true, 3], [false, 4], [globalThis, 5], [this, 6]]);
+			this._idPool = [];
+
+			const storeValue = (v) => {
+				if (typeof v === "number" && v !== 0) {
+					if (isNaN(v)) {
+						return BigInt(nanHead) << 32n;
+					}
+					scratch.setFloat64(0, v, true);
+					return scratch.getBigUint64(0, true);
+				}
+				if (v === undefined) {
+					return 0n;
+				}
+				let id = this._ids.get(v);
+				if (id === undefined) {
+					id = this._idPool.pop();
+					if (id === undefined) {
+						id = this._values.length;
+					}
+					this._values[id] = v;
+					this._refCounts[id] = 0;
+					this._ids.set(v, id);
+				}
+				this._refCounts[id]++;
+				let typeFlag = 0;
+				switch (typeof v) {
+					case "object":
+						if (v !== null) {
+							typeFlag = 1;
+						}
+						break;
+					case "string":
+						typeFlag = 2;
+						break;
+					case "symbol":
+						typeFlag = 3;
+						break;
+					case "function":
+						typeFlag = 4;
+						break;
+				}
+				return (BigInt(nanHead | typeFlag) << 32n) | BigInt(id);
+			};
+
+			const loadValue = (ref) => {
+				scratch.setBigUint64(0, BigInt.asUintN(64, ref), true);
+				const f = scratch.getFloat64(0, true);
+				if (f === 0) {
+					return undefined;
+				}
+				if (!isNaN(f)) {
+					return f;
+				}
+				return this._values[Number(ref & 0xFFFFFFFFn)];
+			};
+
+			const loadRefs = (p, n) => {
+				const dv = mem();
+				const a = new Array(n);
+				for (let i = 0; i < n; i++) {
+					a[i] = loadValue(dv.getBigUint64(p + i * 8, true));
+				}
+				return a;
+			};
+
+			const setBool = (p, b) => mem().setUint8(p, b ? 1 : 0);
+
+			const isBytes = (v) => v instanceof Uint8Array || v instanceof Uint8ClampedArray;
+
+			const write = (fd, buf) => {
+				if (isNode) {
+					require("fs").writeSync(fd, buf);
+					return;
+				}
+				let text = this._lines[fd] + decoder.decode(buf);
+				let nl;
+				while ((nl = text.indexOf("\n")) >= 0) {
+					(fd === 2 ? console.error : console.log)(text.slice(0, nl));
+					text = text.slice(nl + 1);
+				}
+				this._lines[fd] = text;
+			};
+
+			const strings = (list, ptrs, buf) => {
+				const dv = mem();
+				for (const s of list) {
+					const b = encoder.encode(s + "\0");
+					dv.setUint32(ptrs, buf, true);
+					bytes(buf, b.length).set(b);
+					ptrs += 4;
+					buf += b.length;
+				}
+				return ESUCCESS;
+			};
+
+			const sizes = (list, countPtr, sizePtr) => {
+				const dv = mem();
+				dv.setUint32(countPtr, list.length, true);
+				dv.setUint32(sizePtr, list.reduce((n, s) => n + encoder.encode(s).length + 1, 0), true);
+				return ESUCCESS;
+			};
+
+			const environ = () => Object.entries(this.env).map(([k, v]) => k + "=" + v);
+
+			const now = (id) => {
+				const ms = id === 0 ? performance.timeOrigin + performance.now() : performance.now();
+				return BigInt(Math.round(ms * 1e6));
+			};
+
+			const wasi = {
+				args_get: (argv, buf) => strings(this.argv, argv, buf),
+				args_sizes_get: (count, size) => sizes(this.argv, count, size),
+				environ_get: (env, buf) => strings(environ(), env, buf),
+				environ_sizes_get: (count, size) => sizes(environ(), count, size),
+				clock_res_get: (id, res) => {
+					mem().setBigUint64(res, 1000n, true);
+					return ESUCCESS;
+				},
+				clock_time_get: (id, precision, time) => {
+					mem().setBigUint64(time, now(id), true);
+					return ESUCCESS;
+				},
+				fd_write: (fd, iovs, iovsLen, nwritten) => {
+					if (fd !== 1 && fd !== 2) {
+						return EBADF;
+					}
+					const dv = mem();
+					let n = 0;
+					for (let i = 0; i < iovsLen; i++) {
+						const p = dv.getUint32(iovs + i * 8, true);
+						const len = dv.getUint32(iovs + i * 8 + 4, true);
+						write(fd, bytes(p, len).slice());
+						n += len;
+					}
+					dv.setUint32(nwritten, n, true);
+					return ESUCCESS;
+				},
+				fd_read: (fd, iovs, iovsLen, nread) => {
+					if (fd !== 0) {
+						return EBADF;
+					}
+					mem().setUint32(nread, 0, true);
+					return ESUCCESS;
+				},
+				fd_close: (fd) => ESUCCESS,
+				fd_seek: (fd, offset, whence, newOffset) => ESPIPE,
+				fd_fdstat_get: (fd, stat) => {
+					if (fd > 2) {
+						return EBADF;
+					}
+					const dv = mem();
+					dv.setUint8(stat, 2); // character device
+					dv.setUint16(stat + 2, 0, true);
+					dv.setBigUint64(stat + 8, ttyRights, true);
+					dv.setBigUint64(stat + 16, ttyRights, true);
+					return ESUCCESS;
+				},
+				fd_fdstat_set_flags: (fd, flags) => ESUCCESS,
+				fd_prestat_get: (fd, prestat) => EBADF,
+				fd_prestat_dir_name: (fd, path, len) => EBADF,
+				poll_oneoff: (subs, events, nsubs, nevents) => {
+					// Sleeping blocks the thread: wait for the earliest clock
+					// and report all subscriptions as ready.
+					const dv = mem();
+					let deadline = null;
+					for (let i = 0; i < nsubs; i++) {
+						const s = subs + i * 48;
+						if (dv.getUint8(s + 8) === 0) { // clock
+							const id = dv.getUint32(s + 16, true);
+							let t = dv.getBigUint64(s + 24, true);
+							if ((dv.getUint16(s + 40, true) & 1) === 0) { // relative
+								t += now(id);
+							}
+							if (deadline === null || t < deadline) {
+								deadline = t;
+							}
+						}
+					}
+					if (deadline !== null) {
+						while (now(1) < deadline) {
+						}
+					}
+					for (let i = 0; i < nsubs; i++) {
+						const s = subs + i * 48;
+						const e = events + i * 32;
+						dv.setBigUint64(e, dv.getBigUint64(s, true), true);
+						dv.setUint16(e + 8, ESUCCESS, true);
+						dv.setUint8(e + 10, dv.getUint8(s + 8));
+					}
+					dv.setUint32(nevents, nsubs, true);
+					return ESUCCESS;
+				},
+				proc_exit: (code) => {
+					throw new ExitStatus(code);
+				},
+				random_get: (buf, len) => {
+					for (let i = 0; i < len; i += 65536) {
+						cryptoImpl.getRandomValues(bytes(buf + i, Math.min(65536, len - i)));
+					}
+					return ESUCCESS;
+				},
+				sched_yield: () => ESUCCESS,
+			};
+
+			const llgoJS = {
+				park: () => {
+					throw park;
+				},
+
+				finalize_ref: (ref) => {
+					const id = Number(ref & 0xFFFFFFFFn);
+					if (--this._refCounts[id] === 0) {
+						this._ids.delete(this._values[id]);
+						this._values[id] = undefined;
+						this._idPool.push(id);
+					}
+				},
+
+				string_val: (p, n) => storeValue(loadString(p, n)),
+
+				value_get: (v, p, n) => storeValue(Reflect.get(loadValue(v), loadString(p, n))),
+
+				value_set: (v, p, n, x) => {
+					Reflect.set(loadValue(v), loadString(p, n), loadValue(x));
+				},
+
+				value_delete: (v, p, n) => {
+					Reflect.deleteProperty(loadValue(v), loadString(p, n));
+				},
+
+				value_index: (v, i) => storeValue(Reflect.get(loadValue(v), i)),
+
+				value_set_index: (v, i, x) => {
+					Reflect.set(loadValue(v), i, loadValue(x));
+				},
+
+				value_length: (v) => Number(loadValue(v).length),
+
+				value_call: (v, p, n, args, nargs, ok) => {
+					try {
+						const o = loadValue(v);
+						const m = Reflect.get(o, loadString(p, n));
+						const res = Reflect.apply(m, o, loadRefs(args, nargs));
+						setBool(ok, true);
+						return storeValue(res);
+					} catch (err) {
+						setBool(ok, false);
+						return storeValue(err);
+					}
+				},
+
+				value_invoke: (v, args, nargs, ok) => {
+					try {
+						const res = Reflect.apply(loadValue(v), undefined, loadRefs(args, nargs));
+						setBool(ok, true);
+						return storeValue(res);
+					} catch (err) {
+						setBool(ok, false);
+						return storeValue(err);
+					}
+				},
+
+				value_new: (v, args, nargs, ok) => {
+					try {
+						const res = Reflect.construct(loadValue(v), loadRefs(args, nargs));
+						setBool(ok, true);
+						return storeValue(res);
+					} catch (err) {
+						setBool(ok, false);
+						return storeValue(err);
+					}
+				},
+
+				value_prepare_string: (v, n) => {
+					const s = encoder.encode(String(loadValue(v)));
+					mem().setInt32(n, s.length, true);
+					return storeValue(s);
+				},
+
+				value_load_string: (v, b, n) => {
+					bytes(b, n).set(loadValue(v).subarray(0, n));
+				},
+
+				value_instance_of: (v, t) => loadValue(v) instanceof loadValue(t),
+
+				copy_bytes_to_go: (dst, n, src) => {
+					const from = loadValue(src);
+					if (!isBytes(from)) {
+						return -1;
+					}
+					const toCopy = from.subarray(0, n);
+					bytes(dst, toCopy.length).set(toCopy);
+					return toCopy.length;
+				},
+
+				copy_bytes_to_js: (dst, src, n) => {
+					const to = loadValue(dst);
+					if (!isBytes(to)) {
+						return -1;
+					}
+					const toCopy = bytes(src, Math.min(n, to.length));
+					to.set(toCopy);
+					return toCopy.length;
+				},
+			};
+
+			// The C library may import WASI functions the loader does not
+			// implement: they fail with ENOSYS.
+			const missing = (target, name) => {
+				if (name in target || typeof name !== "string") {
+					return target[name];
+				}
+				return () => ENOSYS;
+			};
+
+			this.importObject = {
+				wasi_snapshot_preview1: new Proxy(wasi, { get: missing }),
+				env: { memory: this.memory },
+				llgo_js: llgoJS,
+			};
+		}
+
+		// run runs the program of instance and returns a promise of its exit
+		// code. A program blocked in main keeps serving callbacks until it
+		// exits.
+		run(instance) {
+			if (this._inst !== null) {
+				throw new Error("llgo: the program is already running");
+			}
+			this._inst = instance;
+			this._call(() => {
+				instance.exports._start();
+				this._exit(0);
+			});
+			return this._exitPromise;
+		}
+
+		// _makeFuncWrapper returns the JavaScript function of js.FuncOf.
+		_makeFuncWrapper(id) {
+			const go = this;
+			return function (...args) {
+				if (go.exited) {
+					throw new Error("llgo: program has already exited");
+				}
+				const event = { id: id, this: this, args: args };
+				go._pendingEvent = event;
+				go._call(() => go._inst.exports.llgo_js_resume());
+				return event.result;
+			};
+		}
+
+		// _call runs fn, which enters the program. An exit unwinds all the
+		// program frames, including the ones calling back into JavaScript,
+		// while a park only unwinds the frames of the current call.
+		_call(fn) {
+			this._depth++;
+			try {
+				fn();
+			} catch (e) {
+				if (e instanceof ExitStatus) {
+					this._exit(e.code);
+					if (this._depth > 1) {
+						throw e;
+					}
+				} else if (e !== park) {
+					throw e;
+				}
+			} finally {
+				this._depth--;
+			}
+		}
+
+		_exit(code) {
+			if (this.exited) {
+				return;
+			}
+			for (const fd of [1, 2]) {
+				if (this._lines[fd] !== "") {
+					(fd === 2 ? console.error : console.log)(this._lines[fd]);
+					this._lines[fd] = "";
+				}
+			}
+			this.exited = true;
+			this.exitCode = code;
+			this._resolveExit(code);
+		}
+	}
+
+	globalThis.LLGo = LLGo;
+
+	if (isNode) {
+		if (require.main !== module) {
+			return;
+		}
+		const fs = require("fs");
+		const path = require("path");
+		const file = path.join(__dirname, wasmFile);
+		const go = new LLGo();
+		go.argv = [file].concat(process.argv.slice(2));
+		go.env = Object.assign({}, process.env);
+		WebAssembly.instantiate(fs.readFileSync(file), go.importObject).then(({ instance }) => {
+			return go.run(instance);
+		}).then((code) => {
+			process.exit(code);
+		}).catch((err) => {
+			console.error(err);
+			process.exit(1);
+		});
+	} else if (typeof document !== "undefined" && document.currentScript) {
+		const url = new URL(wasmFile, document.currentScript.src);
+		const go = new LLGo();
+		go.argv = [wasmFile];
+		WebAssembly.instantiateStreaming(fetch(url), go.importObject).then(({ instance }) => {
+			return go.run(instance);
+		}).catch((err) => {
+			console.error(err);
+		});
+	}
+})();
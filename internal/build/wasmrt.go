@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	stdcontext "context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// wasmRuntimeBuiltin selects the WASI runtime built into llgo.
+const wasmRuntimeBuiltin = "builtin"
+
+// builtinWasmRunner runs wasi programs in process with wazero, so that no
+// WASI runtime needs to be installed. Programs only see dir (or the current
+// directory if it is empty), which is mounted at / so that relative paths
+// are resolved in it, as in a working directory.
+//
+// The wazero release supporting the Go version llgo is built with has no
+// exception handling, so programs using it (eg. setjmp and longjmp with
+// -fwasm-exceptions) need another WASI runtime.
+type builtinWasmRunner struct{}
+
+func (builtinWasmRunner) run(app string, args []string, dir string, stdin io.Reader, stdout, stderr io.Writer) error {
+	bin, err := os.ReadFile(app)
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return err
+		}
+	}
+	ctx := stdcontext.Background()
+	// llgo programs use atomics for pthread emulation.
+	features := api.CoreFeaturesV2 | experimental.CoreFeaturesThreads
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCoreFeatures(features))
+	defer r.Close(ctx)
+
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+	mod, err := r.CompileModule(ctx, bin)
+	if err != nil {
+		return fmt.Errorf("%s: %v (set LLGO_WASM_RUNTIME to use another WASI runtime)", app, err)
+	}
+	if err = instantiateMemories(ctx, r, mod.ImportedMemories()); err != nil {
+		return err
+	}
+
+	conf := wazero.NewModuleConfig().
+		WithArgs(append([]string{filepath.Base(app)}, args...)...).
+		WithStdin(stdin).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader).
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(dir, "/"))
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			conf = conf.WithEnv(k, v)
+		}
+	}
+	_, err = r.InstantiateModule(ctx, mod, conf)
+	var e *sys.ExitError
+	if errors.As(err, &e) {
+		if code := e.ExitCode(); code != 0 {
+			return exitError(code)
+		}
+		return nil
+	}
+	return err
+}
+
+// instantiateMemories defines the memories a program linked with
+// --import-memory imports, one module per import module name.
+func instantiateMemories(ctx stdcontext.Context, r wazero.Runtime, mems []api.MemoryDefinition) error {
+	var names []string
+	byModule := make(map[string][]api.MemoryDefinition)
+	for _, mem := range mems {
+		module, _, _ := mem.Import()
+		if _, ok := byModule[module]; !ok {
+			names = append(names, module)
+		}
+		byModule[module] = append(byModule[module], mem)
+	}
+	for _, module := range names {
+		conf := wazero.NewModuleConfig().WithName(module)
+		if _, err := r.InstantiateWithConfig(ctx, memoryModule(byModule[module]), conf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memoryModule returns the binary of a wasm module that exports the
+// memories mems are imported as, with the same limits.
+func memoryModule(mems []api.MemoryDefinition) []byte {
+	memories := binary.AppendUvarint(nil, uint64(len(mems)))
+	exports := binary.AppendUvarint(nil, uint64(len(mems)))
+	for i, mem := range mems {
+		max, hasMax := mem.Max()
+		if hasMax {
+			memories = append(memories, 1)
+			memories = binary.AppendUvarint(memories, uint64(mem.Min()))
+			memories = binary.AppendUvarint(memories, uint64(max))
+		} else {
+			memories = append(memories, 0)
+			memories = binary.AppendUvarint(memories, uint64(mem.Min()))
+		}
+		_, name, _ := mem.Import()
+		exports = binary.AppendUvarint(exports, uint64(len(name)))
+		exports = append(exports, name...)
+		exports = append(exports, 2) // memory
+		exports = binary.AppendUvarint(exports, uint64(i))
+	}
+	b := []byte("\x00asm\x01\x00\x00\x00")
+	b = appendWasmSection(b, 5, memories)
+	b = appendWasmSection(b, 7, exports)
+	return b
+}
+
+func appendWasmSection(b []byte, id byte, content []byte) []byte {
+	b = append(b, id)
+	b = binary.AppendUvarint(b, uint64(len(content)))
+	return append(b, content...)
+}
@@ -0,0 +1,955 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cbind generates the Go package binding a C library for llgo from
+// its headers, as "llgo bind c" does.
+package cbind
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llgo/xtool/clang"
+	"github.com/goplus/llgo/xtool/clang/ast"
+	"github.com/goplus/llgo/xtool/clang/parser"
+)
+
+// -----------------------------------------------------------------------------
+
+// Config is the configuration of Gen.
+type Config struct {
+	Headers []string   // headers of the library
+	Package string     // package name, the name of the first header by default
+	Link    []string   // link flags of the library, such as -lz
+	Trim    []string   // prefixes trimmed from the C names
+	Clang   *clang.Cmd // clang command, clang in PATH if nil
+	Flags   []string   // flags of clang, such as -I and -D
+	Cpp     bool       // the headers are C++ headers
+}
+
+// Gen returns the source of the Go package binding the declarations of the
+// headers conf.Headers: their structs, unions, enums, typedefs, functions,
+// variables and the constants their macros define.
+func Gen(conf *Config) ([]byte, error) {
+	if len(conf.Headers) == 0 {
+		return nil, errors.New("no header to bind")
+	}
+	ext := ".c"
+	if conf.Cpp {
+		ext = ".cpp"
+	}
+	f, err := os.CreateTemp("", "cbind*"+ext)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	headers := make(map[string]bool)
+	for _, header := range conf.Headers {
+		abs, err := filepath.Abs(header)
+		if err != nil {
+			return nil, err
+		}
+		headers[abs] = true
+		fmt.Fprintf(f, "#include %q\n", abs)
+	}
+	if err = f.Close(); err != nil {
+		return nil, err
+	}
+	pconf := &parser.Config{Clang: conf.Clang, Flags: conf.Flags}
+	file, err := parser.ParseFile(f.Name(), pconf)
+	if err != nil {
+		return nil, err
+	}
+	macros, err := parser.DumpMacros(f.Name(), pconf)
+	if err != nil {
+		return nil, err
+	}
+	return gen(conf, headers, file, macros)
+}
+
+// -----------------------------------------------------------------------------
+
+type generator struct {
+	conf    *Config
+	headers map[string]bool // headers to bind
+
+	names    map[string]string    // Go name => C name of the declarations
+	types    map[string]*cType    // typedef or tag name => type of the package
+	typedefs map[string]*ast.Type // typedef name => underlying type, of all headers
+	consts   map[string]string    // C name => Go name of the constants
+	layouts  map[string]layout    // Go type of the package => layout
+	anon     map[ast.ID]string    // unnamed struct, union or enum => typedef name
+	merged   map[ast.ID]bool      // typedefs declaring the Go type of their tag
+	tags     []string             // tags of the package, in source order
+	defined  map[string]bool      // tags with a definition
+	classes  map[string]*class    // Go type => C++ class
+
+	anonField *cType // type of the unnamed record of the next field
+
+	b bytes.Buffer
+}
+
+// gen generates the package binding the declarations of the headers in the
+// AST file, and the constants of the macros, the output of DumpMacros.
+func gen(conf *Config, headers map[string]bool, file *ast.Node, macros []byte) ([]byte, error) {
+	g := &generator{
+		conf:     conf,
+		headers:  headers,
+		names:    make(map[string]string),
+		types:    make(map[string]*cType),
+		typedefs: make(map[string]*ast.Type),
+		consts:   make(map[string]string),
+		layouts:  make(map[string]layout),
+		anon:     make(map[ast.ID]string),
+		merged:   make(map[ast.ID]bool),
+		defined:  make(map[string]bool),
+		classes:  make(map[string]*class),
+	}
+	decls := g.decls(file)
+	g.declTypes(decls)
+
+	for _, decl := range decls {
+		switch decl.Kind {
+		case ast.RecordDecl, ast.CXXRecordDecl:
+			g.genRecord(decl)
+		case ast.EnumDecl:
+			g.genEnum(decl)
+		case ast.TypedefDecl:
+			g.genTypedef(decl)
+		case ast.FunctionDecl:
+			g.genFunc(decl)
+		case ast.VarDecl:
+			g.genVar(decl)
+		}
+	}
+	for _, tag := range g.tags {
+		if !g.defined[tag] {
+			// an incomplete type, only used by pointers
+			fmt.Fprintf(&g.b, "\ntype %s struct {\n\tUnused [8]byte\n}\n", g.types[tag].name)
+		}
+	}
+	consts := g.genMacros(macros)
+
+	pkgName := conf.Package
+	if pkgName == "" {
+		name := filepath.Base(conf.Headers[0])
+		pkgName = strings.ToLower(genName(strings.TrimSuffix(name, filepath.Ext(name)), ""))
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "package %s\n\nimport (\n\t_ \"unsafe\"\n", pkgName)
+	if usesC(g.b.Bytes()) {
+		b.WriteString("\n\t\"github.com/goplus/lib/c\"\n")
+	}
+	link := "decl"
+	if len(conf.Link) > 0 {
+		link = "link: " + strings.Join(conf.Link, " ")
+	}
+	fmt.Fprintf(&b, ")\n\nconst (\n\tLLGoPackage = %q\n)\n", link)
+	b.Write(consts)
+	b.Write(g.b.Bytes())
+	return format.Source(b.Bytes())
+}
+
+// usesC reports whether the declarations src refer to the package c.
+func usesC(src []byte) bool {
+	for _, line := range bytes.Split(src, []byte("\n")) {
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("//")) && refC.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+var refC = regexp.MustCompile(`(^|[^\w.])c\.[A-Z]`)
+
+// decls returns the declarations of the headers to bind, in source order.
+// The declarations of the C++ namespaces, except std, and of the extern "C"
+// blocks are flattened.
+func (g *generator) decls(file *ast.Node) []*ast.Node {
+	var cur string // file of the last location, only dumped when it changes
+	return g.declsIn(file.Inner, &cur, nil)
+}
+
+func (g *generator) declsIn(inner []*ast.Node, cur *string, decls []*ast.Node) []*ast.Node {
+	for _, decl := range inner {
+		*cur = locFile(decl.Loc, *cur)
+		in := g.headers[*cur] && !decl.IsImplicit
+		if decl.Range != nil {
+			*cur = posFile(&decl.Range.Begin, *cur)
+			*cur = posFile(&decl.Range.End, *cur)
+		}
+		switch decl.Kind {
+		case ast.LinkageSpecDecl, ast.NamespaceDecl:
+			if decl.Name != "std" && !strings.HasPrefix(decl.Name, "__") {
+				decls = g.declsIn(decl.Inner, cur, decls)
+				continue
+			}
+		case ast.TypedefDecl:
+			if decl.Type != nil {
+				g.typedefs[decl.Name] = decl.Type
+			}
+		}
+		if in {
+			decls = append(decls, decl)
+		}
+		*cur = innerFile(decl.Inner, *cur)
+	}
+	return decls
+}
+
+func locFile(loc *ast.Loc, cur string) string {
+	switch {
+	case loc == nil:
+	case loc.SpellingLoc != nil || loc.ExpansionLoc != nil:
+		cur = locFile(loc.SpellingLoc, cur)
+		cur = locFile(loc.ExpansionLoc, cur)
+	case loc.File != "":
+		cur = loc.File
+	}
+	return cur
+}
+
+func posFile(pos *ast.Pos, cur string) string {
+	switch {
+	case pos.SpellingLoc != nil || pos.ExpansionLoc != nil:
+		cur = locFile(pos.SpellingLoc, cur)
+		cur = locFile(pos.ExpansionLoc, cur)
+	case pos.File != "":
+		cur = pos.File
+	}
+	return cur
+}
+
+func innerFile(inner []*ast.Node, cur string) string {
+	for _, node := range inner {
+		cur = locFile(node.Loc, cur)
+		if node.Range != nil {
+			cur = posFile(&node.Range.Begin, cur)
+			cur = posFile(&node.Range.End, cur)
+		}
+		cur = innerFile(node.Inner, cur)
+	}
+	return cur
+}
+
+// declTypes declares the Go names of the typedefs and the tags of decls. A
+// typedef naming a tag, such as typedef struct foo {...} foo_t, gives its name
+// to the type of the tag.
+func (g *generator) declTypes(decls []*ast.Node) {
+	for _, decl := range decls {
+		if decl.Kind != ast.TypedefDecl {
+			continue
+		}
+		tag := tagOf(decl.Type.QualType)
+		if tag == "" {
+			continue
+		}
+		name := g.typeName(decl.Name)
+		if owned := ownedTag(decl); owned != nil && owned.Name == "" {
+			if g.declare(name, decl.Name) {
+				g.anon[owned.ID] = name
+				g.merged[decl.ID] = true
+				g.declTag(tag, name)
+			}
+		} else if _, ok := g.types[tag]; ok || strings.IndexByte(tag, '(') >= 0 || builtinTypes[tag] != "" {
+			continue
+		} else if g.declare(name, decl.Name) {
+			g.merged[decl.ID] = true
+			g.declTag(tag, name)
+		}
+		if g.merged[decl.ID] {
+			g.types[decl.Name] = g.types[tag]
+		}
+	}
+	for _, decl := range decls {
+		g.declRecord(decl)
+	}
+}
+
+// declRecord declares the Go name of the tag of decl, and those of the
+// records it contains, whose tags have the file scope.
+func (g *generator) declRecord(decl *ast.Node) {
+	switch decl.Kind {
+	case ast.RecordDecl, ast.CXXRecordDecl, ast.EnumDecl:
+		if decl.Name == "" || decl.IsImplicit {
+			break
+		}
+		tag := tagKind(decl) + " " + decl.Name
+		if _, ok := g.types[tag]; !ok {
+			name := g.typeName(decl.Name)
+			if g.declare(name, tag) {
+				g.declTag(tag, name)
+			}
+		}
+		if t, ok := g.types[tag]; ok && g.conf.Cpp {
+			// a C++ tag is a type name, and class and struct are the same
+			g.types[decl.Name] = t
+			if decl.Kind == ast.CXXRecordDecl {
+				g.types["class "+decl.Name] = t
+				g.types["struct "+decl.Name] = t
+			}
+		}
+		for _, node := range decl.Inner {
+			g.declRecord(node)
+		}
+	}
+}
+
+func (g *generator) declTag(tag, name string) {
+	g.types[tag] = &cType{kind: cBasic, name: name}
+	g.tags = append(g.tags, tag)
+	if strings.HasPrefix(tag, "enum ") {
+		g.defined[tag] = true
+		g.layouts[name] = layout{4, 4}
+	}
+}
+
+func tagKind(decl *ast.Node) string {
+	if decl.Kind == ast.EnumDecl {
+		return "enum"
+	}
+	return decl.TagUsed
+}
+
+// tagOf returns the tag of a qualType such as "struct foo", or "".
+func tagOf(qualType string) string {
+	for _, kind := range []string{"struct ", "union ", "enum "} {
+		if strings.HasPrefix(qualType, kind) {
+			return qualType
+		}
+	}
+	return ""
+}
+
+// ownedTag returns the struct, union or enum a typedef declares.
+func ownedTag(decl *ast.Node) *ast.Node {
+	for _, node := range decl.Inner {
+		if node.Kind == ast.ElaboratedType && node.OwnedTagDecl != nil {
+			return node.OwnedTagDecl
+		}
+	}
+	return nil
+}
+
+// declare declares the Go name of the C declaration cName. It reports false,
+// and logs why, if the name is already declared.
+func (g *generator) declare(name, cName string) bool {
+	if name == "" || name == "LLGoPackage" {
+		log.Println("skip", cName+": invalid Go name", name)
+		return false
+	}
+	if other, ok := g.names[name]; ok {
+		if other != cName {
+			log.Println("skip", cName+":", name, "redeclared by", other)
+		}
+		return false
+	}
+	g.names[name] = cName
+	return true
+}
+
+// -----------------------------------------------------------------------------
+
+// lookup returns the type of a typedef or tag name of a qualType.
+func (g *generator) lookup(name string) (*cType, error) {
+	if t, ok := g.types[name]; ok {
+		return t, nil
+	}
+	if t, ok := builtinTypes[name]; ok {
+		return &cType{kind: cBasic, name: t}, nil
+	}
+	if strings.Contains(name, "(unnamed ") || strings.Contains(name, "(anonymous ") {
+		if g.anonField != nil {
+			return g.anonField, nil
+		}
+	} else if t, ok := g.typedefs[name]; ok { // a typedef of another header
+		return g.parseType(t)
+	} else if i := strings.LastIndex(name, "::"); i >= 0 && !strings.HasPrefix(name, "std::") {
+		return g.lookup(name[i+2:]) // a name of a flattened namespace
+	}
+	return nil, errUnknown
+}
+
+// parseType parses the type of a declaration.
+func (g *generator) parseType(t *ast.Type) (*cType, error) {
+	if t == nil {
+		return nil, errors.New("missing type")
+	}
+	return parseType(t.QualType, g.lookup)
+}
+
+// genDoc generates the doc comment of decl from its comment.
+func (g *generator) genDoc(decl *ast.Node) {
+	for _, node := range decl.Inner {
+		if node.Kind != ast.FullComment {
+			continue
+		}
+		var paras []string
+		for _, para := range node.Inner {
+			var lines []string
+			for _, text := range para.Inner {
+				if text.Kind == ast.TextComment {
+					if line := strings.TrimSpace(text.Text); line != "" {
+						lines = append(lines, line)
+					}
+				}
+			}
+			if lines != nil {
+				paras = append(paras, "// "+strings.Join(lines, "\n// "))
+			}
+		}
+		if paras != nil {
+			fmt.Fprintf(&g.b, "%s\n", strings.Join(paras, "\n//\n"))
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func (g *generator) genRecord(decl *ast.Node) {
+	if !decl.CompleteDefinition {
+		return
+	}
+	var tag, name string
+	if decl.Name != "" {
+		tag = tagKind(decl) + " " + decl.Name
+		t, ok := g.types[tag]
+		if !ok || g.defined[tag] {
+			return
+		}
+		name = t.name
+	} else if name = g.anon[decl.ID]; name == "" {
+		return
+	} else {
+		tag = g.tagOfName(name)
+	}
+	var cls *class
+	if decl.Kind == ast.CXXRecordDecl {
+		var err error
+		if cls, err = g.newClass(decl, name); err != nil {
+			log.Println("skip", name+":", err)
+			return
+		}
+	}
+	typ, l, err := g.recordType(decl, cls)
+	if err != nil {
+		log.Println("skip", name+":", err)
+		return
+	}
+	g.defined[tag] = true
+	if l.align > 0 {
+		g.layouts[name] = l
+	}
+	fmt.Fprintln(&g.b)
+	g.genDoc(decl)
+	fmt.Fprintf(&g.b, "type %s %s\n", name, typ)
+	if cls != nil {
+		g.classes[name] = cls
+		g.genClass(cls)
+	}
+}
+
+func (g *generator) tagOfName(name string) string {
+	for _, tag := range g.tags {
+		if g.types[tag].name == name {
+			return tag
+		}
+	}
+	return ""
+}
+
+// A recordLayout lays out the fields of a struct or a union.
+type recordLayout struct {
+	union  bool
+	opaque bool // a union, or a struct with bit fields
+	known  bool // the layout is known
+	fields []string
+	bits   int64 // size of a struct so far, in bits
+	layout
+}
+
+// add adds the field of type t, a bit field of width bits if width >= 0.
+func (r *recordLayout) add(field string, t *cType, width int64, layouts map[string]layout) {
+	r.fields = append(r.fields, field)
+	fl, ok := layoutOf(t, layouts)
+	if !ok {
+		r.known = false
+		return
+	}
+	r.align = max(r.align, fl.align)
+	switch {
+	case r.union:
+		r.size = max(r.size, fl.size)
+	case width >= 0:
+		// a bit field does not straddle the units of its type
+		unit := fl.size * 8
+		if width == 0 || r.bits/unit != (r.bits+width-1)/unit {
+			r.bits = alignUp(r.bits, unit)
+		}
+		r.bits += width
+		r.opaque = true
+	default:
+		r.bits = alignUp(r.bits, fl.align*8) + fl.size*8
+	}
+}
+
+// recordType returns the Go type of the struct or union decl, and its layout
+// if it is known. A union, or a struct with bit fields, has no Go equivalent:
+// it is an opaque struct of the same layout.
+//
+// The Go type of a C++ class cls starts with its base classes, embedded, and
+// its virtual table pointer Vptr if its primary base has none. Its private and
+// protected fields are unexported.
+func (g *generator) recordType(decl *ast.Node, cls *class) (typ string, l layout, err error) {
+	r := &recordLayout{union: decl.TagUsed == "union", known: true}
+	r.opaque = r.union
+	access := defaultAccess(decl)
+	tail := int64(-1) // end of the data of the last base class, if not a POD
+	if cls != nil {
+		if cls.poly && cls.primary == nil {
+			r.add("Vptr *"+cls.name+"Vtbl", &cType{kind: cPointer}, -1, g.layouts)
+		}
+		for _, base := range cls.bases {
+			start := alignUp(r.bits, g.layouts[base.name].align*8) / 8
+			r.add(base.name, &cType{kind: cBasic, name: base.name}, -1, g.layouts)
+			if tail = -1; !base.pod {
+				tail = start + base.dsize
+			}
+		}
+	}
+	var anonField *cType
+	for _, node := range decl.Inner {
+		if node.IsImplicit {
+			continue
+		}
+		switch node.Kind {
+		case ast.AccessSpecDecl:
+			access = node.Access
+		case ast.RecordDecl, ast.CXXRecordDecl:
+			if node.Name != "" {
+				g.genRecord(node)
+			} else if node.CompleteDefinition {
+				typ, l, err := g.recordType(node, nil)
+				if err != nil {
+					return "", layout{}, err
+				}
+				anonField = &cType{kind: cBasic, name: typ}
+				if l.align > 0 {
+					g.layouts[typ] = l
+				}
+			}
+		case ast.EnumDecl:
+			g.genEnum(node)
+		case ast.FieldDecl:
+			g.anonField = anonField
+			t, e := g.parseType(node.Type)
+			g.anonField = nil
+			if e != nil {
+				return "", layout{}, e
+			}
+			ft, e := goType(t, false)
+			if e != nil {
+				return "", layout{}, e
+			}
+			name := fieldName(node, access)
+			if name == "" {
+				name = fmt.Sprintf("Anon%d", len(r.fields))
+			}
+			if fl, ok := layoutOf(t, g.layouts); ok && tail >= 0 && alignUp(tail, fl.align) < r.bits/8 {
+				// the Itanium C++ ABI reuses the tail padding of a base class
+				return "", layout{}, errors.New("fields in the tail padding of a base class are not supported")
+			}
+			tail = -1
+			width := int64(-1)
+			if node.IsBitfield {
+				var ok bool
+				if width, ok = bitWidth(node); !ok {
+					return "", layout{}, errors.New("unknown bit field width")
+				}
+			}
+			r.add(name+" "+ft, t, width, g.layouts)
+		}
+	}
+	if !r.known {
+		if r.opaque {
+			return "", layout{}, errors.New("unknown layout")
+		}
+		return "struct {\n" + strings.Join(r.fields, "\n") + "\n}", layout{}, nil
+	}
+	l = r.layout
+	if !r.union {
+		l.size = (r.bits + 7) / 8
+	}
+	if cls != nil {
+		cls.dsize = l.size
+	}
+	l.align = max(l.align, 1)
+	l.size = alignUp(l.size, l.align)
+	if r.opaque {
+		return fmt.Sprintf("struct {\n\tUnused [%d]%s\n}", l.size/l.align, uintOfSize(l.align)), l, nil
+	}
+	return "struct {\n" + strings.Join(r.fields, "\n") + "\n}", l, nil
+}
+
+// defaultAccess returns the access of the first members of decl.
+func defaultAccess(decl *ast.Node) string {
+	if decl.TagUsed == "class" {
+		return "private"
+	}
+	return "public"
+}
+
+// fieldName returns the Go name of the field decl, unexported if its access
+// is not public, or "" for an unnamed field.
+func fieldName(decl *ast.Node, access string) string {
+	name := genName(decl.Name, "")
+	if decl.Access != "" {
+		access = decl.Access
+	}
+	if name != "" && access != "public" {
+		name = strings.ToLower(name[:1]) + name[1:]
+	}
+	return name
+}
+
+func bitWidth(field *ast.Node) (int64, bool) {
+	for _, node := range field.Inner {
+		if node.Kind == ast.ConstantExpr {
+			if v, ok := node.Value.(string); ok {
+				n, err := strconv.ParseInt(v, 0, 64)
+				return n, err == nil
+			}
+		}
+	}
+	return 0, false
+}
+
+func alignUp(n, align int64) int64 {
+	return (n + align - 1) / align * align
+}
+
+func uintOfSize(size int64) string {
+	switch size {
+	case 2:
+		return "uint16"
+	case 4:
+		return "uint32"
+	case 8:
+		return "uint64"
+	}
+	return "byte"
+}
+
+func (g *generator) genEnum(decl *ast.Node) {
+	if len(decl.Inner) == 0 { // a forward declaration
+		return
+	}
+	name := ""
+	if decl.Name != "" {
+		if t, ok := g.types["enum "+decl.Name]; ok {
+			name = t.name
+		}
+	} else {
+		name = g.anon[decl.ID]
+	}
+	typ := "c.Int"
+	if decl.FixedUnderlyingType != nil { // enum foo : uint8_t
+		t, err := g.parseType(decl.FixedUnderlyingType)
+		if err == nil {
+			typ, err = goType(t, false)
+		}
+		if err != nil {
+			log.Println("skip", decl.Name+":", err)
+			return
+		}
+		if l, ok := layoutOf(t, g.layouts); ok && name != "" {
+			g.layouts[name] = l
+		}
+	}
+	b := &g.b
+	fmt.Fprintln(b)
+	if name != "" {
+		g.genDoc(decl)
+		fmt.Fprintf(b, "type %s %s\n\n", name, typ)
+	}
+	fmt.Fprintln(b, "const (")
+	var val int64 = -1
+	for _, node := range decl.Inner {
+		if node.Kind != ast.EnumConstantDecl {
+			continue
+		}
+		val++
+		for _, expr := range node.Inner {
+			if expr.Kind == ast.ConstantExpr {
+				if v, ok := expr.Value.(string); ok {
+					if n, err := strconv.ParseInt(v, 0, 64); err == nil {
+						val = n
+					}
+				}
+			}
+		}
+		cname := g.constName(node.Name)
+		if decl.ScopedEnumTag != "" && name != "" { // enum class foo
+			cname = name + strings.ToUpper(cname[:1]) + cname[1:]
+		}
+		if !g.declare(cname, node.Name) {
+			continue
+		}
+		g.consts[node.Name] = cname
+		if name != "" {
+			fmt.Fprintf(b, "\t%s %s = %d\n", cname, name, val)
+		} else {
+			fmt.Fprintf(b, "\t%s = %d\n", cname, val)
+		}
+	}
+	fmt.Fprintln(b, ")")
+}
+
+func (g *generator) genTypedef(decl *ast.Node) {
+	if g.merged[decl.ID] {
+		return
+	}
+	t, err := g.parseType(decl.Type)
+	if err == nil && t.kind == cFunc {
+		err = errors.New("function types are not supported, only pointers to functions")
+	}
+	if err != nil {
+		log.Println("skip", decl.Name+":", err)
+		return
+	}
+	name := g.typeName(decl.Name)
+	if !g.declare(name, decl.Name) {
+		return
+	}
+	b := &g.b
+	fmt.Fprintln(b)
+	g.genDoc(decl)
+	if t.kind == cPointer && t.elem.kind == cFunc {
+		fn, err := goFunc(t.elem)
+		if err != nil {
+			log.Println("skip", decl.Name+":", err)
+			return
+		}
+		fmt.Fprintf(b, "// llgo:type C\ntype %s %s\n", name, fn)
+		g.layouts[name] = layout{8, 8}
+	} else {
+		typ, err := goType(t, false)
+		if err != nil {
+			log.Println("skip", decl.Name+":", err)
+			return
+		}
+		fmt.Fprintf(b, "type %s = %s\n", name, typ)
+		if l, ok := layoutOf(t, g.layouts); ok {
+			g.layouts[name] = l
+		}
+	}
+	g.types[decl.Name] = &cType{kind: cBasic, name: name}
+}
+
+func (g *generator) genFunc(decl *ast.Node) {
+	if decl.StorageClass == ast.Static || isInline(decl) || strings.HasPrefix(decl.Name, "__") ||
+		strings.HasPrefix(decl.Name, "operator") {
+		return
+	}
+	name, sym := g.funcName(decl.Name), symbol(decl)
+	if g.names[name] == sym {
+		return // redeclared
+	}
+	fn, err := g.parseType(decl.Type)
+	if err == nil && fn.kind != cFunc {
+		err = errors.New("not a function")
+	}
+	var params []param
+	if err == nil {
+		params, err = g.params(decl, fn, true)
+	}
+	var ret string
+	if err == nil {
+		ret, err = goResult(fn.elem)
+	}
+	if err != nil {
+		log.Println("skip", decl.Name+":", err)
+		return
+	}
+	if !g.declare(name, sym) {
+		return
+	}
+	b := &g.b
+	fmt.Fprintln(b)
+	g.genDoc(decl)
+	fmt.Fprintf(b, "//go:linkname %s C.%s\nfunc %s(%s)%s\n", name, sym, name, joinParams(params), ret)
+}
+
+// isInline reports whether the function decl is inline, so that the library
+// may not define it.
+func isInline(decl *ast.Node) bool {
+	if decl.Inline {
+		return true
+	}
+	for _, node := range decl.Inner {
+		if node.Kind == ast.CompoundStmt { // defined in a header
+			return true
+		}
+	}
+	return false
+}
+
+// symbol returns the symbol of the function or variable decl: its name, or
+// its mangled name in C++.
+func symbol(decl *ast.Node) string {
+	sym := decl.MangledName
+	switch {
+	case sym == "":
+		return decl.Name
+	case sym == "_"+decl.Name || strings.HasPrefix(sym, "__Z"):
+		return sym[1:] // the global prefix of Mach-O
+	}
+	return sym
+}
+
+// A param is a parameter of a Go function.
+type param struct {
+	name, typ string
+}
+
+func joinParams(params []param) string {
+	list := make([]string, len(params))
+	for i, p := range params {
+		list[i] = p.name + " " + p.typ
+	}
+	return strings.Join(list, ", ")
+}
+
+// params returns the parameters of the function decl of type fn. A pointer to
+// a function is a Go func if funcs is true, or a c.Pointer. The parameters
+// named as one of reserved are renamed.
+func (g *generator) params(decl *ast.Node, fn *cType, funcs bool, reserved ...string) ([]param, error) {
+	var names []string
+	for _, node := range decl.Inner {
+		if node.Kind == ast.ParmVarDecl {
+			names = append(names, node.Name)
+		}
+	}
+	params := make([]param, 0, len(fn.params)+1)
+	for i, t := range fn.params {
+		typ, err := goType(t, funcs)
+		if err != nil {
+			return nil, err
+		}
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		switch {
+		case name == "":
+			name = fmt.Sprintf("p%d", i)
+		case token.IsKeyword(name) || name == "c" || types.Universe.Lookup(name) != nil ||
+			slices.Contains(reserved, name):
+			name += "_"
+		}
+		params = append(params, param{name, typ})
+	}
+	if fn.variadic {
+		params = append(params, param{"__llgo_va_list", "...any"})
+	}
+	return params, nil
+}
+
+func (g *generator) genVar(decl *ast.Node) {
+	if decl.StorageClass == ast.Static {
+		return
+	}
+	t, err := g.parseType(decl.Type)
+	var typ string
+	if err == nil {
+		typ, err = goType(t, false)
+	}
+	if err != nil {
+		log.Println("skip", decl.Name+":", err)
+		return
+	}
+	name := g.funcName(decl.Name)
+	if !g.declare(name, decl.Name) {
+		return
+	}
+	b := &g.b
+	fmt.Fprintln(b)
+	g.genDoc(decl)
+	fmt.Fprintf(b, "//go:linkname %s %s\nvar %s %s\n", name, symbol(decl), name, typ)
+}
+
+// -----------------------------------------------------------------------------
+
+// trim trims the longest prefix of conf.Trim from the C name.
+func (g *generator) trim(name string) string {
+	trimmed := name
+	for _, prefix := range g.conf.Trim {
+		if s, ok := strings.CutPrefix(name, prefix); ok && len(s) < len(trimmed) && s != "" {
+			trimmed = s
+		}
+	}
+	return trimmed
+}
+
+// typeName returns the Go name of a C type: foo_bar_t => FooBarT.
+func (g *generator) typeName(name string) string {
+	return genName(g.trim(name), "")
+}
+
+// funcName returns the Go name of a C function or variable: foo_bar => FooBar.
+func (g *generator) funcName(name string) string {
+	return genName(g.trim(name), "")
+}
+
+// constName returns the Go name of a C constant, exported but keeping its
+// case: FOO_BAR => FOO_BAR.
+func (g *generator) constName(name string) string {
+	name = strings.TrimLeft(g.trim(name), "_")
+	if name == "" || !isIdentChar(name[0]) || name[0] >= '0' && name[0] <= '9' {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// genName returns the exported Go name of the C name: its words separated by
+// underscores are capitalized and joined, as foo_bar => FooBar. It returns ""
+// if name has no word.
+func genName(name, sep string) string {
+	var words []string
+	for _, w := range strings.Split(name, "_") {
+		if w != "" {
+			words = append(words, strings.ToUpper(w[:1])+w[1:])
+		}
+	}
+	name = strings.Join(words, sep)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return ""
+	}
+	return name
+}
+
+// -----------------------------------------------------------------------------
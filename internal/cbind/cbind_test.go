@@ -0,0 +1,101 @@
+//go:build !llgo
+// +build !llgo
+
+package cbind
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/goplus/llgo/xtool/clang/ast"
+)
+
+func TestGen(t *testing.T) {
+	tests := []struct {
+		dir    string
+		header string
+		conf   *Config
+	}{
+		{"foo", "/foo/foo.h", &Config{Link: []string{"-lfoo"}, Trim: []string{"foo_", "FOO_"}}},
+		{"shape", "/foo/shape.h", &Config{Link: []string{"-lshape"}, Cpp: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			dir := "testdata/" + tt.dir + "/"
+			data, err := os.ReadFile(dir + "ast.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			file := new(ast.Node)
+			if err = json.Unmarshal(data, file); err != nil {
+				t.Fatal(err)
+			}
+			macros, err := os.ReadFile(dir + "macros.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.conf.Headers = []string{tt.header}
+			out, err := gen(tt.conf, map[string]bool{tt.header: true}, file, macros)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := os.ReadFile(dir + "out.expect")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != string(expected) {
+				t.Fatalf("gen:\n%s\nexpected:\n%s", out, expected)
+			}
+		})
+	}
+}
+
+func TestParseType(t *testing.T) {
+	lookup := func(name string) (*cType, error) {
+		switch name {
+		case "foo_t", "struct foo":
+			return &cType{kind: cBasic, name: "Foo"}, nil
+		case "size_t":
+			return &cType{kind: cBasic, name: "uintptr"}, nil
+		}
+		return nil, errUnknown
+	}
+	tests := []struct {
+		src, param, field string
+	}{
+		{"int", "c.Int", "c.Int"},
+		{"unsigned long long", "c.UlongLong", "c.UlongLong"},
+		{"const char *const", "*c.Char", "*c.Char"},
+		{"unsigned char **", "**byte", "**byte"},
+		{"void *", "c.Pointer", "c.Pointer"},
+		{"struct foo *", "*Foo", "*Foo"},
+		{"const foo_t *", "*Foo", "*Foo"},
+		{"char [16]", "*c.Char", "[16]c.Char"},
+		{"int [2][3]", "*[3]c.Int", "[2][3]c.Int"},
+		{"double []", "*c.Double", "[0]c.Double"},
+		{"int (*)(void)", "func() c.Int", "c.Pointer"},
+		{"void (*)(void *, size_t)", "func(c.Pointer, uintptr)", "c.Pointer"},
+		{"int (*)(const char *, ...)", "func(*c.Char, ...any) c.Int", "c.Pointer"},
+		{"void (**)(int)", "*c.Pointer", "*c.Pointer"},
+		{"float _Complex", "complex64", "complex64"},
+		{"_Bool", "bool", "bool"},
+	}
+	for _, tt := range tests {
+		typ, err := parseType(tt.src, lookup)
+		if err != nil {
+			t.Fatal(tt.src, err)
+		}
+		if param, err := goType(typ, true); err != nil || param != tt.param {
+			t.Fatal(tt.src, "param:", param, err)
+		}
+		if field, err := goType(typ, false); err != nil || field != tt.field {
+			t.Fatal(tt.src, "field:", field, err)
+		}
+	}
+	for _, src := range []string{"long double", "__int128", "struct bar", "bar_t", "int int", "char (^)(void)"} {
+		if _, err := parseType(src, lookup); err == nil {
+			t.Fatal(src, "no error")
+		}
+	}
+}
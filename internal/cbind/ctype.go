@@ -0,0 +1,551 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cbind
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+type cKind int
+
+const (
+	cVoid cKind = iota
+	cBasic
+	cPointer
+	cArray
+	cFunc
+)
+
+// A cType is a C type, as parsed from the qualType clang gives to a
+// declaration.
+type cType struct {
+	kind     cKind
+	name     string   // Go type of a cBasic
+	elem     *cType   // pointee, array element or function result
+	len      int64    // length of a cArray, -1 if unspecified
+	params   []*cType // parameters of a cFunc
+	variadic bool     // a cFunc has variadic parameters
+}
+
+// errUnknown is returned by a lookup function for the names it does not know.
+var errUnknown = errors.New("unknown type")
+
+// typeParser parses a qualType: a C type with an abstract declarator, such as
+// "const char *", "int (*)(void *, int)" or "struct foo [4]".
+type typeParser struct {
+	src    string
+	pos    int
+	tok    string // "" at the end
+	lookup func(name string) (*cType, error)
+}
+
+// parseType parses the qualType src. lookup returns the type named by a
+// typedef name or a tag, such as "foo_t", "struct foo" or "union (unnamed
+// union at foo.h:3:5)", or errUnknown.
+func parseType(src string, lookup func(name string) (*cType, error)) (t *cType, err error) {
+	p := &typeParser{src: src, lookup: lookup}
+	p.next()
+	if t, err = p.parse(); err != nil {
+		return
+	}
+	if p.tok != "" {
+		err = p.errorf("unexpected %s", p.tok)
+	}
+	return
+}
+
+func (p *typeParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s: %s", p.src, fmt.Sprintf(format, args...))
+}
+
+func (p *typeParser) next() {
+	src := p.src
+	for p.pos < len(src) && src[p.pos] == ' ' {
+		p.pos++
+	}
+	start := p.pos
+	switch {
+	case start == len(src):
+		p.tok = ""
+		return
+	case isIdentChar(src[start]):
+		// a name, qualified and with template arguments in C++: ns::vec<int>
+		for p.pos < len(src) {
+			switch c := src[p.pos]; {
+			case isIdentChar(c):
+				p.pos++
+			case strings.HasPrefix(src[p.pos:], "::"):
+				p.pos += 2
+			case c == '<':
+				p.pos = skipTemplateArgs(src, p.pos)
+			default:
+				p.tok = src[start:p.pos]
+				return
+			}
+		}
+	case strings.HasPrefix(src[start:], "..."):
+		p.pos += 3
+	case strings.HasPrefix(src[start:], "&&"):
+		p.pos += 2
+	default:
+		p.pos++
+	}
+	p.tok = src[start:p.pos]
+}
+
+// skipTemplateArgs returns the end of the template arguments at src[pos].
+func skipTemplateArgs(src string, pos int) int {
+	depth := 0
+	for ; pos < len(src); pos++ {
+		switch src[pos] {
+		case '<':
+			depth++
+		case '>':
+			if depth--; depth == 0 {
+				return pos + 1
+			}
+		}
+	}
+	return pos
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *typeParser) expect(tok string) error {
+	if p.tok != tok {
+		return p.errorf("expect %s, got %q", tok, p.tok)
+	}
+	p.next()
+	return nil
+}
+
+func (p *typeParser) parse() (*cType, error) {
+	t, err := p.specifiers()
+	if err != nil {
+		return nil, err
+	}
+	return p.declarator(t)
+}
+
+func isQualifier(tok string) bool {
+	switch tok {
+	case "const", "volatile", "restrict", "__restrict", "_Nullable", "_Nonnull", "_Null_unspecified":
+		return true
+	}
+	return false
+}
+
+// specifiers parses the type specifiers and qualifiers.
+func (p *typeParser) specifiers() (t *cType, err error) {
+	var words []string // of a builtin type
+	for {
+		switch tok := p.tok; tok {
+		case "void", "char", "short", "int", "long", "signed", "unsigned", "float", "double",
+			"_Bool", "bool", "_Complex", "__int128":
+			words = append(words, tok)
+		case "struct", "union", "enum", "class":
+			if t != nil || words != nil {
+				return nil, p.errorf("unexpected %s", tok)
+			}
+			tag := tok + " " + p.tagName()
+			if t, err = p.named(tag); err != nil {
+				return
+			}
+			continue
+		default:
+			if isQualifier(tok) {
+				break
+			}
+			if tok == "" || !isIdentChar(tok[0]) || t != nil || words != nil {
+				switch {
+				case t != nil && words != nil:
+					return nil, p.errorf("invalid type")
+				case t != nil:
+					return
+				case words == nil:
+					return nil, p.errorf("missing type")
+				}
+				return p.builtin(words)
+			}
+			if t, err = p.named(tok); err != nil {
+				return
+			}
+		}
+		p.next()
+	}
+}
+
+// tagName returns the name after struct, union or enum, or the description of
+// an unnamed one, such as "(unnamed struct at foo.h:3:5)".
+func (p *typeParser) tagName() string {
+	src := p.src
+	for p.pos < len(src) && src[p.pos] == ' ' {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos < len(src) && src[p.pos] == '(' {
+		if end := strings.IndexByte(src[p.pos:], ')'); end >= 0 {
+			p.pos += end + 1
+		}
+	} else {
+		for p.pos < len(src) && isIdentChar(src[p.pos]) {
+			p.pos++
+		}
+	}
+	name := src[start:p.pos]
+	p.next()
+	return name
+}
+
+func (p *typeParser) named(name string) (*cType, error) {
+	t, err := p.lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return t, nil
+}
+
+// builtin returns the Go type of the builtin C type made of words.
+func (p *typeParser) builtin(words []string) (*cType, error) {
+	var signed, unsigned, complex bool
+	var long, ints, base int
+	name := ""
+	for _, w := range words {
+		switch w {
+		case "signed":
+			signed = true
+		case "unsigned":
+			unsigned = true
+		case "_Complex":
+			complex = true
+		case "long":
+			long++
+		case "int":
+			if ints++; ints > 1 {
+				return nil, p.errorf("invalid type")
+			}
+		default:
+			if name != "" {
+				return nil, p.errorf("invalid type")
+			}
+			name = w
+		}
+		base++
+	}
+	switch name {
+	case "void":
+		if base > 1 {
+			return nil, p.errorf("invalid type")
+		}
+		return &cType{kind: cVoid}, nil
+	case "_Bool", "bool":
+		name = "bool"
+	case "float", "double":
+		switch {
+		case signed || unsigned || name == "float" && long > 0 || long > 1:
+			return nil, p.errorf("invalid type")
+		case long == 1:
+			return nil, p.errorf("long double is not supported")
+		case complex && name == "float":
+			name = "complex64"
+		case complex:
+			name = "complex128"
+		case name == "float":
+			name = "c.Float"
+		default:
+			name = "c.Double"
+		}
+	case "char":
+		switch {
+		case long > 0:
+			return nil, p.errorf("invalid type")
+		case unsigned:
+			name = "byte"
+		case signed:
+			name = "int8"
+		default:
+			name = "c.Char"
+		}
+	case "short":
+		if unsigned {
+			name = "uint16"
+		} else {
+			name = "int16"
+		}
+	case "__int128":
+		return nil, p.errorf("__int128 is not supported")
+	case "":
+		switch {
+		case long > 1 && unsigned:
+			name = "c.UlongLong"
+		case long > 1:
+			name = "c.LongLong"
+		case long == 1 && unsigned:
+			name = "c.Ulong"
+		case long == 1:
+			name = "c.Long"
+		case unsigned:
+			name = "c.Uint"
+		default:
+			name = "c.Int"
+		}
+	}
+	if complex && name != "complex64" && name != "complex128" {
+		return nil, p.errorf("invalid type")
+	}
+	return &cType{kind: cBasic, name: name}, nil
+}
+
+// declarator parses the abstract declarator of a type whose specifiers are t.
+// A C++ reference is a pointer, as in the Itanium C++ ABI.
+func (p *typeParser) declarator(t *cType) (*cType, error) {
+	for p.tok == "*" || p.tok == "&" || p.tok == "&&" {
+		t = &cType{kind: cPointer, elem: t}
+		p.next()
+		for isQualifier(p.tok) {
+			p.next()
+		}
+	}
+	if p.tok == "(" && p.nestedDeclarator() {
+		// int (*)(int): the inner declarator applies to the type of the
+		// suffixes which follow it, unknown yet
+		p.next()
+		hole := new(cType)
+		inner, err := p.declarator(hole)
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		outer, err := p.suffixes(t)
+		if err != nil {
+			return nil, err
+		}
+		*hole = *outer
+		return inner, nil
+	}
+	return p.suffixes(t)
+}
+
+// nestedDeclarator reports whether the ( at p.pos starts a declarator, not
+// the parameters of a function.
+func (p *typeParser) nestedDeclarator() bool {
+	src := p.src[p.pos:]
+	src = strings.TrimLeft(src, " ")
+	return src != "" && (src[0] == '*' || src[0] == '(' || src[0] == '[' || src[0] == '^')
+}
+
+// suffixes parses the array and function suffixes of a declarator.
+func (p *typeParser) suffixes(t *cType) (*cType, error) {
+	switch p.tok {
+	case "[":
+		p.next()
+		n := int64(-1)
+		if p.tok != "]" {
+			v, err := strconv.ParseInt(p.tok, 0, 64)
+			if err != nil {
+				return nil, p.errorf("invalid array length %s", p.tok)
+			}
+			n = v
+			p.next()
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		elem, err := p.suffixes(t)
+		if err != nil {
+			return nil, err
+		}
+		return &cType{kind: cArray, elem: elem, len: n}, nil
+	case "(":
+		p.next()
+		fn := &cType{kind: cFunc, elem: t}
+		for p.tok != ")" {
+			if p.tok == "..." {
+				fn.variadic = true
+				p.next()
+				break
+			}
+			param, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			fn.params = append(fn.params, param)
+			if p.tok != "," {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		// the qualifiers of a C++ method
+		for isQualifier(p.tok) || p.tok == "&" || p.tok == "&&" || p.tok == "noexcept" {
+			p.next()
+		}
+		if len(fn.params) == 1 && fn.params[0].kind == cVoid {
+			fn.params = nil
+		}
+		return fn, nil
+	case "^":
+		return nil, p.errorf("blocks are not supported")
+	}
+	return t, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// builtinTypes are the Go types of the typedefs of the C standard library.
+var builtinTypes = map[string]string{
+	"size_t":               "uintptr",
+	"ssize_t":              "c.SsizeT",
+	"intptr_t":             "uintptr",
+	"uintptr_t":            "uintptr",
+	"int8_t":               "int8",
+	"int16_t":              "int16",
+	"int32_t":              "int32",
+	"int64_t":              "int64",
+	"uint8_t":              "uint8",
+	"uint16_t":             "uint16",
+	"uint32_t":             "uint32",
+	"uint64_t":             "uint64",
+	"FILE":                 "c.FILE",
+	"va_list":              "c.VaList",
+	"__builtin_va_list":    "c.VaList",
+	"wchar_t":              "int32",
+	"char16_t":             "uint16",
+	"char32_t":             "uint32",
+	"__gnuc_va_list":       "c.VaList",
+	"struct _IO_FILE":      "c.FILE",
+	"struct __sFILE":       "c.FILE",
+	"struct __va_list_tag": "c.VaList",
+}
+
+// goType returns the Go type of t. In a parameter, a function pointer is a Go
+// func, otherwise it is a c.Pointer as a func would be a closure.
+func goType(t *cType, param bool) (string, error) {
+	switch t.kind {
+	case cBasic:
+		return t.name, nil
+	case cPointer:
+		switch elem := t.elem; elem.kind {
+		case cVoid:
+			return "c.Pointer", nil
+		case cFunc:
+			if param {
+				return goFunc(elem)
+			}
+			return "c.Pointer", nil
+		default:
+			s, err := goType(elem, false)
+			return "*" + s, err
+		}
+	case cArray:
+		if param {
+			return goType(&cType{kind: cPointer, elem: t.elem}, false)
+		}
+		elem, err := goType(t.elem, false)
+		n := t.len
+		if n < 0 {
+			n = 0 // flexible array member
+		}
+		return fmt.Sprintf("[%d]%s", n, elem), err
+	case cFunc:
+		return goFunc(t)
+	}
+	return "", errors.New("void is not a value type")
+}
+
+// goFunc returns the Go func type of the C function type fn.
+func goFunc(fn *cType) (string, error) {
+	params := make([]string, len(fn.params))
+	for i, param := range fn.params {
+		s, err := goType(param, true)
+		if err != nil {
+			return "", err
+		}
+		params[i] = s
+	}
+	if fn.variadic {
+		params = append(params, "...any")
+	}
+	ret, err := goResult(fn.elem)
+	if err != nil {
+		return "", err
+	}
+	return "func(" + strings.Join(params, ", ") + ")" + ret, nil
+}
+
+// goResult returns the result of a Go func type returning t.
+func goResult(t *cType) (string, error) {
+	if t.kind == cVoid {
+		return "", nil
+	}
+	s, err := goType(t, false)
+	return " " + s, err
+}
+
+// -----------------------------------------------------------------------------
+
+// A layout is the size and the alignment of a type, on 64-bit targets.
+type layout struct {
+	size, align int64
+}
+
+var basicLayouts = map[string]layout{
+	"bool": {1, 1}, "byte": {1, 1}, "int8": {1, 1}, "uint8": {1, 1}, "c.Char": {1, 1},
+	"int16": {2, 2}, "uint16": {2, 2},
+	"int32": {4, 4}, "uint32": {4, 4}, "c.Int": {4, 4}, "c.Uint": {4, 4}, "c.Float": {4, 4},
+	"int64": {8, 8}, "uint64": {8, 8}, "c.Long": {8, 8}, "c.Ulong": {8, 8},
+	"c.LongLong": {8, 8}, "c.UlongLong": {8, 8}, "c.Double": {8, 8}, "c.SsizeT": {8, 8},
+	"uintptr": {8, 8}, "c.Pointer": {8, 8}, "c.VaList": {8, 8},
+	"complex64": {8, 4}, "complex128": {16, 8},
+}
+
+// layoutOf returns the layout of t, or false if it is unknown. named are the
+// layouts of the types declared by the package.
+func layoutOf(t *cType, named map[string]layout) (layout, bool) {
+	switch t.kind {
+	case cBasic:
+		l, ok := basicLayouts[t.name]
+		if !ok {
+			l, ok = named[t.name]
+		}
+		return l, ok
+	case cPointer:
+		return layout{8, 8}, true
+	case cArray:
+		l, ok := layoutOf(t.elem, named)
+		if t.len > 0 {
+			l.size *= t.len
+		} else {
+			l.size = 0
+		}
+		return l, ok
+	}
+	return layout{}, false
+}
+
+// -----------------------------------------------------------------------------
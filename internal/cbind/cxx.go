@@ -0,0 +1,486 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cbind
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/goplus/llgo/xtool/clang/ast"
+)
+
+// -----------------------------------------------------------------------------
+
+// A class is a C++ class bound as a Go struct type. Its methods follow the
+// Itanium C++ ABI: this is their first parameter, and a polymorphic class
+// starts with a pointer to its virtual table, shared with its primary base.
+type class struct {
+	name    string               // Go type
+	decl    *ast.Node            // CXXRecordDecl
+	bases   []*class             // non-virtual base classes, the primary one first
+	primary *class               // first polymorphic base class
+	poly    bool                 // has a virtual table
+	multi   bool                 // has polymorphic base classes besides the primary one
+	pod     bool                 // is a POD, whose tail padding is not reused
+	dsize   int64                // size without the tail padding
+	slots   []*slot              // virtual table, after its offset to top and type info
+	methods map[*ast.Node]string // Go names of the methods
+}
+
+// A slot is an entry of a virtual table.
+type slot struct {
+	name  string    // Go name of the method, and field of the virtual table
+	key   string    // name and parameters, matched by the overriders
+	decl  *ast.Node // CXXMethodDecl or CXXDestructorDecl
+	owner *class    // class introducing the slot
+}
+
+// newClass returns the C++ class of decl, whose Go type is name.
+func (g *generator) newClass(decl *ast.Node, name string) (*class, error) {
+	cls := &class{name: name, decl: decl, poly: decl.DefinitionData != nil && decl.DefinitionData.IsPolymorphic}
+	for _, base := range decl.Bases {
+		if base.IsVirtual {
+			return nil, errors.New("virtual base classes are not supported")
+		}
+		t, err := g.parseType(base.Type)
+		if err != nil {
+			return nil, err
+		}
+		bc := g.classes[t.name]
+		if t.kind != cBasic || bc == nil {
+			return nil, fmt.Errorf("%s: base class not bound", base.Type.QualType)
+		}
+		switch {
+		case !bc.poly:
+			cls.bases = append(cls.bases, bc)
+		case cls.primary == nil:
+			cls.primary = bc
+			cls.bases = append([]*class{bc}, cls.bases...)
+		default:
+			cls.multi = true
+			cls.bases = append(cls.bases, bc)
+		}
+	}
+	cls.methods = g.methodNames(decl)
+	if cls.poly {
+		g.virtuals(cls)
+	}
+	// a POD for the purpose of layout, as in C++03
+	cls.pod = !cls.poly && len(cls.bases) == 0
+	access := defaultAccess(decl)
+	for _, node := range decl.Inner {
+		switch {
+		case node.Kind == ast.AccessSpecDecl:
+			access = node.Access
+		case node.Kind == ast.FieldDecl && fieldName(node, access) != genName(node.Name, ""),
+			node.Kind == ast.CXXConstructorDecl && !node.IsImplicit,
+			node.Kind == ast.CXXDestructorDecl && !node.IsImplicit:
+			cls.pod = false
+		}
+	}
+	return cls, nil
+}
+
+// methodNames returns the Go names of the methods of the class decl. The
+// constructors are Init and the destructor Dispose. Overloaded methods are
+// suffixed by __0, __1, ... as the overloads of Go+.
+func (g *generator) methodNames(decl *ast.Node) map[*ast.Node]string {
+	names := make(map[*ast.Node]string)
+	var order []string
+	overloads := make(map[string][]*ast.Node)
+	for _, node := range decl.Inner {
+		var name string
+		switch node.Kind {
+		case ast.CXXConstructorDecl:
+			name = "Init"
+		case ast.CXXDestructorDecl:
+			name = "Dispose"
+		case ast.CXXMethodDecl:
+			if strings.HasPrefix(node.Name, "operator") {
+				continue
+			}
+			name = genName(node.Name, "")
+		default:
+			continue
+		}
+		if node.IsImplicit || node.ExplicitlyDeleted || name == "" {
+			continue
+		}
+		if overloads[name] == nil {
+			order = append(order, name)
+		}
+		overloads[name] = append(overloads[name], node)
+	}
+	for _, name := range order {
+		nodes := overloads[name]
+		for i, node := range nodes {
+			if len(nodes) > 1 {
+				names[node] = fmt.Sprintf("%s__%d", name, i)
+			} else {
+				names[node] = name
+			}
+		}
+	}
+	return names
+}
+
+// virtuals lays out the virtual table of cls: the one of its primary base,
+// then the virtual methods of cls not overriding one of it. A virtual
+// destructor takes two slots, the complete and the deleting destructors.
+func (g *generator) virtuals(cls *class) {
+	if cls.primary != nil {
+		cls.slots = append(cls.slots, cls.primary.slots...)
+	}
+	for _, node := range cls.decl.Inner {
+		if !node.Virtual || node.IsImplicit {
+			continue
+		}
+		var key string
+		switch node.Kind {
+		case ast.CXXDestructorDecl:
+			key = "~"
+		case ast.CXXMethodDecl:
+			key = node.Name + " " + node.Type.QualType
+			if t, err := g.parseType(node.Type); err == nil && t.kind == cFunc {
+				key = node.Name + paramsKey(t)
+			}
+		default:
+			continue
+		}
+		overrides := false
+		for _, s := range cls.slots {
+			if s.key == key {
+				overrides = true
+			}
+		}
+		if overrides {
+			continue
+		}
+		if key == "~" {
+			cls.slots = append(cls.slots,
+				&slot{name: "Dtor", key: key, decl: node, owner: cls},
+				&slot{name: "DeletingDtor", key: key, decl: node, owner: cls})
+			continue
+		}
+		cls.slots = append(cls.slots, &slot{name: cls.methods[node], key: key, decl: node, owner: cls})
+	}
+}
+
+// paramsKey returns the Go types of the parameters of fn, as an overrider
+// has the same ones.
+func paramsKey(fn *cType) string {
+	list := make([]string, len(fn.params))
+	for i, t := range fn.params {
+		list[i], _ = goType(t, false)
+	}
+	return "(" + strings.Join(list, ", ") + ")"
+}
+
+// -----------------------------------------------------------------------------
+
+// genClass generates the virtual table, the methods and the Go subclass of
+// the C++ class cls, whose Go type is generated:
+//
+//	// FooVtbl is the virtual table of Foo.
+//	type FooVtbl struct {
+//		Val c.Pointer
+//	}
+//
+//	// llgo:link (*Foo).Init C._ZN3FooC1Ev
+//	func (p *Foo) Init() {}
+//
+//	// llgo:link (*Foo).Get C._ZNK3Foo3getEv
+//	func (p *Foo) Get() (_ c.Int) { return }
+//
+//	func (p *Foo) Val() c.Int { ... }
+func (g *generator) genClass(cls *class) {
+	members := make(map[string]bool) // fields and methods of the Go type
+	for _, base := range cls.bases {
+		members[base.name] = true
+	}
+	if cls.poly {
+		members["Vptr"] = true
+		g.genVtbl(cls)
+	}
+	access := defaultAccess(cls.decl)
+	for _, node := range cls.decl.Inner {
+		if node.Kind == ast.AccessSpecDecl {
+			access = node.Access
+		} else if node.Kind == ast.FieldDecl {
+			members[fieldName(node, access)] = true
+		}
+	}
+	abstract := cls.decl.DefinitionData != nil && cls.decl.DefinitionData.IsAbstract
+	access = defaultAccess(cls.decl)
+	for _, node := range cls.decl.Inner {
+		if node.Kind == ast.AccessSpecDecl {
+			access = node.Access
+		}
+		name, ok := cls.methods[node]
+		if !ok || node.Access != "" && node.Access != "public" || node.Access == "" && access != "public" {
+			continue
+		}
+		if members[name] {
+			log.Println("skip", cls.decl.Name+"::"+node.Name+":", name, "redeclared")
+			continue
+		}
+		switch {
+		case node.Kind == ast.CXXConstructorDecl && abstract:
+			continue
+		case node.StorageClass == ast.Static:
+			g.genStatic(cls, node, name)
+			continue
+		case node.Virtual && node.Kind == ast.CXXMethodDecl:
+			if g.genVirtual(cls, node, name) {
+				members[name] = true
+			}
+			continue
+		case isInline(node):
+			continue
+		}
+		if g.genMethod(cls, node, name) {
+			members[name] = true
+		}
+	}
+	if cls.poly {
+		g.genSubclass(cls)
+	}
+}
+
+// genVtbl generates the virtual table of cls, embedding the one of its
+// primary base.
+func (g *generator) genVtbl(cls *class) {
+	name := cls.name + "Vtbl"
+	if !g.declare(name, cls.decl.Name+" vtable") {
+		return
+	}
+	b := &g.b
+	fmt.Fprintf(b, "\n// %s is the virtual table of %s.\ntype %s struct {\n", name, cls.name, name)
+	if cls.primary != nil {
+		fmt.Fprintf(b, "\t%sVtbl\n", cls.primary.name)
+	}
+	for _, s := range cls.slots {
+		if s.owner == cls {
+			fmt.Fprintf(b, "\t%s c.Pointer\n", s.name)
+		}
+	}
+	fmt.Fprintln(b, "}")
+}
+
+// methodSig returns the parameters and the result of the method decl.
+func (g *generator) methodSig(decl *ast.Node, funcs bool) (params []param, ret string, err error) {
+	fn, err := g.parseType(decl.Type)
+	if err == nil && fn.kind != cFunc {
+		err = errors.New("not a function")
+	}
+	if err == nil {
+		params, err = g.params(decl, fn, funcs, "p", "vtbl", "this")
+	}
+	if err == nil {
+		ret, err = goResult(fn.elem)
+	}
+	return
+}
+
+// genMethod generates the non-virtual method decl, a constructor or a
+// destructor, linked to its symbol.
+func (g *generator) genMethod(cls *class, decl *ast.Node, name string) bool {
+	params, ret, err := g.methodSig(decl, true)
+	if err != nil {
+		log.Println("skip", cls.decl.Name+"::"+decl.Name+":", err)
+		return false
+	}
+	sym := symbol(decl)
+	if sym == decl.Name {
+		log.Println("skip", cls.decl.Name+"::"+decl.Name+": no mangled name")
+		return false
+	}
+	b := &g.b
+	fmt.Fprintln(b)
+	g.genDoc(decl)
+	fmt.Fprintf(b, "// llgo:link (*%s).%s C.%s\nfunc (p *%s) %s(%s)", cls.name, name, sym, cls.name, name, joinParams(params))
+	if ret != "" {
+		fmt.Fprintf(b, " (_%s) {\n\treturn\n}\n", ret)
+	} else {
+		fmt.Fprintln(b, " {}")
+	}
+	return true
+}
+
+// genStatic generates the static method decl as the function FooName.
+func (g *generator) genStatic(cls *class, decl *ast.Node, name string) {
+	if isInline(decl) {
+		return
+	}
+	params, ret, err := g.methodSig(decl, true)
+	if err != nil {
+		log.Println("skip", cls.decl.Name+"::"+decl.Name+":", err)
+		return
+	}
+	sym := symbol(decl)
+	name = cls.name + name
+	if !g.declare(name, sym) {
+		return
+	}
+	b := &g.b
+	fmt.Fprintln(b)
+	g.genDoc(decl)
+	fmt.Fprintf(b, "//go:linkname %s C.%s\nfunc %s(%s)%s\n", name, sym, name, joinParams(params), ret)
+}
+
+// genVirtual generates the method calling the virtual method decl through the
+// virtual table, if cls introduces its slot. The overriders are the methods
+// of the base classes, promoted.
+func (g *generator) genVirtual(cls *class, decl *ast.Node, name string) bool {
+	var s *slot
+	for _, v := range cls.slots {
+		if v.decl == decl && v.owner == cls {
+			s = v
+			break
+		}
+	}
+	if s == nil || decl.Kind == ast.CXXDestructorDecl {
+		return false
+	}
+	params, ret, err := g.methodSig(decl, false)
+	if err != nil {
+		log.Println("skip", cls.decl.Name+"::"+decl.Name+":", err)
+		return false
+	}
+	fnType := strings.ToLower(cls.name[:1]) + cls.name[1:] + s.name + "Fn"
+	if !g.declare(fnType, cls.decl.Name+"::"+decl.Name+" type") {
+		return false
+	}
+	args := []string{"p"}
+	for _, p := range params {
+		if p.typ == "...any" {
+			log.Println("skip", cls.decl.Name+"::"+decl.Name+": variadic virtual method")
+			return false
+		}
+		args = append(args, p.name)
+	}
+	thisParams := append([]param{{"this", "*" + cls.name}}, params...)
+	b := &g.b
+	fmt.Fprintf(b, "\n// llgo:type C\ntype %s func(%s)%s\n\n", fnType, joinParams(thisParams), ret)
+	g.genDoc(decl)
+	fmt.Fprintf(b, "func (p *%s) %s(%s)%s {\n", cls.name, name, joinParams(params), ret)
+	if cls.primary != nil {
+		fmt.Fprintf(b, "\tvtbl := (*%sVtbl)(c.Pointer(p.Vptr))\n\t", cls.name)
+	} else {
+		b.WriteString("\tvtbl := p.Vptr\n\t")
+	}
+	if ret != "" {
+		b.WriteString("return ")
+	}
+	fmt.Fprintf(b, "(*(*%s)(c.Pointer(&vtbl.%s)))(%s)\n}\n", fnType, s.name, strings.Join(args, ", "))
+	return true
+}
+
+// genSubclass generates FooSubclass, a C++ subclass of the polymorphic class
+// Foo whose virtual methods are the ones of a Go type embedding it:
+//
+//	type FooMethods interface {
+//		Val() c.Int
+//	}
+//
+//	type FooSubclass struct {
+//		Foo
+//		impl FooMethods
+//	}
+//
+//	func (p *FooSubclass) InitSubclass(impl FooMethods)
+//
+// Its virtual table, shared by its instances, calls the methods of impl.
+func (g *generator) genSubclass(cls *class) {
+	if cls.multi {
+		log.Println("skip", cls.name+"Subclass: multiple polymorphic base classes")
+		return
+	}
+	type method struct {
+		s         *slot
+		params    []param
+		ret, impl string
+	}
+	var methods []method
+	seen := make(map[string]bool)
+	for _, s := range cls.slots {
+		if s.key == "~" {
+			continue
+		}
+		params, ret, err := g.methodSig(s.decl, false)
+		if err != nil || seen[s.name] {
+			log.Println("skip", cls.name+"Subclass:", s.decl.Name, "not supported")
+			return
+		}
+		seen[s.name] = true
+		methods = append(methods, method{s: s, params: params, ret: ret})
+	}
+	lower := strings.ToLower(cls.name[:1]) + cls.name[1:]
+	iface, sub, vtbl := cls.name+"Methods", cls.name+"Subclass", lower+"SubclassVtbl"
+	for _, name := range []string{iface, sub, vtbl, lower + "SubclassDtor"} {
+		if !g.declare(name, cls.decl.Name+" subclass") {
+			return
+		}
+	}
+	for i, m := range methods {
+		methods[i].impl = lower + "Subclass" + m.s.name
+		if !g.declare(methods[i].impl, cls.decl.Name+" subclass") {
+			return
+		}
+	}
+
+	b := &g.b
+	fmt.Fprintf(b, "\n// %s are the virtual methods of %s, implemented by a Go type\n// embedding %s.\ntype %s interface {\n", iface, cls.name, sub, iface)
+	for _, m := range methods {
+		fmt.Fprintf(b, "\t%s(%s)%s\n", m.s.name, joinParams(m.params), m.ret)
+	}
+	fmt.Fprintf(b, "}\n\n// %s is a C++ subclass of %s whose virtual methods call the ones of\n// the Go type which embeds it, once InitSubclass is called.\n", sub, cls.name)
+	fmt.Fprintf(b, "type %s struct {\n\t%s\n\timpl %s\n}\n\n", sub, cls.name, iface)
+	vptrType := cls.name + "Vtbl"
+	for base := cls.primary; base != nil; base = base.primary {
+		vptrType = base.name + "Vtbl"
+	}
+	fmt.Fprintf(b, "// InitSubclass makes the virtual methods of p call the ones of impl, which\n// embeds p. It is called after the constructor of %s, if any.\n", cls.name)
+	fmt.Fprintf(b, "func (p *%s) InitSubclass(impl %s) {\n\tp.Vptr = (*%s)(c.Pointer(&%s.vtbl))\n\tp.impl = impl\n}\n\n", sub, iface, vptrType, vtbl)
+	fmt.Fprintf(b, "var %s struct {\n\toffsetToTop uintptr\n\ttypeInfo    c.Pointer\n\tvtbl        %sVtbl\n}\n\n", vtbl, cls.name)
+	fmt.Fprintf(b, "func init() {\n\tvtbl := &%s.vtbl\n", vtbl)
+	for _, s := range cls.slots {
+		impl := lower + "SubclassDtor"
+		for _, m := range methods {
+			if m.s == s {
+				impl = m.impl
+			}
+		}
+		fmt.Fprintf(b, "\tvtbl.%s = c.Func(%s)\n", s.name, impl)
+	}
+	fmt.Fprintf(b, "}\n\nfunc %sSubclassDtor(this *%s) {}\n", lower, sub)
+	for _, m := range methods {
+		args := make([]string, len(m.params))
+		for i, p := range m.params {
+			args[i] = p.name
+		}
+		fmt.Fprintf(b, "\nfunc %s(%s)%s {\n\t", m.impl, joinParams(append([]param{{"this", "*" + sub}}, m.params...)), m.ret)
+		if m.ret != "" {
+			b.WriteString("return ")
+		}
+		fmt.Fprintf(b, "this.impl.%s(%s)\n}\n", m.s.name, strings.Join(args, ", "))
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cbind
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// A macro is an object-like macro defined by a header to bind.
+type macro struct {
+	name, value string
+}
+
+var (
+	intLit   = regexp.MustCompile(`^[-+]?(0[xX][0-9a-fA-F]+|[0-9]+)([uU]?[lL]{0,2}|[lL]{1,2}[uU])$`)
+	floatLit = regexp.MustCompile(`^[-+]?([0-9]+\.[0-9]*|\.[0-9]+|[0-9]+)([eE][-+]?[0-9]+)?[fFlL]?$`)
+)
+
+// genMacros returns the constants of the object-like macros the headers to
+// bind define, in src, the output of DumpMacros. Only the macros defining a
+// literal, or another constant, are constants.
+func (g *generator) genMacros(src []byte) []byte {
+	var macros []*macro
+	defined := make(map[string]int) // name => index in macros
+	var cur string
+	s := bufio.NewScanner(bytes.NewReader(src))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case strings.HasPrefix(line, "# "): // # 1 "foo.h" 2
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) >= 3 {
+				if file, err := strconv.Unquote(fields[2]); err == nil {
+					cur = file
+				}
+			}
+		case strings.HasPrefix(line, "#undef "):
+			name := strings.TrimSpace(line[len("#undef "):])
+			if i, ok := defined[name]; ok {
+				macros[i] = nil
+				delete(defined, name)
+			}
+		case strings.HasPrefix(line, "#define ") && g.headers[cur]:
+			def := line[len("#define "):]
+			n := 0
+			for n < len(def) && isIdentChar(def[n]) {
+				n++
+			}
+			name, value := def[:n], strings.TrimSpace(def[n:])
+			if n == len(def) || def[n] != ' ' || value == "" || strings.HasPrefix(name, "__") {
+				continue // a function-like or an empty macro
+			}
+			if i, ok := defined[name]; ok {
+				macros[i] = nil
+			}
+			defined[name] = len(macros)
+			macros = append(macros, &macro{name, value})
+		}
+	}
+
+	var b bytes.Buffer
+	for _, m := range macros {
+		if m == nil {
+			continue
+		}
+		value, ok := g.constValue(m.value)
+		if !ok {
+			continue
+		}
+		name := g.constName(m.name)
+		if !g.declare(name, m.name) {
+			continue
+		}
+		g.consts[m.name] = name
+		fmt.Fprintf(&b, "\t%s = %s\n", name, value)
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	return []byte("\nconst (\n" + b.String() + ")\n")
+}
+
+// constValue returns the Go constant expression of the value of a macro.
+func (g *generator) constValue(v string) (string, bool) {
+	for len(v) > 2 && v[0] == '(' && v[len(v)-1] == ')' {
+		v = strings.TrimSpace(v[1 : len(v)-1])
+	}
+	switch {
+	case intLit.MatchString(v):
+		v = strings.TrimRight(v, "uUlL")
+		if len(v) > 1 && v[0] == '0' && v[1] >= '0' && v[1] <= '9' {
+			v = "0o" + v[1:]
+		} else if len(v) > 2 && (v[0] == '-' || v[0] == '+') && v[1] == '0' && v[2] >= '0' && v[2] <= '9' {
+			v = v[:1] + "0o" + v[2:]
+		}
+		return v, true
+	case floatLit.MatchString(v):
+		v = strings.TrimRight(v, "fFlL")
+		if strings.HasSuffix(v, ".") {
+			v += "0"
+		}
+		return v, true
+	case strings.HasPrefix(v, `"`):
+		if _, err := strconv.Unquote(v); err == nil {
+			return v, true
+		}
+	case strings.HasPrefix(v, "'"):
+		if _, _, tail, err := strconv.UnquoteChar(v[1:], '\''); err == nil && tail == "'" {
+			return v, true
+		}
+	default:
+		if name, ok := g.consts[v]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// -----------------------------------------------------------------------------
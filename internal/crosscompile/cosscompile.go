@@ -15,6 +15,7 @@ type Export struct {
 	CCFLAGS []string
 	CFLAGS  []string
 	LDFLAGS []string
+	Sysroot string // root of the target's libraries, to run programs with an emulator
 }
 
 const wasiSdkUrl = "https://github.com/WebAssembly/wasi-sdk/releases/download/wasi-sdk-25/wasi-sdk-25.0-x86_64-macos.tar.gz"
@@ -59,8 +60,52 @@ func UseCrossCompileSDK(goos, goarch string, wasiThreads bool) (export Export, e
 		export.LDFLAGS = []string{
 			"-L" + libDir,
 		}
+		export.Sysroot = sysrootDir
 		return
 	}
+	if goos == "linux" && runtime.GOOS == "linux" {
+		export = linuxCross("/usr", goarch)
+	}
 	// TODO(lijie): supports other platforms
 	return
 }
+
+// gnuTriples are the triples of the GNU cross toolchains for Linux.
+var gnuTriples = map[string]string{
+	"386":      "i686-linux-gnu",
+	"amd64":    "x86_64-linux-gnu",
+	"arm":      "arm-linux-gnueabihf",
+	"arm64":    "aarch64-linux-gnu",
+	"loong64":  "loongarch64-linux-gnu",
+	"ppc64le":  "powerpc64le-linux-gnu",
+	"riscv64":  "riscv64-linux-gnu",
+	"s390x":    "s390x-linux-gnu",
+	"mips64le": "mips64el-linux-gnuabi64",
+}
+
+// linuxCross returns the flags to build for Linux on goarch with a GNU
+// cross toolchain installed in root (eg. gcc-aarch64-linux-gnu in /usr on
+// Debian and Ubuntu), which has the libraries of the target in
+// root/$TRIPLE. It returns no flags if there is none.
+func linuxCross(root, goarch string) (export Export) {
+	triple, ok := gnuTriples[goarch]
+	if !ok {
+		return
+	}
+	sysroot := filepath.Join(root, triple)
+	libDir := filepath.Join(sysroot, "lib")
+	if _, err := os.Stat(libDir); err != nil {
+		return
+	}
+	// The GCC of the toolchain, with the crt files, is not in the sysroot
+	// but in root/lib/gcc-cross.
+	export.CCFLAGS = []string{
+		"--sysroot=" + sysroot,
+		"--gcc-toolchain=" + root,
+	}
+	export.LDFLAGS = []string{
+		"-L" + libDir,
+	}
+	export.Sysroot = sysroot
+	return
+}
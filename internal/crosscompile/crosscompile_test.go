@@ -5,6 +5,8 @@ package crosscompile
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 )
@@ -154,3 +156,25 @@ func TestUseCrossCompileSDK(t *testing.T) {
 		})
 	}
 }
+
+func TestLinuxCross(t *testing.T) {
+	root := t.TempDir()
+	if export := linuxCross(root, "arm64"); !reflect.DeepEqual(export, Export{}) {
+		t.Fatalf("linuxCross without a toolchain = %+v", export)
+	}
+	sysroot := filepath.Join(root, "aarch64-linux-gnu")
+	if err := os.MkdirAll(filepath.Join(sysroot, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	want := Export{
+		CCFLAGS: []string{"--sysroot=" + sysroot, "--gcc-toolchain=" + root},
+		LDFLAGS: []string{"-L" + filepath.Join(sysroot, "lib")},
+		Sysroot: sysroot,
+	}
+	if export := linuxCross(root, "arm64"); !reflect.DeepEqual(export, want) {
+		t.Errorf("linuxCross = %+v, want %+v", export, want)
+	}
+	if export := linuxCross(root, "riscv64"); !reflect.DeepEqual(export, Export{}) {
+		t.Errorf("linuxCross of another toolchain = %+v", export)
+	}
+}
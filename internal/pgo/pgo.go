@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package pgo loads the profiles used by profile-guided optimization.
+package pgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kind is the format of a profile.
+type Kind int
+
+const (
+	// Pprof is a Go pprof CPU profile, such as a default.pgo file.
+	Pprof Kind = iota
+	// Instr is an indexed LLVM instrumentation profile, produced by
+	// `llvm-profdata merge` from the raw profiles of a program built with
+	// -pgo-instrument.
+	Instr
+)
+
+const (
+	// hotCDF is the fraction of the samples covered by hot functions.
+	hotCDF = 0.99
+	// coldRatio is the maximal fraction of the samples in which a cold
+	// function is on the stack.
+	coldRatio = 0.001
+)
+
+var (
+	llvmIndexedMagic = []byte("\xfflprofi\x81")
+	llvmRawMagic     = []byte("\xfflprofr\x81")
+)
+
+// Profile is a profile loaded from a file.
+type Profile struct {
+	File string
+	Kind Kind
+
+	hot  map[string]bool // functions covering hotCDF of the samples
+	cold map[string]bool // sampled functions on the stack of few samples
+
+	mainPkgs []string // import paths of the packages gc names "main"
+}
+
+// Load loads a Go pprof profile or an indexed LLVM instrumentation profile.
+// mainPkgs are the import paths of the main packages being built: gc names
+// their functions "main.F" where llgo uses the import path.
+func Load(file string, mainPkgs []string) (*Profile, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(data, llvmIndexedMagic):
+		return &Profile{File: file, Kind: Instr}, nil
+	case bytes.HasPrefix(data, llvmRawMagic):
+		return nil, fmt.Errorf("%s: raw LLVM profile, run `llvm-profdata merge -o default.profdata %s` first", file, file)
+	}
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	samples, err := parsePprof(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	ret := &Profile{File: file, Kind: Pprof, mainPkgs: mainPkgs}
+	ret.init(samples)
+	return ret, nil
+}
+
+// init classifies the functions of samples. A sample lists the function
+// names of its stack, innermost first.
+func (p *Profile) init(samples []sample) {
+	flat := make(map[string]int64)
+	cum := make(map[string]int64)
+	p.hot = make(map[string]bool)
+	p.cold = make(map[string]bool)
+	var total int64
+	for _, s := range samples {
+		if s.value <= 0 || len(s.funcs) == 0 {
+			continue
+		}
+		fn := funcKey(s.funcs[0], true)
+		flat[fn] += s.value
+		total += s.value
+		onStack := make(map[string]bool, len(s.funcs))
+		for _, name := range s.funcs {
+			fn := funcKey(name, true)
+			if !onStack[fn] { // count recursive functions once
+				onStack[fn] = true
+				cum[fn] += s.value
+			}
+		}
+	}
+	fns := make([]string, 0, len(flat))
+	for fn := range flat {
+		fns = append(fns, fn)
+	}
+	sort.Slice(fns, func(i, j int) bool {
+		if flat[fns[i]] != flat[fns[j]] {
+			return flat[fns[i]] > flat[fns[j]]
+		}
+		return fns[i] < fns[j]
+	})
+	var sum int64
+	for _, fn := range fns {
+		if float64(sum) >= hotCDF*float64(total) {
+			break
+		}
+		p.hot[fn] = true
+		sum += flat[fn]
+	}
+	for fn, n := range cum {
+		if !p.hot[fn] && float64(n) <= coldRatio*float64(total) {
+			p.cold[fn] = true
+		}
+	}
+}
+
+// Func reports whether the function with the symbol name produced by llgo
+// is hot or cold according to a pprof profile. Hot functions are those
+// accounting for most of the samples. Cold functions are those sampled on
+// the stack of a tiny fraction of the samples: functions missing from the
+// profile are neither, as the profile may just not cover them.
+func (p *Profile) Func(name string) (hot, cold bool) {
+	fn := funcKey(p.gcName(name), false)
+	return p.hot[fn], p.cold[fn]
+}
+
+// gcName renames a function of a main package "main.F" as gc does.
+func (p *Profile) gcName(name string) string {
+	for _, pkg := range p.mainPkgs {
+		if strings.HasPrefix(name, pkg+".") {
+			return "main" + name[len(pkg):]
+		}
+	}
+	return name
+}
+
+var (
+	typeArgs = regexp.MustCompile(`\[[^\[\]]*\]`)
+	closure  = regexp.MustCompile(`\.func(\d+)((?:\.\d+)*)$`)
+)
+
+// funcKey maps a function name to the key it is matched by. gc names
+// closures "f.func1.2" where llgo names them "f$1$2", and elides type
+// arguments as "F[...]".
+func funcKey(name string, gc bool) string {
+	for {
+		s := typeArgs.ReplaceAllString(name, "[]")
+		if s == name {
+			break
+		}
+		name = s
+	}
+	if gc {
+		if m := closure.FindStringSubmatchIndex(name); m != nil {
+			suffix := "$" + name[m[2]:m[3]] + strings.ReplaceAll(name[m[4]:m[5]], ".", "$")
+			name = name[:m[0]] + suffix
+		}
+	}
+	return name
+}
@@ -0,0 +1,153 @@
+//go:build !llgo
+// +build !llgo
+
+package pgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pb encodes protocol buffer messages.
+type pb []byte
+
+func (b pb) varint(field int, v uint64) pb {
+	b = binary.AppendUvarint(b, uint64(field)<<3)
+	return binary.AppendUvarint(b, v)
+}
+
+func (b pb) bytes(field int, data []byte) pb {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func (b pb) packed(field int, vs ...uint64) pb {
+	var data []byte
+	for _, v := range vs {
+		data = binary.AppendUvarint(data, v)
+	}
+	return b.bytes(field, data)
+}
+
+// testProfile returns a gzipped CPU profile of the given stacks, innermost
+// function first, each sampled the given number of times.
+func testProfile(stacks [][]string, counts []uint64) []byte {
+	var p pb
+	strs := []string{""}
+	ids := map[string]uint64{}
+	for i, stack := range stacks {
+		var locs []uint64
+		for _, fn := range stack {
+			id, ok := ids[fn]
+			if !ok {
+				strs = append(strs, fn)
+				id = uint64(len(ids) + 1)
+				ids[fn] = id
+				p = p.bytes(profileFunction, pb{}.varint(functionID, id).varint(functionName, uint64(len(strs)-1)))
+				p = p.bytes(profileLocation, pb{}.varint(locationID, id).bytes(locationLine, pb{}.varint(lineFunctionID, id)))
+			}
+			locs = append(locs, id)
+		}
+		p = p.bytes(profileSample, pb{}.packed(sampleLocationID, locs...).packed(sampleValue, counts[i], counts[i]*10000000))
+	}
+	for _, s := range strs {
+		p = p.bytes(profileStringTable, []byte(s))
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(p)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestLoadPprof(t *testing.T) {
+	data := testProfile([][]string{
+		{"example.com/app.(*T).loop", "example.com/app.main.func1", "main.main"},
+		{"example.com/app.Sum[...]", "main.main"},
+		{"example.com/app.init.func2.1", "main.main"},
+	}, []uint64{900, 99, 1})
+	file := filepath.Join(t.TempDir(), "default.pgo")
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Kind != Pprof {
+		t.Fatalf("Kind = %v, want Pprof", p.Kind)
+	}
+	tests := []struct {
+		name      string
+		hot, cold bool
+	}{
+		{"example.com/app.(*T).loop", true, false},
+		{"example.com/app.Sum[int]", true, false},
+		{"example.com/app.main$1", false, false},
+		{"example.com/app.init$2$1", false, true},
+		{"example.com/app.unused", false, false},
+		{"example.com/other.unused", false, false},
+	}
+	for _, tt := range tests {
+		if hot, cold := p.Func(tt.name); hot != tt.hot || cold != tt.cold {
+			t.Errorf("Func(%q) = %v, %v, want %v, %v", tt.name, hot, cold, tt.hot, tt.cold)
+		}
+	}
+}
+
+func TestLoadPprofMain(t *testing.T) {
+	// gc names the functions of the main package "main.F", llgo
+	// "example.com/cmd/app.F".
+	data := testProfile([][]string{
+		{"main.work", "main.main"},
+		{"main.(*T).run.func1", "main.main"},
+		{"example.com/cmd/app/lib.Helper", "main.main"},
+	}, []uint64{9000, 999, 1})
+	file := filepath.Join(t.TempDir(), "default.pgo")
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(file, []string{"example.com/cmd/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		hot, cold bool
+	}{
+		{"example.com/cmd/app.work", true, false},
+		{"example.com/cmd/app.(*T).run$1", true, false},
+		{"example.com/cmd/app.main", false, false},
+		{"example.com/cmd/app/lib.Helper", false, true},
+		{"example.com/cmd/app.unused", false, false},
+	}
+	for _, tt := range tests {
+		if hot, cold := p.Func(tt.name); hot != tt.hot || cold != tt.cold {
+			t.Errorf("Func(%q) = %v, %v, want %v, %v", tt.name, hot, cold, tt.hot, tt.cold)
+		}
+	}
+}
+
+func TestLoadLLVM(t *testing.T) {
+	dir := t.TempDir()
+	indexed := filepath.Join(dir, "default.profdata")
+	os.WriteFile(indexed, append(llvmIndexedMagic, 0, 0, 0, 0), 0644)
+	if p, err := Load(indexed, nil); err != nil || p.Kind != Instr {
+		t.Fatalf("Load(indexed, nil) = %v, %v", p, err)
+	}
+	raw := filepath.Join(dir, "default.profraw")
+	os.WriteFile(raw, append(llvmRawMagic, 0, 0, 0, 0), 0644)
+	if _, err := Load(raw, nil); err == nil {
+		t.Fatal("Load(raw, nil): no error")
+	}
+	bad := filepath.Join(dir, "bad.pgo")
+	os.WriteFile(bad, []byte{0x12, 0x10}, 0644)
+	if _, err := Load(bad, nil); err == nil {
+		t.Fatal("Load(truncated): no error")
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pgo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var errTruncated = errors.New("truncated profile")
+
+// sample is a sample of a pprof profile with the function names of its
+// stack, innermost first.
+type sample struct {
+	funcs []string
+	value int64
+}
+
+// Field numbers of the profile.proto messages used.
+const (
+	profileSample      = 2
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+
+	functionID   = 1
+	functionName = 2
+)
+
+// message iterates over the fields of a protocol buffer message.
+type message struct {
+	data []byte
+	err  error
+
+	field int
+	wire  int
+	val   uint64 // value of varint fields
+	bytes []byte // value of length-delimited fields
+}
+
+func (m *message) next() bool {
+	if m.err != nil || len(m.data) == 0 {
+		return false
+	}
+	key, ok := m.varint()
+	if !ok {
+		return false
+	}
+	m.field, m.wire = int(key>>3), int(key&7)
+	switch m.wire {
+	case 0:
+		m.val, ok = m.varint()
+	case 1:
+		if ok = len(m.data) >= 8; ok {
+			m.val = binary.LittleEndian.Uint64(m.data)
+			m.data = m.data[8:]
+		}
+	case 2:
+		var n uint64
+		if n, ok = m.varint(); ok && n <= uint64(len(m.data)) {
+			m.bytes, m.data = m.data[:n], m.data[n:]
+		} else {
+			ok = false
+		}
+	case 5:
+		if ok = len(m.data) >= 4; ok {
+			m.val = uint64(binary.LittleEndian.Uint32(m.data))
+			m.data = m.data[4:]
+		}
+	default:
+		m.err = fmt.Errorf("unsupported wire type %d", m.wire)
+		return false
+	}
+	if !ok {
+		m.err = errTruncated
+	}
+	return ok
+}
+
+func (m *message) varint() (uint64, bool) {
+	v, n := binary.Uvarint(m.data)
+	if n <= 0 {
+		m.err = errTruncated
+		return 0, false
+	}
+	m.data = m.data[n:]
+	return v, true
+}
+
+// uints returns the values of a repeated integer field, either packed or not.
+func (m *message) uints(dst []uint64) []uint64 {
+	if m.wire != 2 {
+		return append(dst, m.val)
+	}
+	data := m.bytes
+	for len(data) > 0 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			m.err = errTruncated
+			return dst
+		}
+		dst = append(dst, v)
+		data = data[n:]
+	}
+	return dst
+}
+
+// parsePprof parses an uncompressed pprof profile. The value of a sample
+// is the last one, that is the CPU time of a CPU profile.
+func parsePprof(data []byte) ([]sample, error) {
+	type rawSample struct {
+		locs   []uint64
+		values []uint64
+	}
+	var (
+		raws    []rawSample
+		locs    = make(map[uint64][]uint64) // location id => function ids
+		funcs   = make(map[uint64]uint64)   // function id => name index
+		strings []string
+	)
+	m := &message{data: data}
+	for m.next() {
+		switch m.field {
+		case profileSample:
+			var s rawSample
+			sm := &message{data: m.bytes}
+			for sm.next() {
+				switch sm.field {
+				case sampleLocationID:
+					s.locs = sm.uints(s.locs)
+				case sampleValue:
+					s.values = sm.uints(s.values)
+				}
+			}
+			if sm.err != nil {
+				return nil, sm.err
+			}
+			raws = append(raws, s)
+		case profileLocation:
+			var id uint64
+			var fns []uint64
+			lm := &message{data: m.bytes}
+			for lm.next() {
+				switch lm.field {
+				case locationID:
+					id = lm.val
+				case locationLine:
+					ln := &message{data: lm.bytes}
+					for ln.next() {
+						if ln.field == lineFunctionID {
+							fns = append(fns, ln.val)
+						}
+					}
+					if ln.err != nil {
+						return nil, ln.err
+					}
+				}
+			}
+			if lm.err != nil {
+				return nil, lm.err
+			}
+			locs[id] = fns
+		case profileFunction:
+			var id, name uint64
+			fm := &message{data: m.bytes}
+			for fm.next() {
+				switch fm.field {
+				case functionID:
+					id = fm.val
+				case functionName:
+					name = fm.val
+				}
+			}
+			if fm.err != nil {
+				return nil, fm.err
+			}
+			funcs[id] = name
+		case profileStringTable:
+			strings = append(strings, string(m.bytes))
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	ret := make([]sample, 0, len(raws))
+	for _, raw := range raws {
+		if len(raw.values) == 0 {
+			continue
+		}
+		s := sample{value: int64(raw.values[len(raw.values)-1])}
+		for _, loc := range raw.locs {
+			for _, fn := range locs[loc] {
+				if idx, ok := funcs[fn]; ok && idx < uint64(len(strings)) {
+					s.funcs = append(s.funcs, strings[idx])
+				}
+			}
+		}
+		ret = append(ret, s)
+	}
+	return ret, nil
+}
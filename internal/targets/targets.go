@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package targets loads target definitions selected by `llgo build -target`.
+//
+// A target is defined by a JSON or TOML file named <name>.json or
+// <name>.toml. Targets are searched in the directories listed in
+// $LLGO_TARGETS and in the targets directory of LLGo. A target may inherit
+// from other targets: their fields are overridden by the non-empty fields
+// of the target, except lists which are concatenated.
+package targets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goplus/llgo/internal/env"
+)
+
+// Target is a target definition.
+type Target struct {
+	Name string `json:"-"`
+
+	Inherits []string `json:"inherits"`
+
+	// GOOS and GOARCH are the values used to load Go packages. Bare-metal
+	// targets use the GOOS and GOARCH with the closest ABI, eg. linux/arm
+	// for 32-bit microcontrollers, and distinguish themselves by BuildTags.
+	GOOS      string   `json:"goos"`
+	GOARCH    string   `json:"goarch"`
+	BuildTags []string `json:"build-tags"`
+
+	Triple   string `json:"triple"`
+	CPU      string `json:"cpu"`
+	Features string `json:"features"`
+
+	// RelocationModel is the relocation model of the generated code:
+	// "static", "pic" or "dynamic-no-pic". It defaults to "pic".
+	RelocationModel string `json:"relocation-model"`
+
+	Linker       string `json:"linker"`        // passed to clang as -fuse-ld
+	LinkerScript string `json:"linker-script"` // relative to the file of the target
+	Libc         string `json:"libc"`          // "none", or a sysroot relative to the file of the target
+
+	CFlags  []string `json:"cflags"`
+	LDFlags []string `json:"ldflags"`
+}
+
+// Dirs returns the directories targets are searched in.
+func Dirs() (dirs []string) {
+	for _, dir := range filepath.SplitList(os.Getenv("LLGO_TARGETS")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if root := env.LLGoROOT(); root != "" {
+		dirs = append(dirs, filepath.Join(root, "targets"))
+	}
+	return
+}
+
+// Load loads the target of the given name, searched in dirs, or from the
+// given file if name is a path to a .json or .toml file.
+func Load(name string, dirs []string) (*Target, error) {
+	return load(name, dirs, nil)
+}
+
+func load(name string, dirs []string, loading []string) (*Target, error) {
+	file, err := find(name, dirs)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range loading {
+		if f == file {
+			return nil, fmt.Errorf("target %s inherits from itself", name)
+		}
+	}
+	loading = append(loading, file)
+
+	t, err := parseFile(file)
+	if err != nil {
+		return nil, err
+	}
+	t.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	dir := filepath.Dir(file)
+	if t.LinkerScript != "" {
+		t.LinkerScript = resolve(dir, t.LinkerScript)
+	}
+	if t.Libc != "" && t.Libc != "none" {
+		t.Libc = resolve(dir, t.Libc)
+	}
+
+	ret := new(Target)
+	for _, parent := range t.Inherits {
+		base, err := load(parent, append([]string{dir}, dirs...), loading)
+		if err != nil {
+			return nil, err
+		}
+		ret.override(base)
+	}
+	ret.override(t)
+	ret.Name, ret.Inherits = t.Name, t.Inherits
+	if ret.Triple == "" || ret.GOOS == "" || ret.GOARCH == "" {
+		return nil, fmt.Errorf("target %s: triple, goos and goarch are required", t.Name)
+	}
+	switch ret.RelocationModel {
+	case "", "static", "pic", "dynamic-no-pic":
+	default:
+		return nil, fmt.Errorf("target %s: invalid relocation-model %q", t.Name, ret.RelocationModel)
+	}
+	return ret, nil
+}
+
+func (p *Target) override(t *Target) {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&p.GOOS, t.GOOS)
+	set(&p.GOARCH, t.GOARCH)
+	set(&p.Triple, t.Triple)
+	set(&p.CPU, t.CPU)
+	set(&p.Features, t.Features)
+	set(&p.RelocationModel, t.RelocationModel)
+	set(&p.Linker, t.Linker)
+	set(&p.LinkerScript, t.LinkerScript)
+	set(&p.Libc, t.Libc)
+	p.BuildTags = append(p.BuildTags, t.BuildTags...)
+	p.CFlags = append(p.CFlags, t.CFlags...)
+	p.LDFlags = append(p.LDFlags, t.LDFlags...)
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func find(name string, dirs []string) (string, error) {
+	if ext := filepath.Ext(name); ext == ".json" || ext == ".toml" {
+		return filepath.Abs(name)
+	}
+	for _, dir := range dirs {
+		for _, ext := range []string{".json", ".toml"} {
+			file := filepath.Join(dir, name+ext)
+			if _, err := os.Stat(file); err == nil {
+				return filepath.Abs(file)
+			}
+		}
+	}
+	return "", fmt.Errorf("target %s not found in %s", name, strings.Join(dirs, string(filepath.ListSeparator)))
+}
+
+func parseFile(file string) (*Target, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	t := new(Target)
+	if filepath.Ext(file) == ".toml" {
+		err = parseTOML(data, t)
+	} else {
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(t)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return t, nil
+}
+
+// CompileFlags returns the clang flags to compile C files for the target.
+func (p *Target) CompileFlags() []string {
+	args := []string{"-target", p.Triple}
+	if p.CPU != "" {
+		args = append(args, "-mcpu="+p.CPU)
+	}
+	switch p.Libc {
+	case "":
+	case "none":
+		args = append(args, "-ffreestanding")
+	default:
+		args = append(args, "--sysroot="+p.Libc)
+	}
+	return append(args, p.CFlags...)
+}
+
+// LinkFlags returns the clang flags to link a program for the target.
+func (p *Target) LinkFlags() []string {
+	args := []string{"-target", p.Triple, "-Wno-override-module"}
+	if p.Linker != "" {
+		args = append(args, "-fuse-ld="+p.Linker)
+	}
+	if p.LinkerScript != "" {
+		args = append(args, "-T", p.LinkerScript)
+	}
+	switch p.Libc {
+	case "":
+	case "none":
+		args = append(args, "-nostdlib")
+	default:
+		args = append(args, "--sysroot="+p.Libc)
+	}
+	return append(args, p.LDFlags...)
+}
@@ -0,0 +1,114 @@
+//go:build !llgo
+// +build !llgo
+
+package targets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func builtinDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "targets")
+}
+
+func TestLoadBuiltin(t *testing.T) {
+	dir := builtinDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		name = name[:len(name)-len(filepath.Ext(name))]
+		if _, err := Load(name, []string{dir}); err != nil {
+			t.Errorf("Load(%s): %v", name, err)
+		}
+	}
+
+	m4, err := Load("cortex-m4", []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m4.GOOS != "linux" || m4.GOARCH != "arm" || m4.CPU != "cortex-m4" || m4.Triple != "thumbv7em-unknown-unknown-eabihf" {
+		t.Errorf("cortex-m4 = %+v", m4)
+	}
+	if want := []string{"baremetal", "nogc", "cortexm"}; !reflect.DeepEqual(m4.BuildTags, want) {
+		t.Errorf("cortex-m4 build tags = %v, want %v", m4.BuildTags, want)
+	}
+	if m4.RelocationModel != "static" {
+		t.Errorf("cortex-m4 relocation model = %q, want static", m4.RelocationModel)
+	}
+
+	rv, err := Load("riscv32", []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"-march=rv32imac", "-mabi=ilp32", "-fdata-sections", "-ffunction-sections"}; !reflect.DeepEqual(rv.CFlags, want) {
+		t.Errorf("riscv32 cflags = %v, want %v", rv.CFlags, want)
+	}
+	if rv.RelocationModel != "static" {
+		t.Errorf("riscv32 relocation model = %q, want static", rv.RelocationModel)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "base.json"), []byte(`{
+	"goos": "linux", "goarch": "arm", "triple": "thumbv7m-unknown-unknown-eabi",
+	"build-tags": ["baremetal"], "libc": "none", "ldflags": ["-Wl,--gc-sections"]
+}`), 0644)
+	os.WriteFile(filepath.Join(dir, "board.toml"), []byte(`
+inherits = ["base"] # from the same directory
+cpu = "cortex-m3"
+linker-script = "board.ld"
+build-tags = [
+	"board",
+	"with#hash",
+]
+ldflags = ["-Wl,-Map,board.map"]
+`), 0644)
+	board, err := Load(filepath.Join(dir, "board.toml"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Target{
+		Name:         "board",
+		Inherits:     []string{"base"},
+		GOOS:         "linux",
+		GOARCH:       "arm",
+		BuildTags:    []string{"baremetal", "board", "with#hash"},
+		Triple:       "thumbv7m-unknown-unknown-eabi",
+		CPU:          "cortex-m3",
+		LinkerScript: filepath.Join(dir, "board.ld"),
+		Libc:         "none",
+		LDFlags:      []string{"-Wl,--gc-sections", "-Wl,-Map,board.map"},
+	}
+	if !reflect.DeepEqual(board, want) {
+		t.Errorf("board =\n%+v, want\n%+v", board, want)
+	}
+	wantLink := []string{"-target", "thumbv7m-unknown-unknown-eabi", "-Wno-override-module",
+		"-T", filepath.Join(dir, "board.ld"), "-nostdlib", "-Wl,--gc-sections", "-Wl,-Map,board.map"}
+	if got := board.LinkFlags(); !reflect.DeepEqual(got, wantLink) {
+		t.Errorf("LinkFlags() = %v, want %v", got, wantLink)
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "loop.json"), []byte(`{"inherits": ["loop"]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "partial.json"), []byte(`{"goos": "linux"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "unknown.toml"), []byte(`arch = "arm"`), 0644)
+	os.WriteFile(filepath.Join(dir, "reloc.json"), []byte(`{
+	"goos": "linux", "goarch": "arm", "triple": "thumbv7m-unknown-unknown-eabi", "relocation-model": "ropi"
+}`), 0644)
+	for _, name := range []string{"loop", "partial", "unknown", "missing", "reloc"} {
+		if _, err := Load(name, []string{dir}); err == nil {
+			t.Errorf("Load(%s): no error", name)
+		}
+	}
+}
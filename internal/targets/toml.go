@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package targets
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML used by target definitions: comments
+// and `key = value` pairs where a value is a string or an array of strings.
+// Keys are the same as the JSON ones.
+func parseTOML(data []byte, t *Target) error {
+	fields := make(map[string]reflect.Value)
+	v := reflect.ValueOf(t).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if key := v.Type().Field(i).Tag.Get("json"); key != "-" {
+			fields[key] = v.Field(i)
+		}
+	}
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineno := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+		pos := strings.IndexByte(line, '=')
+		if pos < 0 {
+			return fmt.Errorf("line %d: expected key = value", lineno)
+		}
+		key := strings.Trim(strings.TrimSpace(line[:pos]), `"`)
+		val := strings.TrimSpace(line[pos+1:])
+		// arrays may span several lines
+		if strings.HasPrefix(val, "[") {
+			for !strings.HasSuffix(val, "]") && i+1 < len(lines) {
+				i++
+				val += " " + strings.TrimSpace(stripComment(lines[i]))
+			}
+		}
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("line %d: unknown key %s", lineno, key)
+		}
+		if field.Kind() == reflect.Slice {
+			list, err := tomlArray(val)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", lineno, err)
+			}
+			field.Set(reflect.ValueOf(list))
+		} else {
+			s, err := strconv.Unquote(val)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid string %s", lineno, val)
+			}
+			field.SetString(s)
+		}
+	}
+	return nil
+}
+
+func tomlArray(val string) (list []string, err error) {
+	if !strings.HasPrefix(val, "[") || !strings.HasSuffix(val, "]") {
+		return nil, fmt.Errorf("invalid array %s", val)
+	}
+	rest := strings.TrimSpace(val[1 : len(val)-1])
+	for rest != "" {
+		prefix, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array %s", val)
+		}
+		s, _ := strconv.Unquote(prefix)
+		list = append(list, s)
+		rest = strings.TrimSpace(rest[len(prefix):])
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, fmt.Errorf("invalid array %s", val)
+			}
+			rest = strings.TrimSpace(rest[1:]) // also allows a trailing comma
+		}
+	}
+	return
+}
+
+// stripComment removes a comment from a line, ignoring # in strings.
+func stripComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case '#':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
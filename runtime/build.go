@@ -12,9 +12,32 @@ func HasAltPkg(path string) (b bool) {
 	return
 }
 
+// HasOpenSSLAltPkg reports whether the alternative package of path binds it
+// to OpenSSL. Such packages are compiled from source instead with the Go
+// crypto backend.
+func HasOpenSSLAltPkg(path string) (b bool) {
+	_, b = hasOpenSSLAltPkg[path]
+	return
+}
+
 type none struct{}
 
+var hasOpenSSLAltPkg = map[string]none{
+	"crypto/aes":     {},
+	"crypto/ecdsa":   {},
+	"crypto/ed25519": {},
+	"crypto/hmac":    {},
+	"crypto/md5":     {},
+	"crypto/rand":    {},
+	"crypto/sha1":    {},
+	"crypto/sha256":  {},
+	"crypto/sha512":  {},
+}
+
 var hasAltPkg = map[string]none{
+	"crypto/aes":               {},
+	"crypto/ecdsa":             {},
+	"crypto/ed25519":           {},
 	"crypto/hmac":              {},
 	"crypto/md5":               {},
 	"crypto/rand":              {},
@@ -39,6 +62,7 @@ var hasAltPkg = map[string]none{
 	"internal/sync":            {},
 	"internal/syscall/execenv": {},
 	"internal/syscall/unix":    {},
+	"iter":                     {},
 	"math":                     {},
 	"math/big":                 {},
 	"math/cmplx":               {},
@@ -47,7 +71,10 @@ var hasAltPkg = map[string]none{
 	"sync":                     {},
 	"sync/atomic":              {},
 	"syscall":                  {},
+	"syscall/js":               {},
 	"time":                     {},
+	"unique":                   {},
+	"weak":                     {},
 	"os":                       {},
 	"os/exec":                  {},
 	"os/signal":                {},
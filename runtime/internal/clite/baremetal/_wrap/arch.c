@@ -0,0 +1,246 @@
+/*
+ * Context switch and setjmp/longjmp.
+ *
+ * llgo_switch(save, sp) saves the callee-saved registers on the current
+ * stack, stores the stack pointer in *save and resumes the context saved
+ * at sp. llgo_init_stack prepares the stack of a new task so that resuming
+ * it calls llgo_task_main(t).
+ *
+ * The compiler calls sigsetjmp (__sigsetjmp on Linux) and siglongjmp to
+ * implement defer. There are no signals, so they are aliases of setjmp and
+ * longjmp.
+ */
+#include "baremetal.h"
+
+void llgo_task_entry(void);
+
+#if defined(__thumb__)
+
+/* ARMv6-M and later in Thumb mode. Only low registers can be pushed by
+ * ARMv6-M, so r8-r11 are saved through r4-r7. */
+
+#if defined(__ARM_FP)
+#define VPUSH "vpush {s16-s31}\n"
+#define VPOP "vpop {s16-s31}\n"
+#define VSTM "vstmia r0!, {s16-s31}\n"
+#define VLDM "vldmia r2!, {s16-s31}\n"
+#define FP_WORDS 16
+#else
+#define VPUSH
+#define VPOP
+#define VSTM
+#define VLDM
+#define FP_WORDS 0
+#endif
+
+__asm__(
+    ".syntax unified\n"
+    ".text\n"
+    ".thumb\n"
+
+    ".global llgo_switch\n"
+    ".type llgo_switch, %function\n"
+    ".thumb_func\n"
+    "llgo_switch:\n"
+    "push {r4-r7, lr}\n"
+    "mov r4, r8\n"
+    "mov r5, r9\n"
+    "mov r6, r10\n"
+    "mov r7, r11\n"
+    "push {r4-r7}\n"
+    VPUSH
+    "mov r2, sp\n"
+    "str r2, [r0]\n"
+    "mov sp, r1\n"
+    VPOP
+    "pop {r4-r7}\n"
+    "mov r8, r4\n"
+    "mov r9, r5\n"
+    "mov r10, r6\n"
+    "mov r11, r7\n"
+    "pop {r4-r7, pc}\n"
+
+    ".global llgo_task_entry\n"
+    ".type llgo_task_entry, %function\n"
+    ".thumb_func\n"
+    "llgo_task_entry:\n"
+    "mov r0, r4\n"
+    "bl llgo_task_main\n"
+
+    ".global setjmp, sigsetjmp, __sigsetjmp\n"
+    ".type setjmp, %function\n"
+    ".type sigsetjmp, %function\n"
+    ".type __sigsetjmp, %function\n"
+    ".thumb_func\n"
+    "setjmp:\n"
+    ".thumb_func\n"
+    "sigsetjmp:\n"
+    ".thumb_func\n"
+    "__sigsetjmp:\n"
+    "stmia r0!, {r4-r7}\n"
+    "mov r1, r8\n"
+    "mov r2, r9\n"
+    "mov r3, r10\n"
+    "stmia r0!, {r1-r3}\n"
+    "mov r1, r11\n"
+    "mov r2, sp\n"
+    "mov r3, lr\n"
+    "stmia r0!, {r1-r3}\n"
+    VSTM
+    "movs r0, #0\n"
+    "bx lr\n"
+
+    ".global longjmp, siglongjmp\n"
+    ".type longjmp, %function\n"
+    ".type siglongjmp, %function\n"
+    ".thumb_func\n"
+    "longjmp:\n"
+    ".thumb_func\n"
+    "siglongjmp:\n"
+    "mov r2, r0\n"
+    "adds r2, #16\n"
+    "ldmia r2!, {r3-r5}\n"
+    "mov r8, r3\n"
+    "mov r9, r4\n"
+    "mov r10, r5\n"
+    "ldmia r2!, {r3-r5}\n"
+    "mov r11, r3\n"
+    "mov sp, r4\n"
+    "mov lr, r5\n"
+    VLDM
+    "ldmia r0!, {r4-r7}\n"
+    "movs r0, r1\n"
+    "bne 1f\n"
+    "movs r0, #1\n"
+    "1:\n"
+    "bx lr\n");
+
+void *llgo_init_stack(void *top, llgo_task *t) {
+    /* FP registers, r8-r11, then r4-r7 and the return address */
+    uint32_t *sp = (uint32_t *)((uintptr_t)top & ~(uintptr_t)7) - (FP_WORDS + 9);
+    memset(sp, 0, (FP_WORDS + 9) * 4);
+    sp[FP_WORDS + 4] = (uint32_t)t; /* r4 */
+    sp[FP_WORDS + 8] = (uint32_t)llgo_task_entry; /* pc */
+    return sp;
+}
+
+#elif defined(__riscv)
+
+#if !defined(__riscv_float_abi_soft)
+#error "the bare-metal runtime only supports soft-float RISC-V ABIs"
+#endif
+
+#if __riscv_xlen == 64
+#define S "sd "
+#define L "ld "
+#define R(i) #i "*8"
+#else
+#define S "sw "
+#define L "lw "
+#define R(i) #i "*4"
+#endif
+
+/* llgo_switch saves ra and s0-s11 in a frame of 16 registers, to keep the
+ * stack 16-byte aligned. */
+__asm__(
+    ".text\n"
+
+    ".global llgo_switch\n"
+    ".type llgo_switch, @function\n"
+    "llgo_switch:\n"
+    "addi sp, sp, -" R(16) "\n"
+    S "ra, " R(0) "(sp)\n"
+    S "s0, " R(1) "(sp)\n"
+    S "s1, " R(2) "(sp)\n"
+    S "s2, " R(3) "(sp)\n"
+    S "s3, " R(4) "(sp)\n"
+    S "s4, " R(5) "(sp)\n"
+    S "s5, " R(6) "(sp)\n"
+    S "s6, " R(7) "(sp)\n"
+    S "s7, " R(8) "(sp)\n"
+    S "s8, " R(9) "(sp)\n"
+    S "s9, " R(10) "(sp)\n"
+    S "s10, " R(11) "(sp)\n"
+    S "s11, " R(12) "(sp)\n"
+    S "sp, 0(a0)\n"
+    "mv sp, a1\n"
+    L "ra, " R(0) "(sp)\n"
+    L "s0, " R(1) "(sp)\n"
+    L "s1, " R(2) "(sp)\n"
+    L "s2, " R(3) "(sp)\n"
+    L "s3, " R(4) "(sp)\n"
+    L "s4, " R(5) "(sp)\n"
+    L "s5, " R(6) "(sp)\n"
+    L "s6, " R(7) "(sp)\n"
+    L "s7, " R(8) "(sp)\n"
+    L "s8, " R(9) "(sp)\n"
+    L "s9, " R(10) "(sp)\n"
+    L "s10, " R(11) "(sp)\n"
+    L "s11, " R(12) "(sp)\n"
+    "addi sp, sp, " R(16) "\n"
+    "ret\n"
+
+    ".global llgo_task_entry\n"
+    ".type llgo_task_entry, @function\n"
+    "llgo_task_entry:\n"
+    "mv a0, s0\n"
+    "call llgo_task_main\n"
+
+    ".global setjmp, sigsetjmp, __sigsetjmp\n"
+    ".type setjmp, @function\n"
+    ".type sigsetjmp, @function\n"
+    ".type __sigsetjmp, @function\n"
+    "setjmp:\n"
+    "sigsetjmp:\n"
+    "__sigsetjmp:\n"
+    S "ra, " R(0) "(a0)\n"
+    S "sp, " R(1) "(a0)\n"
+    S "s0, " R(2) "(a0)\n"
+    S "s1, " R(3) "(a0)\n"
+    S "s2, " R(4) "(a0)\n"
+    S "s3, " R(5) "(a0)\n"
+    S "s4, " R(6) "(a0)\n"
+    S "s5, " R(7) "(a0)\n"
+    S "s6, " R(8) "(a0)\n"
+    S "s7, " R(9) "(a0)\n"
+    S "s8, " R(10) "(a0)\n"
+    S "s9, " R(11) "(a0)\n"
+    S "s10, " R(12) "(a0)\n"
+    S "s11, " R(13) "(a0)\n"
+    "li a0, 0\n"
+    "ret\n"
+
+    ".global longjmp, siglongjmp\n"
+    ".type longjmp, @function\n"
+    ".type siglongjmp, @function\n"
+    "longjmp:\n"
+    "siglongjmp:\n"
+    L "ra, " R(0) "(a0)\n"
+    L "sp, " R(1) "(a0)\n"
+    L "s0, " R(2) "(a0)\n"
+    L "s1, " R(3) "(a0)\n"
+    L "s2, " R(4) "(a0)\n"
+    L "s3, " R(5) "(a0)\n"
+    L "s4, " R(6) "(a0)\n"
+    L "s5, " R(7) "(a0)\n"
+    L "s6, " R(8) "(a0)\n"
+    L "s7, " R(9) "(a0)\n"
+    L "s8, " R(10) "(a0)\n"
+    L "s9, " R(11) "(a0)\n"
+    L "s10, " R(12) "(a0)\n"
+    L "s11, " R(13) "(a0)\n"
+    "seqz a0, a1\n"
+    "add a0, a0, a1\n"
+    "ret\n");
+
+void *llgo_init_stack(void *top, llgo_task *t) {
+    uintptr_t *sp = (uintptr_t *)((uintptr_t)top & ~(uintptr_t)15) - 16;
+    memset(sp, 0, 16 * sizeof(uintptr_t));
+    sp[0] = (uintptr_t)llgo_task_entry; /* ra */
+    sp[1] = (uintptr_t)t; /* s0 */
+    return sp;
+}
+
+#else
+#error "the bare-metal runtime does not support this architecture"
+#endif
@@ -0,0 +1,51 @@
+#ifndef LLGO_BAREMETAL_H
+#define LLGO_BAREMETAL_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+#include "llgo_hal.h"
+
+#ifndef LLGO_STACK_SIZE
+#define LLGO_STACK_SIZE (16 * 1024)
+#endif
+
+#ifndef LLGO_HEAP_SIZE
+#define LLGO_HEAP_SIZE (64 * 1024)
+#endif
+
+#define LLGO_MAX_KEYS 16
+
+/* error numbers of Linux, the GOOS bare-metal targets are loaded as */
+#define LLGO_EAGAIN 11
+#define LLGO_EBUSY 16
+#define LLGO_EINVAL 22
+#define LLGO_ETIMEDOUT 110
+
+typedef struct llgo_task {
+    void *sp; /* saved stack pointer, see llgo_switch */
+    struct llgo_task *next; /* circular list of live tasks */
+    void *(*start)(void *);
+    void *arg;
+    void *stack;
+    void *keys[LLGO_MAX_KEYS]; /* values of pthread keys */
+} llgo_task;
+
+struct timespec {
+    long tv_sec;
+    long tv_nsec;
+};
+
+void *malloc(size_t size);
+void *calloc(size_t n, size_t size);
+void free(void *ptr);
+void *memset(void *dst, int c, size_t n);
+
+uint64_t llgo_nanotime(void);
+
+/* implemented in arch.c */
+void llgo_switch(void **save, void *sp);
+void *llgo_init_stack(void *top, llgo_task *t);
+void llgo_task_main(llgo_task *t) __attribute__((noreturn));
+
+#endif
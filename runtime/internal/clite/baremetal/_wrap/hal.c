@@ -0,0 +1,12 @@
+#include "baremetal.h"
+
+__attribute__((weak)) void llgo_hal_heap(void **start, size_t *size) {
+    static char heap[LLGO_HEAP_SIZE] __attribute__((aligned(16)));
+    *start = heap;
+    *size = sizeof(heap);
+}
+
+__attribute__((weak)) uint64_t llgo_hal_nanotime(void) {
+    static uint64_t now;
+    return now += 1000;
+}
@@ -0,0 +1,476 @@
+/*
+ * The subset of the C library used by the runtime: memory allocation,
+ * memory and string functions, console output through the HAL, clocks and
+ * exit.
+ */
+#include <stdarg.h>
+
+#include "baremetal.h"
+
+/* ------------------------------------------------------------------------- */
+
+#define NO_BUILTIN __attribute__((no_builtin))
+
+NO_BUILTIN void *memset(void *dst, int c, size_t n) {
+    unsigned char *d = dst;
+    while (n--) {
+        *d++ = (unsigned char)c;
+    }
+    return dst;
+}
+
+NO_BUILTIN void *memcpy(void *dst, const void *src, size_t n) {
+    unsigned char *d = dst;
+    const unsigned char *s = src;
+    while (n--) {
+        *d++ = *s++;
+    }
+    return dst;
+}
+
+NO_BUILTIN void *memmove(void *dst, const void *src, size_t n) {
+    unsigned char *d = dst;
+    const unsigned char *s = src;
+    if (d < s) {
+        while (n--) {
+            *d++ = *s++;
+        }
+    } else {
+        d += n;
+        s += n;
+        while (n--) {
+            *--d = *--s;
+        }
+    }
+    return dst;
+}
+
+NO_BUILTIN int memcmp(const void *a, const void *b, size_t n) {
+    const unsigned char *p = a, *q = b;
+    for (; n; n--, p++, q++) {
+        if (*p != *q) {
+            return *p - *q;
+        }
+    }
+    return 0;
+}
+
+NO_BUILTIN size_t strlen(const char *s) {
+    const char *p = s;
+    while (*p) {
+        p++;
+    }
+    return p - s;
+}
+
+/* ------------------------------------------------------------------------- */
+
+/* First-fit allocator over the HAL heap. Free blocks are kept in address
+ * order so that adjacent blocks are merged when freed. */
+
+typedef struct block {
+    size_t size; /* including the header */
+    struct block *next; /* next free block */
+} block;
+
+#define HDR sizeof(block)
+
+static block *free_list;
+static int heap_ready;
+
+static void heap_init(void) {
+    void *start;
+    size_t size;
+    llgo_hal_heap(&start, &size);
+    uintptr_t p = ((uintptr_t)start + HDR - 1) & ~(uintptr_t)(HDR - 1);
+    size -= p - (uintptr_t)start;
+    free_list = (block *)p;
+    free_list->size = size & ~(HDR - 1);
+    free_list->next = NULL;
+    heap_ready = 1;
+}
+
+void *malloc(size_t size) {
+    if (!heap_ready) {
+        heap_init();
+    }
+    if (size > (size_t)-1 - 2 * HDR) {
+        return NULL;
+    }
+    size_t need = (size + 2 * HDR - 1) & ~(HDR - 1);
+    for (block **prev = &free_list; *prev; prev = &(*prev)->next) {
+        block *b = *prev;
+        if (b->size < need) {
+            continue;
+        }
+        if (b->size - need >= 2 * HDR) {
+            block *rest = (block *)((char *)b + need);
+            rest->size = b->size - need;
+            rest->next = b->next;
+            *prev = rest;
+            b->size = need;
+        } else {
+            *prev = b->next;
+        }
+        return (char *)b + HDR;
+    }
+    return NULL;
+}
+
+void free(void *ptr) {
+    if (ptr == NULL) {
+        return;
+    }
+    block *b = (block *)((char *)ptr - HDR);
+    block *prev = NULL, *next = free_list;
+    while (next && next < b) {
+        prev = next;
+        next = next->next;
+    }
+    if (next && (char *)b + b->size == (char *)next) {
+        b->size += next->size;
+        next = next->next;
+    }
+    b->next = next;
+    if (prev && (char *)prev + prev->size == (char *)b) {
+        prev->size += b->size;
+        prev->next = next;
+    } else if (prev) {
+        prev->next = b;
+    } else {
+        free_list = b;
+    }
+}
+
+void *calloc(size_t n, size_t size) {
+    if (size && n > (size_t)-1 / size) {
+        return NULL;
+    }
+    void *p = malloc(n * size);
+    if (p) {
+        memset(p, 0, n * size);
+    }
+    return p;
+}
+
+void *realloc(void *ptr, size_t size) {
+    if (ptr == NULL) {
+        return malloc(size);
+    }
+    if (size == 0) {
+        free(ptr);
+        return NULL;
+    }
+    block *b = (block *)((char *)ptr - HDR);
+    size_t old = b->size - HDR;
+    if (old >= size) {
+        return ptr;
+    }
+    void *p = malloc(size);
+    if (p) {
+        memcpy(p, ptr, old);
+        free(ptr);
+    }
+    return p;
+}
+
+/* ------------------------------------------------------------------------- */
+
+/* Both streams write to the console. */
+void *stdout = (void *)1;
+void *stderr = (void *)2;
+
+typedef struct {
+    char buf[64];
+    size_t n;
+    int total;
+} writer;
+
+static void flush(writer *w) {
+    llgo_hal_write(w->buf, w->n);
+    w->n = 0;
+}
+
+static void put(writer *w, const char *s, size_t n) {
+    w->total += n;
+    while (n--) {
+        if (w->n == sizeof(w->buf)) {
+            flush(w);
+        }
+        w->buf[w->n++] = *s++;
+    }
+}
+
+static void pad(writer *w, char c, int n) {
+    while (n-- > 0) {
+        put(w, &c, 1);
+    }
+}
+
+static void put_field(writer *w, const char *s, int n, int width, int left) {
+    if (!left) {
+        pad(w, ' ', width - n);
+    }
+    put(w, s, n);
+    if (left) {
+        pad(w, ' ', width - n);
+    }
+}
+
+static void put_uint(writer *w, unsigned long long v, unsigned base, int upper,
+                     const char *prefix, int width, int zero, int left) {
+    const char *digits = upper ? "0123456789ABCDEF" : "0123456789abcdef";
+    char buf[24];
+    int i = sizeof(buf);
+    do {
+        buf[--i] = digits[v % base];
+        v /= base;
+    } while (v);
+    int plen = strlen(prefix);
+    int n = sizeof(buf) - i + plen;
+    if (zero && !left) {
+        put(w, prefix, plen);
+        pad(w, '0', width - n);
+    } else {
+        if (!left) {
+            pad(w, ' ', width - n);
+        }
+        put(w, prefix, plen);
+    }
+    put(w, buf + i, sizeof(buf) - i);
+    if (left) {
+        pad(w, ' ', width - n);
+    }
+}
+
+/* put_exp formats v as [-]d.ddde±dd. */
+static void put_exp(writer *w, double v, int prec, const char *sign, int width, int left) {
+    char buf[48];
+    int n = 0;
+    if (v < 0) {
+        v = -v;
+        buf[n++] = '-';
+    } else if (*sign) {
+        buf[n++] = *sign;
+    }
+    if (v != v) {
+        put_field(w, "nan", 3, width, left);
+        return;
+    }
+    if (v > 1.7976931348623157e308) {
+        buf[n++] = 'i';
+        buf[n++] = 'n';
+        buf[n++] = 'f';
+        put_field(w, buf, n, width, left);
+        return;
+    }
+    if (prec > 30) {
+        prec = 30;
+    }
+    int exp = 0;
+    if (v != 0) {
+        while (v >= 10) {
+            v /= 10;
+            exp++;
+        }
+        while (v < 1) {
+            v *= 10;
+            exp--;
+        }
+        double round = 0.5;
+        for (int i = 0; i < prec; i++) {
+            round /= 10;
+        }
+        v += round;
+        if (v >= 10) {
+            v /= 10;
+            exp++;
+        }
+    }
+    int d = (int)v;
+    buf[n++] = '0' + d;
+    if (prec > 0) {
+        buf[n++] = '.';
+    }
+    for (int i = 0; i < prec; i++) {
+        v = (v - d) * 10;
+        d = (int)v;
+        buf[n++] = '0' + d;
+    }
+    buf[n++] = 'e';
+    buf[n++] = exp < 0 ? '-' : '+';
+    if (exp < 0) {
+        exp = -exp;
+    }
+    if (exp >= 100) {
+        buf[n++] = '0' + exp / 100;
+    }
+    buf[n++] = '0' + exp / 10 % 10;
+    buf[n++] = '0' + exp % 10;
+    put_field(w, buf, n, width, left);
+}
+
+int vfprintf(void *stream, const char *format, va_list ap) {
+    writer w = {.n = 0, .total = 0};
+    for (const char *f = format; *f; f++) {
+        if (*f != '%') {
+            put(&w, f, 1);
+            continue;
+        }
+        f++;
+        const char *sign = "";
+        int zero = 0, left = 0, width = 0, prec = -1, lng = 0;
+        for (;; f++) {
+            if (*f == '+') {
+                sign = "+";
+            } else if (*f == ' ' && !*sign) {
+                sign = " ";
+            } else if (*f == '0') {
+                zero = 1;
+            } else if (*f == '-') {
+                left = 1;
+            } else {
+                break;
+            }
+        }
+        for (; *f >= '0' && *f <= '9'; f++) {
+            width = width * 10 + *f - '0';
+        }
+        if (*f == '.') {
+            prec = 0;
+            for (f++; *f >= '0' && *f <= '9'; f++) {
+                prec = prec * 10 + *f - '0';
+            }
+        }
+        for (;; f++) {
+            if (*f == 'l') {
+                lng++;
+            } else if (*f == 'z' || *f == 't') {
+                lng = sizeof(size_t) == sizeof(long long) ? 2 : 1;
+            } else if (*f == 'j') {
+                lng = 2;
+            } else if (*f != 'h') {
+                break;
+            }
+        }
+        switch (*f) {
+        case 'd':
+        case 'i': {
+            long long v = lng >= 2 ? va_arg(ap, long long) : lng ? va_arg(ap, long) : va_arg(ap, int);
+            unsigned long long u = v < 0 ? -(unsigned long long)v : (unsigned long long)v;
+            put_uint(&w, u, 10, 0, v < 0 ? "-" : sign, width, zero, left);
+            break;
+        }
+        case 'u':
+        case 'x':
+        case 'X': {
+            unsigned long long v = lng >= 2 ? va_arg(ap, unsigned long long)
+                                   : lng  ? va_arg(ap, unsigned long)
+                                          : va_arg(ap, unsigned int);
+            put_uint(&w, v, *f == 'u' ? 10 : 16, *f == 'X', "", width, zero, left);
+            break;
+        }
+        case 'p':
+            put_uint(&w, (uintptr_t)va_arg(ap, void *), 16, 0, "0x", width, zero, left);
+            break;
+        case 'c': {
+            char c = (char)va_arg(ap, int);
+            put_field(&w, &c, 1, width, left);
+            break;
+        }
+        case 's': {
+            const char *s = va_arg(ap, const char *);
+            if (s == NULL) {
+                s = "(null)";
+            }
+            int n = strlen(s);
+            if (prec >= 0 && n > prec) {
+                n = prec;
+            }
+            put_field(&w, s, n, width, left);
+            break;
+        }
+        case 'e':
+        case 'g':
+            put_exp(&w, va_arg(ap, double), prec < 0 ? 6 : prec, sign, width, left);
+            break;
+        case '%':
+            put(&w, "%", 1);
+            break;
+        case '\0':
+            f--;
+            break;
+        default:
+            put(&w, f - 1, 2);
+        }
+    }
+    flush(&w);
+    return w.total;
+}
+
+int fprintf(void *stream, const char *format, ...) {
+    va_list ap;
+    va_start(ap, format);
+    int n = vfprintf(stream, format, ap);
+    va_end(ap);
+    return n;
+}
+
+int printf(const char *format, ...) {
+    va_list ap;
+    va_start(ap, format);
+    int n = vfprintf(stdout, format, ap);
+    va_end(ap);
+    return n;
+}
+
+int fputc(int c, void *stream) {
+    char ch = (char)c;
+    llgo_hal_write(&ch, 1);
+    return c & 0xff;
+}
+
+int putchar(int c) {
+    return fputc(c, stdout);
+}
+
+int fputs(const char *s, void *stream) {
+    llgo_hal_write(s, strlen(s));
+    return 0;
+}
+
+int puts(const char *s) {
+    fputs(s, stdout);
+    return fputc('\n', stdout);
+}
+
+size_t fwrite(const void *ptr, size_t size, size_t n, void *stream) {
+    llgo_hal_write(ptr, size * n);
+    return n;
+}
+
+int fflush(void *stream) {
+    return 0;
+}
+
+/* ------------------------------------------------------------------------- */
+
+uint64_t llgo_nanotime(void) {
+    return llgo_hal_nanotime();
+}
+
+/* All clocks are the monotonic clock of the HAL. */
+int clock_gettime(int clock, struct timespec *ts) {
+    uint64_t now = llgo_nanotime();
+    ts->tv_sec = (long)(now / 1000000000);
+    ts->tv_nsec = (long)(now % 1000000000);
+    return 0;
+}
+
+void exit(int code) {
+    llgo_hal_exit(code);
+}
+
+void abort(void) {
+    llgo_hal_exit(134);
+}
@@ -0,0 +1,26 @@
+/*
+ * Hardware abstraction layer of the LLGo bare-metal runtime.
+ *
+ * Boards implement llgo_hal_write and llgo_hal_exit. llgo_hal_heap and
+ * llgo_hal_nanotime have weak default implementations: a static heap of
+ * LLGO_HEAP_SIZE bytes and a clock advancing by one microsecond per call.
+ */
+#ifndef LLGO_HAL_H
+#define LLGO_HAL_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+/* llgo_hal_write writes n bytes to the console, eg. a UART. */
+void llgo_hal_write(const char *buf, size_t n);
+
+/* llgo_hal_exit stops the program with the given status. */
+void llgo_hal_exit(int code) __attribute__((noreturn));
+
+/* llgo_hal_heap returns the memory region used by malloc. */
+void llgo_hal_heap(void **start, size_t *size);
+
+/* llgo_hal_nanotime returns a monotonic time in nanoseconds. */
+uint64_t llgo_hal_nanotime(void);
+
+#endif
@@ -0,0 +1,303 @@
+/*
+ * Cooperative single-core scheduler implementing the subset of the pthread
+ * API used by the runtime. Every goroutine is a task with its own stack;
+ * tasks only switch when they block, sleep or yield. The values of pthread
+ * keys, such as the defer chain and the panic of a goroutine, are stored in
+ * its task instead of thread local storage.
+ */
+#include "baremetal.h"
+
+typedef llgo_task *pthread_t;
+typedef unsigned int pthread_key_t;
+
+typedef struct {
+    int locked;
+} pthread_mutex_t;
+
+typedef struct {
+    unsigned seq;
+} pthread_cond_t;
+
+typedef struct {
+    int readers;
+    int writer;
+} pthread_rwlock_t;
+
+typedef int pthread_once_t;
+
+static llgo_task main_task = {.next = &main_task};
+static llgo_task *current = &main_task;
+static llgo_task *zombie; /* exited task, freed by the next task to run */
+
+static pthread_key_t nkeys;
+static void (*destructors[LLGO_MAX_KEYS])(void *);
+
+static void reap(void) {
+    if (zombie) {
+        free(zombie->stack);
+        free(zombie);
+        zombie = NULL;
+    }
+}
+
+/* schedule switches to the next task, if any. */
+static void schedule(void) {
+    llgo_task *prev = current;
+    if (prev->next == prev) {
+        return;
+    }
+    current = prev->next;
+    llgo_switch(&prev->sp, current->sp);
+    reap();
+}
+
+int sched_yield(void) {
+    schedule();
+    return 0;
+}
+
+void pthread_exit(void *retval);
+
+void llgo_task_main(llgo_task *t) {
+    reap();
+    pthread_exit(t->start(t->arg));
+    __builtin_unreachable();
+}
+
+int pthread_create(pthread_t *th, const void *attr, void *(*start)(void *), void *arg) {
+    llgo_task *t = calloc(1, sizeof(llgo_task));
+    if (t == NULL) {
+        return LLGO_EAGAIN;
+    }
+    t->stack = malloc(LLGO_STACK_SIZE);
+    if (t->stack == NULL) {
+        free(t);
+        return LLGO_EAGAIN;
+    }
+    t->start = start;
+    t->arg = arg;
+    t->sp = llgo_init_stack((char *)t->stack + LLGO_STACK_SIZE, t);
+    t->next = current->next;
+    current->next = t;
+    *th = t;
+    return 0;
+}
+
+void pthread_exit(void *retval) {
+    llgo_task *t = current;
+    for (pthread_key_t i = 0; i < nkeys; i++) {
+        void *v = t->keys[i];
+        if (v != NULL && destructors[i] != NULL) {
+            t->keys[i] = NULL;
+            destructors[i](v);
+        }
+    }
+    if (t == &main_task) {
+        /* the program exits when its last task exits */
+        while (t->next != t) {
+            schedule();
+        }
+        llgo_hal_exit(0);
+    }
+    llgo_task *prev = t;
+    while (prev->next != t) {
+        prev = prev->next;
+    }
+    prev->next = t->next;
+    zombie = t;
+    current = t->next;
+    llgo_switch(&t->sp, current->sp);
+    __builtin_unreachable();
+}
+
+pthread_t pthread_self(void) {
+    return current;
+}
+
+int pthread_equal(pthread_t t1, pthread_t t2) {
+    return t1 == t2;
+}
+
+/* ------------------------------------------------------------------------- */
+
+/* Keys start at 1: the compiler creates its key if it is still zero. */
+
+int pthread_key_create(pthread_key_t *key, void (*destructor)(void *)) {
+    if (nkeys == LLGO_MAX_KEYS) {
+        return LLGO_EAGAIN;
+    }
+    destructors[nkeys] = destructor;
+    *key = ++nkeys;
+    return 0;
+}
+
+int pthread_key_delete(pthread_key_t key) {
+    return 0;
+}
+
+void *pthread_getspecific(pthread_key_t key) {
+    return current->keys[key - 1];
+}
+
+int pthread_setspecific(pthread_key_t key, const void *value) {
+    current->keys[key - 1] = (void *)value;
+    return 0;
+}
+
+/* ------------------------------------------------------------------------- */
+
+int pthread_mutex_init(pthread_mutex_t *m, const void *attr) {
+    m->locked = 0;
+    return 0;
+}
+
+int pthread_mutex_destroy(pthread_mutex_t *m) {
+    return 0;
+}
+
+int pthread_mutex_lock(pthread_mutex_t *m) {
+    while (m->locked) {
+        schedule();
+    }
+    m->locked = 1;
+    return 0;
+}
+
+int pthread_mutex_trylock(pthread_mutex_t *m) {
+    if (m->locked) {
+        return LLGO_EBUSY;
+    }
+    m->locked = 1;
+    return 0;
+}
+
+int pthread_mutex_unlock(pthread_mutex_t *m) {
+    m->locked = 0;
+    return 0;
+}
+
+/* ------------------------------------------------------------------------- */
+
+/* Waiters poll the sequence number of the condition, so that signal wakes
+ * up all of them. Spurious wakeups are allowed by POSIX. */
+
+int pthread_cond_init(pthread_cond_t *c, const void *attr) {
+    c->seq = 0;
+    return 0;
+}
+
+int pthread_cond_destroy(pthread_cond_t *c) {
+    return 0;
+}
+
+int pthread_cond_signal(pthread_cond_t *c) {
+    c->seq++;
+    return 0;
+}
+
+int pthread_cond_broadcast(pthread_cond_t *c) {
+    c->seq++;
+    return 0;
+}
+
+int pthread_cond_wait(pthread_cond_t *c, pthread_mutex_t *m) {
+    unsigned seq = c->seq;
+    pthread_mutex_unlock(m);
+    do {
+        schedule();
+    } while (c->seq == seq);
+    return pthread_mutex_lock(m);
+}
+
+int pthread_cond_timedwait(pthread_cond_t *c, pthread_mutex_t *m, const struct timespec *abstime) {
+    uint64_t deadline = (uint64_t)abstime->tv_sec * 1000000000 + abstime->tv_nsec;
+    unsigned seq = c->seq;
+    int ret = 0;
+    pthread_mutex_unlock(m);
+    do {
+        if (llgo_nanotime() >= deadline) {
+            ret = LLGO_ETIMEDOUT;
+            break;
+        }
+        schedule();
+    } while (c->seq == seq);
+    pthread_mutex_lock(m);
+    return ret;
+}
+
+/* ------------------------------------------------------------------------- */
+
+int pthread_rwlock_init(pthread_rwlock_t *l, const void *attr) {
+    l->readers = 0;
+    l->writer = 0;
+    return 0;
+}
+
+int pthread_rwlock_destroy(pthread_rwlock_t *l) {
+    return 0;
+}
+
+int pthread_rwlock_rdlock(pthread_rwlock_t *l) {
+    while (l->writer) {
+        schedule();
+    }
+    l->readers++;
+    return 0;
+}
+
+int pthread_rwlock_tryrdlock(pthread_rwlock_t *l) {
+    if (l->writer) {
+        return LLGO_EBUSY;
+    }
+    l->readers++;
+    return 0;
+}
+
+int pthread_rwlock_wrlock(pthread_rwlock_t *l) {
+    while (l->writer || l->readers) {
+        schedule();
+    }
+    l->writer = 1;
+    return 0;
+}
+
+int pthread_rwlock_trywrlock(pthread_rwlock_t *l) {
+    if (l->writer || l->readers) {
+        return LLGO_EBUSY;
+    }
+    l->writer = 1;
+    return 0;
+}
+
+int pthread_rwlock_unlock(pthread_rwlock_t *l) {
+    if (l->writer) {
+        l->writer = 0;
+    } else {
+        l->readers--;
+    }
+    return 0;
+}
+
+/* ------------------------------------------------------------------------- */
+
+int pthread_once(pthread_once_t *once, void (*fn)(void)) {
+    if (*once == 0) {
+        *once = 1;
+        fn();
+        *once = 2;
+    }
+    while (*once == 1) {
+        schedule();
+    }
+    return 0;
+}
+
+/* ------------------------------------------------------------------------- */
+
+int usleep(unsigned int usec) {
+    uint64_t deadline = llgo_nanotime() + (uint64_t)usec * 1000;
+    do {
+        schedule();
+    } while (llgo_nanotime() < deadline);
+    return 0;
+}
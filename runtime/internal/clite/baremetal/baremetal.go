@@ -0,0 +1,47 @@
+//go:build baremetal
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package baremetal provides the C runtime used by the baremetal build tag:
+// a cooperative single-core scheduler behind the pthread API, a first-fit
+// allocator, a small stdio and setjmp/longjmp. It needs no libc.
+//
+// Boards provide the hardware abstraction layer declared in
+// _wrap/llgo_hal.h:
+//
+//	void llgo_hal_write(const char *buf, size_t n);
+//	void llgo_hal_exit(int code);
+//
+// and may override the weak defaults of
+//
+//	void llgo_hal_heap(void **start, size_t *size);
+//	uint64_t llgo_hal_nanotime(void);
+package baremetal
+
+import (
+	_ "unsafe"
+)
+
+const (
+	LLGoFiles   = "_wrap/hal.c; _wrap/libc.c; _wrap/sched.c; _wrap/arch.c"
+	LLGoPackage = "link"
+)
+
+// Yield gives up the processor to the next runnable task.
+//
+//go:linkname Yield C.sched_yield
+func Yield() int32
@@ -34,12 +34,56 @@ func Init()
 //go:linkname Malloc C.GC_malloc
 func Malloc(size uintptr) c.Pointer
 
+// MallocAtomic allocates memory which is not scanned for pointers.
+//
+//go:linkname MallocAtomic C.GC_malloc_atomic
+func MallocAtomic(size uintptr) c.Pointer
+
 //go:linkname Realloc C.GC_realloc
 func Realloc(ptr c.Pointer, size uintptr) c.Pointer
 
 //go:linkname Free C.GC_free
 func Free(ptr c.Pointer)
 
+// Base returns the start of the object ptr points into, or nil if ptr does
+// not point into the collected heap.
+//
+//go:linkname Base C.GC_base
+func Base(ptr c.Pointer) c.Pointer
+
+// -----------------------------------------------------------------------------
+
+// Results of the functions registering threads.
+const (
+	SUCCESS   = 0
+	DUPLICATE = 1 // the thread was already registered
+)
+
+// StackBase describes the stack of a thread.
+type StackBase struct {
+	MemBase c.Pointer // the hot end of the stack
+}
+
+// AllowRegisterThreads allows threads not created by GC_pthread_create to
+// register themselves. It is called from the main thread.
+//
+//go:linkname AllowRegisterThreads C.GC_allow_register_threads
+func AllowRegisterThreads()
+
+//go:linkname GetStackBase C.GC_get_stack_base
+func GetStackBase(sb *StackBase) c.Int
+
+// RegisterMyThread makes the collector scan the stack of the calling thread.
+//
+//go:linkname RegisterMyThread C.GC_register_my_thread
+func RegisterMyThread(sb *StackBase) c.Int
+
+// UnregisterMyThread is called by a thread registered by RegisterMyThread
+// before it exits.
+//
+//go:linkname UnregisterMyThread C.GC_unregister_my_thread
+func UnregisterMyThread() c.Int
+
 // -----------------------------------------------------------------------------
 
 //go:linkname RegisterFinalizer C.GC_register_finalizer
@@ -68,6 +112,27 @@ func RegisterFinalizerUnreachable(
 
 // -----------------------------------------------------------------------------
 
+// GeneralRegisterDisappearingLink makes the collector clear *link when obj
+// becomes unreachable. *link must not be seen by the collector as a pointer
+// to obj, e.g. by being in memory allocated by MallocAtomic.
+//
+//go:linkname GeneralRegisterDisappearingLink C.GC_general_register_disappearing_link
+func GeneralRegisterDisappearingLink(link *c.Pointer, obj c.Pointer) c.Int
+
+//go:linkname UnregisterDisappearingLink C.GC_unregister_disappearing_link
+func UnregisterDisappearingLink(link *c.Pointer) c.Int
+
+//llgo:type C
+type FnType func(c.Pointer) c.Pointer
+
+// CallWithAllocLock calls fn(cd) with the allocation lock held, so that no
+// collection runs meanwhile.
+//
+//go:linkname CallWithAllocLock C.GC_call_with_alloc_lock
+func CallWithAllocLock(fn FnType, cd c.Pointer) c.Pointer
+
+// -----------------------------------------------------------------------------
+
 //go:linkname Enable C.GC_enable
 func Enable()
 
@@ -83,6 +148,11 @@ func Gcollect()
 //go:linkname GetMemoryUse C.GC_get_memory_use
 func GetMemoryUse() uintptr
 
+// GetGcNo returns the number of collections completed so far.
+//
+//go:linkname GetGcNo C.GC_get_gc_no
+func GetGcNo() uintptr
+
 // -----------------------------------------------------------------------------
 
 //go:linkname EnableIncremental C.GC_enable_incremental
@@ -101,3 +171,27 @@ func StartIncrementalCollection()
 func CollectALittle()
 
 // -----------------------------------------------------------------------------
+
+// EventType is the type of the events reported to the collection event
+// callback.
+type EventType c.Int
+
+const (
+	EVENT_START EventType = iota
+	EVENT_MARK_START
+	EVENT_MARK_END
+	EVENT_RECLAIM_START
+	EVENT_RECLAIM_END
+	EVENT_END
+	EVENT_PRE_STOP_WORLD
+	EVENT_POST_STOP_WORLD
+	EVENT_PRE_START_WORLD
+	EVENT_POST_START_WORLD
+	EVENT_THREAD_SUSPENDED
+	EVENT_THREAD_UNSUSPENDED
+)
+
+//go:linkname SetOnCollectionEvent C.GC_set_on_collection_event
+func SetOnCollectionEvent(fn func(EventType))
+
+// -----------------------------------------------------------------------------
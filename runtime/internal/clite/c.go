@@ -196,6 +196,12 @@ func GoStringData(string) *Char
 //go:linkname GoDeferData llgo.deferData
 func GoDeferData() Pointer
 
+// ReturnAddress returns the address the calling function returns to, or nil
+// if the target cannot tell.
+//
+//go:linkname ReturnAddress llgo.returnAddress
+func ReturnAddress() Pointer
+
 // -----------------------------------------------------------------------------
 
 //go:linkname AllocaSigjmpBuf llgo.sigjmpbuf
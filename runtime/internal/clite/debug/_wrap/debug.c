@@ -7,7 +7,12 @@
 #endif
 
 #include <dlfcn.h>
+#include <errno.h>
 #include <libunwind.h>
+#include <pthread.h>
+#include <signal.h>
+#include <string.h>
+#include <unistd.h>
 
 void *llgo_address() {
     return __builtin_return_address(0);
@@ -38,4 +43,90 @@ void llgo_stacktrace(int skip, void *ctx, int (*fn)(void *ctx, void *pc, void *o
             }
         }
     }
-}
\ No newline at end of file
+}
+
+// llgo_backtrace stores the pcs of at most max frames of the current call
+// stack into pcs. The local unwinding functions of libunwind it uses are
+// async-signal-safe, so it can be used inside a signal handler.
+int llgo_backtrace(int skip, void **pcs, int max) {
+    unw_cursor_t cursor;
+    unw_context_t context;
+    unw_word_t pc;
+    unw_getcontext(&context);
+    unw_init_local(&cursor, &context);
+    int depth = 0, n = 0;
+    while (n < max && unw_step(&cursor) > 0) {
+        if (depth < skip) {
+            depth++;
+            continue;
+        }
+        if (unw_get_reg(&cursor, UNW_REG_IP, &pc) == 0) {
+            pcs[n++] = (void*)pc;
+        }
+    }
+    return n;
+}
+
+typedef struct {
+    void *pc;
+    void *offset;
+    char name[120];
+} llgo_frame;
+
+// llgo_symbolize stores the name of the function containing pc, and the
+// offset of pc in it, into frame. It returns 0 if the function is not found.
+int llgo_symbolize(void *pc, llgo_frame *frame) {
+    frame->pc = pc;
+    frame->offset = 0;
+    frame->name[0] = '\0';
+#if defined(UNW_VERSION_MAJOR) && (UNW_VERSION_MAJOR > 1 || UNW_VERSION_MINOR >= 6)
+    unw_word_t offset;
+    if (unw_get_proc_name_by_ip(unw_local_addr_space, (unw_word_t)pc, frame->name, sizeof(frame->name), &offset, NULL) == 0) {
+        frame->offset = (void*)offset;
+        return 1;
+    }
+#endif
+    Dl_info info;
+    if (dladdr(pc, &info) == 0 || info.dli_sname == NULL) {
+        return 0;
+    }
+    strncpy(frame->name, info.dli_sname, sizeof(frame->name) - 1);
+    frame->name[sizeof(frame->name) - 1] = '\0';
+    frame->offset = (void*)((char*)pc - (char*)info.dli_saddr);
+    return 1;
+}
+
+static int llgo_sigquit_pipe[2] = {-1, -1};
+
+int llgo_handle_sigquit(void (*fn)(int)) {
+    if (pipe(llgo_sigquit_pipe) != 0) {
+        return -1;
+    }
+    struct sigaction sa;
+    memset(&sa, 0, sizeof(sa));
+    sa.sa_handler = fn;
+    sa.sa_flags = SA_RESTART;
+    sigemptyset(&sa.sa_mask);
+    return sigaction(SIGQUIT, &sa, NULL);
+}
+
+// llgo_notify_sigquit wakes up llgo_wait_sigquit. It only calls write, so it
+// can be used inside a signal handler.
+void llgo_notify_sigquit(void) {
+    char b = 0;
+    write(llgo_sigquit_pipe[1], &b, 1);
+}
+
+// llgo_wait_sigquit blocks until llgo_notify_sigquit is called.
+void llgo_wait_sigquit(void) {
+    char b;
+    while (read(llgo_sigquit_pipe[0], &b, 1) != 1) {
+        if (errno != EINTR) {
+            return;
+        }
+    }
+}
+
+int llgo_send_sigquit(pthread_t th) {
+    return pthread_kill(th, SIGQUIT);
+}
@@ -1,4 +1,4 @@
-//go:build !wasm
+//go:build !wasm && !baremetal
 
 package debug
 
@@ -6,6 +6,7 @@ import (
 	"unsafe"
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
 )
 
 const (
@@ -44,3 +45,64 @@ func StackTrace(skip int, fn func(fr *Frame) bool) {
 		return 1
 	})
 }
+
+// RawFrame is a stack frame symbolized by Symbolize.
+type RawFrame struct {
+	PC     uintptr
+	Offset uintptr
+	Name   [120]c.Char
+}
+
+//go:linkname backtrace C.llgo_backtrace
+func backtrace(skip c.Int, pcs *uintptr, max c.Int) c.Int
+
+// Backtrace stores the return addresses of the current call stack into pcs
+// and returns the number of addresses written. It neither allocates memory
+// nor symbolizes the frames, so it can be called from a signal handler.
+func Backtrace(skip int, pcs []uintptr) int {
+	if len(pcs) == 0 {
+		return 0
+	}
+	return int(backtrace(c.Int(1+skip), &pcs[0], c.Int(len(pcs))))
+}
+
+//go:linkname symbolize C.llgo_symbolize
+func symbolize(pc uintptr, frame *RawFrame) c.Int
+
+// Symbolize stores the name of the function containing pc, and the offset of
+// pc in it, into frame. It reports whether the function was found.
+func Symbolize(pc uintptr, frame *RawFrame) bool {
+	return symbolize(pc, frame) != 0
+}
+
+//llgo:type C
+type SigHandler func(c.Int)
+
+// HandleSigquit installs fn as the handler of SIGQUIT. Signal handlers may
+// only call async-signal-safe functions: fn can call NotifySigquit to have
+// the work done by a thread waiting in WaitSigquit.
+//
+//go:linkname HandleSigquit C.llgo_handle_sigquit
+func HandleSigquit(fn SigHandler) c.Int
+
+// SendSigquit sends SIGQUIT to the thread th.
+//
+//go:linkname SendSigquit C.llgo_send_sigquit
+func SendSigquit(th pthread.Thread) c.Int
+
+// NotifySigquit wakes up the thread blocked in WaitSigquit. It can be called
+// from a signal handler.
+//
+//go:linkname NotifySigquit C.llgo_notify_sigquit
+func NotifySigquit()
+
+// WaitSigquit blocks until NotifySigquit is called.
+//
+//go:linkname WaitSigquit C.llgo_wait_sigquit
+func WaitSigquit()
+
+// Exit terminates the process immediately, without flushing stdio buffers or
+// running atexit handlers.
+//
+//go:linkname Exit C._exit
+func Exit(code c.Int)
@@ -0,0 +1,74 @@
+//go:build baremetal
+
+package debug
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+)
+
+const (
+	LLGoPackage = "link"
+)
+
+type Info struct {
+	Fname *c.Char
+	Fbase c.Pointer
+	Sname *c.Char
+	Saddr c.Pointer
+}
+
+func Address() unsafe.Pointer {
+	return nil
+}
+
+func Addrinfo(addr unsafe.Pointer, info *Info) c.Int {
+	return 0
+}
+
+type Frame struct {
+	PC     uintptr
+	Offset uintptr
+	SP     unsafe.Pointer
+	Name   string
+}
+
+// StackTrace reports no frames: bare-metal builds carry no unwinder.
+func StackTrace(skip int, fn func(fr *Frame) bool) {
+}
+
+type RawFrame struct {
+	PC     uintptr
+	Offset uintptr
+	Name   [120]c.Char
+}
+
+func Backtrace(skip int, pcs []uintptr) int {
+	return 0
+}
+
+func Symbolize(pc uintptr, frame *RawFrame) bool {
+	return false
+}
+
+type SigHandler func(c.Int)
+
+func HandleSigquit(fn SigHandler) c.Int {
+	return -1
+}
+
+func SendSigquit(th pthread.Thread) c.Int {
+	return -1
+}
+
+func NotifySigquit() {
+}
+
+func WaitSigquit() {
+}
+
+func Exit(code c.Int) {
+	c.Exit(code)
+}
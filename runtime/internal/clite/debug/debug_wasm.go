@@ -4,6 +4,7 @@ import (
 	"unsafe"
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
 )
 
 type Info struct {
@@ -31,3 +32,37 @@ type Frame struct {
 func StackTrace(skip int, fn func(fr *Frame) bool) {
 	panic("not implemented")
 }
+
+type RawFrame struct {
+	PC     uintptr
+	Offset uintptr
+	Name   [120]c.Char
+}
+
+func Backtrace(skip int, pcs []uintptr) int {
+	return 0
+}
+
+func Symbolize(pc uintptr, frame *RawFrame) bool {
+	return false
+}
+
+type SigHandler func(c.Int)
+
+func HandleSigquit(fn SigHandler) c.Int {
+	return -1
+}
+
+func SendSigquit(th pthread.Thread) c.Int {
+	return -1
+}
+
+func NotifySigquit() {
+}
+
+func WaitSigquit() {
+}
+
+func Exit(code c.Int) {
+	c.Exit(code)
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openssl
+
+import (
+	_ "unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	AES_ENCRYPT = 1
+	AES_DECRYPT = 0
+
+	AES_MAXNR      = 14
+	AES_BLOCK_SIZE = 16
+)
+
+type AES_KEY struct {
+	RdKey  [4 * (AES_MAXNR + 1)]c.Uint
+	Rounds c.Int
+}
+
+// OSSL_DEPRECATEDIN_3_0
+// int AES_set_encrypt_key(const unsigned char *userKey, const int bits, AES_KEY *key);
+//
+//go:linkname AESSetEncryptKey C.AES_set_encrypt_key
+func AESSetEncryptKey(userKey *byte, bits c.Int, key *AES_KEY) c.Int
+
+// OSSL_DEPRECATEDIN_3_0
+// int AES_set_decrypt_key(const unsigned char *userKey, const int bits, AES_KEY *key);
+//
+//go:linkname AESSetDecryptKey C.AES_set_decrypt_key
+func AESSetDecryptKey(userKey *byte, bits c.Int, key *AES_KEY) c.Int
+
+// OSSL_DEPRECATEDIN_3_0
+// void AES_encrypt(const unsigned char *in, unsigned char *out, const AES_KEY *key);
+//
+//go:linkname AESEncrypt C.AES_encrypt
+func AESEncrypt(in, out *byte, key *AES_KEY)
+
+// OSSL_DEPRECATEDIN_3_0
+// void AES_decrypt(const unsigned char *in, unsigned char *out, const AES_KEY *key);
+//
+//go:linkname AESDecrypt C.AES_decrypt
+func AESDecrypt(in, out *byte, key *AES_KEY)
+
+// -----------------------------------------------------------------------------
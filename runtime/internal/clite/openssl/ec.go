@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openssl
+
+import (
+	_ "unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	NID_secp224r1        = 713
+	NID_X9_62_prime256v1 = 415
+	NID_secp384r1        = 715
+	NID_secp521r1        = 716
+)
+
+type EC_KEY struct {
+	Unused [0]byte
+}
+
+// OSSL_DEPRECATEDIN_3_0 EC_KEY *EC_KEY_new_by_curve_name(int nid);
+//
+//go:linkname EC_KEYNewByCurveName C.EC_KEY_new_by_curve_name
+func EC_KEYNewByCurveName(nid c.Int) *EC_KEY
+
+// OSSL_DEPRECATEDIN_3_0 void EC_KEY_free(EC_KEY *key);
+//
+// llgo:link (*EC_KEY).Free C.EC_KEY_free
+func (*EC_KEY) Free() {}
+
+// OSSL_DEPRECATEDIN_3_0 int EC_KEY_set_private_key(EC_KEY *key, const BIGNUM *prv);
+//
+// llgo:link (*EC_KEY).SetPrivateKey C.EC_KEY_set_private_key
+func (*EC_KEY) SetPrivateKey(prv *BIGNUM) c.Int { return 0 }
+
+// OSSL_DEPRECATEDIN_3_0
+// int EC_KEY_set_public_key_affine_coordinates(EC_KEY *key, BIGNUM *x, BIGNUM *y);
+//
+// llgo:link (*EC_KEY).SetPublicKeyAffineCoordinates C.EC_KEY_set_public_key_affine_coordinates
+func (*EC_KEY) SetPublicKeyAffineCoordinates(x, y *BIGNUM) c.Int { return 0 }
+
+// -----------------------------------------------------------------------------
+
+// OSSL_DEPRECATEDIN_3_0 int ECDSA_size(const EC_KEY *eckey);
+//
+//go:linkname ECDSASize C.ECDSA_size
+func ECDSASize(eckey *EC_KEY) c.Int
+
+// OSSL_DEPRECATEDIN_3_0
+// int ECDSA_sign(int type, const unsigned char *dgst, int dgstlen, unsigned char *sig, unsigned int *siglen, EC_KEY *eckey);
+//
+//go:linkname ECDSASign C.ECDSA_sign
+func ECDSASign(typ c.Int, dgst *byte, dgstlen c.Int, sig *byte, siglen *c.Uint, eckey *EC_KEY) c.Int
+
+// OSSL_DEPRECATEDIN_3_0
+// int ECDSA_verify(int type, const unsigned char *dgst, int dgstlen, const unsigned char *sig, int siglen, EC_KEY *eckey);
+//
+//go:linkname ECDSAVerify C.ECDSA_verify
+func ECDSAVerify(typ c.Int, dgst *byte, dgstlen c.Int, sig *byte, siglen c.Int, eckey *EC_KEY) c.Int
+
+// -----------------------------------------------------------------------------
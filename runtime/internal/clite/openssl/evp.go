@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openssl
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+)
+
+// -----------------------------------------------------------------------------
+
+type EVP_CIPHER struct {
+	Unused [0]byte
+}
+
+// const EVP_CIPHER *EVP_aes_128_gcm(void);
+//
+//go:linkname EVPAes128Gcm C.EVP_aes_128_gcm
+func EVPAes128Gcm() *EVP_CIPHER
+
+// const EVP_CIPHER *EVP_aes_192_gcm(void);
+//
+//go:linkname EVPAes192Gcm C.EVP_aes_192_gcm
+func EVPAes192Gcm() *EVP_CIPHER
+
+// const EVP_CIPHER *EVP_aes_256_gcm(void);
+//
+//go:linkname EVPAes256Gcm C.EVP_aes_256_gcm
+func EVPAes256Gcm() *EVP_CIPHER
+
+// -----------------------------------------------------------------------------
+
+const (
+	EVP_CTRL_AEAD_SET_IVLEN = 0x9
+	EVP_CTRL_AEAD_GET_TAG   = 0x10
+	EVP_CTRL_AEAD_SET_TAG   = 0x11
+)
+
+type EVP_CIPHER_CTX struct {
+	Unused [0]byte
+}
+
+// EVP_CIPHER_CTX *EVP_CIPHER_CTX_new(void);
+//
+//go:linkname EVP_CIPHER_CTXNew C.EVP_CIPHER_CTX_new
+func EVP_CIPHER_CTXNew() *EVP_CIPHER_CTX
+
+// void EVP_CIPHER_CTX_free(EVP_CIPHER_CTX *c);
+//
+// llgo:link (*EVP_CIPHER_CTX).Free C.EVP_CIPHER_CTX_free
+func (*EVP_CIPHER_CTX) Free() {}
+
+// int EVP_CIPHER_CTX_ctrl(EVP_CIPHER_CTX *ctx, int type, int arg, void *ptr);
+//
+// llgo:link (*EVP_CIPHER_CTX).Ctrl C.EVP_CIPHER_CTX_ctrl
+func (*EVP_CIPHER_CTX) Ctrl(typ, arg c.Int, ptr unsafe.Pointer) c.Int { return 0 }
+
+// int EVP_EncryptInit_ex(EVP_CIPHER_CTX *ctx, const EVP_CIPHER *cipher, ENGINE *impl, const unsigned char *key, const unsigned char *iv);
+//
+// llgo:link (*EVP_CIPHER_CTX).EncryptInit C.EVP_EncryptInit_ex
+func (*EVP_CIPHER_CTX) EncryptInit(cipher *EVP_CIPHER, impl unsafe.Pointer, key, iv *byte) c.Int {
+	return 0
+}
+
+// int EVP_EncryptUpdate(EVP_CIPHER_CTX *ctx, unsigned char *out, int *outl, const unsigned char *in, int inl);
+//
+// llgo:link (*EVP_CIPHER_CTX).EncryptUpdate C.EVP_EncryptUpdate
+func (*EVP_CIPHER_CTX) EncryptUpdate(out *byte, outl *c.Int, in *byte, inl c.Int) c.Int { return 0 }
+
+// int EVP_EncryptFinal_ex(EVP_CIPHER_CTX *ctx, unsigned char *out, int *outl);
+//
+// llgo:link (*EVP_CIPHER_CTX).EncryptFinal C.EVP_EncryptFinal_ex
+func (*EVP_CIPHER_CTX) EncryptFinal(out *byte, outl *c.Int) c.Int { return 0 }
+
+// int EVP_DecryptInit_ex(EVP_CIPHER_CTX *ctx, const EVP_CIPHER *cipher, ENGINE *impl, const unsigned char *key, const unsigned char *iv);
+//
+// llgo:link (*EVP_CIPHER_CTX).DecryptInit C.EVP_DecryptInit_ex
+func (*EVP_CIPHER_CTX) DecryptInit(cipher *EVP_CIPHER, impl unsafe.Pointer, key, iv *byte) c.Int {
+	return 0
+}
+
+// int EVP_DecryptUpdate(EVP_CIPHER_CTX *ctx, unsigned char *out, int *outl, const unsigned char *in, int inl);
+//
+// llgo:link (*EVP_CIPHER_CTX).DecryptUpdate C.EVP_DecryptUpdate
+func (*EVP_CIPHER_CTX) DecryptUpdate(out *byte, outl *c.Int, in *byte, inl c.Int) c.Int { return 0 }
+
+// int EVP_DecryptFinal_ex(EVP_CIPHER_CTX *ctx, unsigned char *outm, int *outl);
+//
+// llgo:link (*EVP_CIPHER_CTX).DecryptFinal C.EVP_DecryptFinal_ex
+func (*EVP_CIPHER_CTX) DecryptFinal(outm *byte, outl *c.Int) c.Int { return 0 }
+
+// -----------------------------------------------------------------------------
+
+const (
+	EVP_PKEY_ED25519 = 1087 // NID_ED25519
+)
+
+type EVP_PKEY struct {
+	Unused [0]byte
+}
+
+// EVP_PKEY *EVP_PKEY_new_raw_private_key(int type, ENGINE *e, const unsigned char *priv, size_t len);
+//
+//go:linkname EVP_PKEYNewRawPrivateKey C.EVP_PKEY_new_raw_private_key
+func EVP_PKEYNewRawPrivateKey(typ c.Int, e unsafe.Pointer, priv *byte, len uintptr) *EVP_PKEY
+
+// EVP_PKEY *EVP_PKEY_new_raw_public_key(int type, ENGINE *e, const unsigned char *pub, size_t len);
+//
+//go:linkname EVP_PKEYNewRawPublicKey C.EVP_PKEY_new_raw_public_key
+func EVP_PKEYNewRawPublicKey(typ c.Int, e unsafe.Pointer, pub *byte, len uintptr) *EVP_PKEY
+
+// int EVP_PKEY_get_raw_public_key(const EVP_PKEY *pkey, unsigned char *pub, size_t *len);
+//
+// llgo:link (*EVP_PKEY).GetRawPublicKey C.EVP_PKEY_get_raw_public_key
+func (*EVP_PKEY) GetRawPublicKey(pub *byte, len *uintptr) c.Int { return 0 }
+
+// void EVP_PKEY_free(EVP_PKEY *key);
+//
+// llgo:link (*EVP_PKEY).Free C.EVP_PKEY_free
+func (*EVP_PKEY) Free() {}
+
+// -----------------------------------------------------------------------------
+
+type EVP_MD_CTX struct {
+	Unused [0]byte
+}
+
+// EVP_MD_CTX *EVP_MD_CTX_new(void);
+//
+//go:linkname EVP_MD_CTXNew C.EVP_MD_CTX_new
+func EVP_MD_CTXNew() *EVP_MD_CTX
+
+// void EVP_MD_CTX_free(EVP_MD_CTX *ctx);
+//
+// llgo:link (*EVP_MD_CTX).Free C.EVP_MD_CTX_free
+func (*EVP_MD_CTX) Free() {}
+
+// int EVP_DigestSignInit(EVP_MD_CTX *ctx, EVP_PKEY_CTX **pctx, const EVP_MD *type, ENGINE *e, EVP_PKEY *pkey);
+//
+// llgo:link (*EVP_MD_CTX).DigestSignInit C.EVP_DigestSignInit
+func (*EVP_MD_CTX) DigestSignInit(pctx, typ, e unsafe.Pointer, pkey *EVP_PKEY) c.Int { return 0 }
+
+// int EVP_DigestSign(EVP_MD_CTX *ctx, unsigned char *sigret, size_t *siglen, const unsigned char *tbs, size_t tbslen);
+//
+// llgo:link (*EVP_MD_CTX).DigestSign C.EVP_DigestSign
+func (*EVP_MD_CTX) DigestSign(sigret *byte, siglen *uintptr, tbs *byte, tbslen uintptr) c.Int {
+	return 0
+}
+
+// int EVP_DigestVerifyInit(EVP_MD_CTX *ctx, EVP_PKEY_CTX **pctx, const EVP_MD *type, ENGINE *e, EVP_PKEY *pkey);
+//
+// llgo:link (*EVP_MD_CTX).DigestVerifyInit C.EVP_DigestVerifyInit
+func (*EVP_MD_CTX) DigestVerifyInit(pctx, typ, e unsafe.Pointer, pkey *EVP_PKEY) c.Int { return 0 }
+
+// int EVP_DigestVerify(EVP_MD_CTX *ctx, const unsigned char *sigret, size_t siglen, const unsigned char *tbs, size_t tbslen);
+//
+// llgo:link (*EVP_MD_CTX).DigestVerify C.EVP_DigestVerify
+func (*EVP_MD_CTX) DigestVerify(sigret *byte, siglen uintptr, tbs *byte, tbslen uintptr) c.Int {
+	return 0
+}
+
+// -----------------------------------------------------------------------------
@@ -257,10 +257,6 @@ func (c *Cond) Broadcast() c.Int {
 	return c_pthread_cond_broadcast(c)
 }
 
-func (c *Cond) Wait(m *Mutex) c.Int {
-	return c_pthread_cond_wait(c, m)
-}
-
 func (c *Cond) TimedWait(m *Mutex, abstime *time.Timespec) c.Int {
 	return c_pthread_cond_timedwait(c, m, abstime)
 }
@@ -0,0 +1,32 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sync
+
+import (
+	c "github.com/goplus/llgo/runtime/internal/clite"
+)
+
+// Wait reports a fatal error on js. A program in a browser has a single
+// thread, so a condition it waits for can only be signaled by a JavaScript
+// callback, and callbacks run only once the program has given control back
+// to the event loop, which would drop the frames of the waiting code. Only
+// select {}, which never resumes, gives control back to the event loop.
+func (cond *Cond) Wait(m *Mutex) c.Int {
+	c.Fputs(c.Str("fatal error: blocking operation on js/wasm, only select {} can wait for callbacks\n"), c.Stderr)
+	c.Exit(2)
+	return 0
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aes
+
+import (
+	"crypto/cipher"
+	"errors"
+	"strconv"
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/openssl"
+)
+
+// llgo:skipall
+type _aes struct{}
+
+// The AES block size in bytes.
+const BlockSize = 16
+
+type KeySizeError int
+
+func (k KeySizeError) Error() string {
+	return "crypto/aes: invalid key size " + strconv.Itoa(int(k))
+}
+
+// NewCipher creates and returns a new [cipher.Block].
+// The key argument must be the AES key,
+// either 16, 24, or 32 bytes to select
+// AES-128, AES-192, or AES-256.
+func NewCipher(key []byte) (cipher.Block, error) {
+	k := len(key)
+	switch k {
+	default:
+		return nil, KeySizeError(k)
+	case 16, 24, 32:
+		break
+	}
+	b := &aesCipher{key: append([]byte(nil), key...)}
+	bits := c.Int(k * 8)
+	openssl.AESSetEncryptKey(unsafe.SliceData(key), bits, &b.enc)
+	openssl.AESSetDecryptKey(unsafe.SliceData(key), bits, &b.dec)
+	return b, nil
+}
+
+type aesCipher struct {
+	key []byte
+	enc openssl.AES_KEY
+	dec openssl.AES_KEY
+}
+
+func (b *aesCipher) BlockSize() int { return BlockSize }
+
+func (b *aesCipher) Encrypt(dst, src []byte) {
+	if len(src) < BlockSize {
+		panic("crypto/aes: input not full block")
+	}
+	if len(dst) < BlockSize {
+		panic("crypto/aes: output not full block")
+	}
+	openssl.AESEncrypt(unsafe.SliceData(src), unsafe.SliceData(dst), &b.enc)
+}
+
+func (b *aesCipher) Decrypt(dst, src []byte) {
+	if len(src) < BlockSize {
+		panic("crypto/aes: input not full block")
+	}
+	if len(dst) < BlockSize {
+		panic("crypto/aes: output not full block")
+	}
+	openssl.AESDecrypt(unsafe.SliceData(src), unsafe.SliceData(dst), &b.dec)
+}
+
+// NewGCM is called by cipher.NewGCM and friends, so that AES-GCM is done
+// by OpenSSL rather than by the generic GCM of crypto/cipher.
+func (b *aesCipher) NewGCM(nonceSize, tagSize int) (cipher.AEAD, error) {
+	if tagSize < gcmMinimumTagSize || tagSize > gcmBlockSize {
+		return nil, errors.New("cipher: incorrect tag size given to GCM")
+	}
+	if nonceSize <= 0 {
+		return nil, errors.New("cipher: the nonce can't have zero length")
+	}
+	var cipher *openssl.EVP_CIPHER
+	switch len(b.key) {
+	case 16:
+		cipher = openssl.EVPAes128Gcm()
+	case 24:
+		cipher = openssl.EVPAes192Gcm()
+	default:
+		cipher = openssl.EVPAes256Gcm()
+	}
+	return &aesGCM{cipher: cipher, key: b.key, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+const (
+	gcmBlockSize      = 16
+	gcmMinimumTagSize = 12
+)
+
+var errOpen = errors.New("cipher: message authentication failed")
+
+type aesGCM struct {
+	cipher    *openssl.EVP_CIPHER
+	key       []byte
+	nonceSize int
+	tagSize   int
+}
+
+func (g *aesGCM) NonceSize() int { return g.nonceSize }
+
+func (g *aesGCM) Overhead() int { return g.tagSize }
+
+func (g *aesGCM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != g.nonceSize {
+		panic("crypto/cipher: incorrect nonce length given to GCM")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+g.tagSize)
+	ctx := openssl.EVP_CIPHER_CTXNew()
+	defer ctx.Free()
+	var n c.Int
+	if ctx.EncryptInit(g.cipher, nil, nil, nil) != 1 ||
+		ctx.Ctrl(openssl.EVP_CTRL_AEAD_SET_IVLEN, c.Int(g.nonceSize), nil) != 1 ||
+		ctx.EncryptInit(nil, nil, unsafe.SliceData(g.key), unsafe.SliceData(nonce)) != 1 ||
+		ctx.EncryptUpdate(nil, &n, unsafe.SliceData(additionalData), c.Int(len(additionalData))) != 1 ||
+		ctx.EncryptUpdate(unsafe.SliceData(out), &n, unsafe.SliceData(plaintext), c.Int(len(plaintext))) != 1 ||
+		ctx.EncryptFinal(unsafe.SliceData(out), &n) != 1 ||
+		ctx.Ctrl(openssl.EVP_CTRL_AEAD_GET_TAG, c.Int(g.tagSize), unsafe.Pointer(&out[len(plaintext)])) != 1 {
+		panic("crypto/aes: OpenSSL GCM encryption failed")
+	}
+	return ret
+}
+
+func (g *aesGCM) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != g.nonceSize {
+		panic("crypto/cipher: incorrect nonce length given to GCM")
+	}
+	if len(ciphertext) < g.tagSize {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-g.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-g.tagSize]
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	ctx := openssl.EVP_CIPHER_CTXNew()
+	defer ctx.Free()
+	var n c.Int
+	var scratch [gcmBlockSize]byte
+	if ctx.DecryptInit(g.cipher, nil, nil, nil) != 1 ||
+		ctx.Ctrl(openssl.EVP_CTRL_AEAD_SET_IVLEN, c.Int(g.nonceSize), nil) != 1 ||
+		ctx.DecryptInit(nil, nil, unsafe.SliceData(g.key), unsafe.SliceData(nonce)) != 1 ||
+		ctx.DecryptUpdate(nil, &n, unsafe.SliceData(additionalData), c.Int(len(additionalData))) != 1 ||
+		ctx.DecryptUpdate(unsafe.SliceData(out), &n, unsafe.SliceData(ciphertext), c.Int(len(ciphertext))) != 1 ||
+		ctx.Ctrl(openssl.EVP_CTRL_AEAD_SET_TAG, c.Int(g.tagSize), unsafe.Pointer(unsafe.SliceData(tag))) != 1 ||
+		ctx.DecryptFinal(&scratch[0], &n) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and a
+// second slice that aliases into it and contains only the extra bytes. If the
+// original slice has sufficient capacity then no allocation is performed.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,219 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ecdsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/openssl"
+)
+
+// llgo:skip SignASN1 VerifyASN1
+type _ecdsa struct{}
+
+func curveNID(name string) c.Int {
+	switch name {
+	case "P-224":
+		return openssl.NID_secp224r1
+	case "P-256":
+		return openssl.NID_X9_62_prime256v1
+	case "P-384":
+		return openssl.NID_secp384r1
+	case "P-521":
+		return openssl.NID_secp521r1
+	}
+	return 0
+}
+
+func bigToBN(x *big.Int) *openssl.BIGNUM {
+	b := x.Bytes()
+	return openssl.BNBin2bn(unsafe.SliceData(b), c.Int(len(b)), nil)
+}
+
+// newKey returns an OpenSSL key for pub, and for d if it is not nil.
+func newKey(pub *ecdsa.PublicKey, d *big.Int) *openssl.EC_KEY {
+	nid := curveNID(pub.Curve.Params().Name)
+	if nid == 0 {
+		return nil
+	}
+	key := openssl.EC_KEYNewByCurveName(nid)
+	if key == nil {
+		return nil
+	}
+	x, y := bigToBN(pub.X), bigToBN(pub.Y)
+	defer x.Free()
+	defer y.Free()
+	ok := key.SetPublicKeyAffineCoordinates(x, y) == 1
+	if ok && d != nil {
+		bd := bigToBN(d)
+		ok = key.SetPrivateKey(bd) == 1
+		bd.ClearFree()
+	}
+	if !ok {
+		key.Free()
+		return nil
+	}
+	return key
+}
+
+// SignASN1 signs a hash (which should be the result of hashing a larger message)
+// using the private key, priv. If the hash is longer than the bit-length of the
+// private key's curve order, the hash will be truncated to that length. It
+// returns the ASN.1 encoded signature.
+//
+// Signatures on the NIST curves are computed by OpenSSL, which uses its own
+// random source: like the upstream package, the signature does not depend on
+// rand. Other curves use the generic implementation, which reads rand.
+func SignASN1(rand io.Reader, priv *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	if curveNID(priv.Curve.Params().Name) == 0 {
+		return signGeneric(rand, priv, hash)
+	}
+	key := newKey(&priv.PublicKey, priv.D)
+	if key == nil {
+		return nil, errors.New("crypto/ecdsa: invalid private key")
+	}
+	defer key.Free()
+	sig := make([]byte, openssl.ECDSASize(key))
+	var n c.Uint
+	if openssl.ECDSASign(0, unsafe.SliceData(hash), c.Int(len(hash)), &sig[0], &n, key) != 1 {
+		return nil, errors.New("crypto/ecdsa: OpenSSL failed to sign the hash")
+	}
+	return sig[:n], nil
+}
+
+// VerifyASN1 verifies the ASN.1 encoded signature, sig, of hash using the
+// public key, pub. Its return value records whether the signature is valid.
+func VerifyASN1(pub *ecdsa.PublicKey, hash, sig []byte) bool {
+	if len(sig) == 0 {
+		return false
+	}
+	if curveNID(pub.Curve.Params().Name) == 0 {
+		return verifyGeneric(pub, hash, sig)
+	}
+	key := newKey(pub, nil)
+	if key == nil {
+		return false
+	}
+	defer key.Free()
+	return openssl.ECDSAVerify(0, unsafe.SliceData(hash), c.Int(len(hash)), &sig[0], c.Int(len(sig)), key) == 1
+}
+
+// -----------------------------------------------------------------------------
+
+// The generic implementation works on any elliptic.Curve with math/big, like
+// the legacy code path of the upstream package. It is not constant time.
+
+type signature struct {
+	R, S *big.Int
+}
+
+func signGeneric(rand io.Reader, priv *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	c := priv.Curve
+	N := c.Params().N
+	if N.Sign() == 0 {
+		return nil, errors.New("crypto/ecdsa: zero parameter")
+	}
+	var r, s *big.Int
+	for {
+		k, err := randFieldElement(c, rand)
+		if err != nil {
+			return nil, err
+		}
+		kInv := new(big.Int).ModInverse(k, N)
+		r, _ = c.ScalarBaseMult(k.Bytes())
+		r.Mod(r, N)
+		if r.Sign() == 0 {
+			continue
+		}
+		s = hashToInt(hash, c)
+		s.Add(s, new(big.Int).Mul(priv.D, r))
+		s.Mul(s, kInv)
+		s.Mod(s, N)
+		if s.Sign() != 0 {
+			break
+		}
+	}
+	return asn1.Marshal(signature{r, s})
+}
+
+func verifyGeneric(pub *ecdsa.PublicKey, hash, sig []byte) bool {
+	var rs signature
+	if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) != 0 {
+		return false
+	}
+	c := pub.Curve
+	N := c.Params().N
+	r, s := rs.R, rs.S
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(N) >= 0 || s.Cmp(N) >= 0 {
+		return false
+	}
+	e := hashToInt(hash, c)
+	w := new(big.Int).ModInverse(s, N)
+	u1 := e.Mul(e, w)
+	u1.Mod(u1, N)
+	u2 := w.Mul(r, w)
+	u2.Mod(u2, N)
+	x1, y1 := c.ScalarBaseMult(u1.Bytes())
+	x2, y2 := c.ScalarMult(pub.X, pub.Y, u2.Bytes())
+	x, y := c.Add(x1, y1, x2, y2)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return false
+	}
+	x.Mod(x, N)
+	return x.Cmp(r) == 0
+}
+
+// hashToInt converts a hash value to an integer. Per FIPS 186-4, Section 6.4,
+// we use the left-most bits of the hash to match the bit-length of the order
+// of the curve.
+func hashToInt(hash []byte, c elliptic.Curve) *big.Int {
+	orderBits := c.Params().N.BitLen()
+	orderBytes := (orderBits + 7) / 8
+	if len(hash) > orderBytes {
+		hash = hash[:orderBytes]
+	}
+	ret := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - orderBits; excess > 0 {
+		ret.Rsh(ret, uint(excess))
+	}
+	return ret
+}
+
+// randFieldElement returns a random element of the order of the given curve.
+func randFieldElement(c elliptic.Curve, rand io.Reader) (k *big.Int, err error) {
+	N := c.Params().N
+	b := make([]byte, (N.BitLen()+7)/8)
+	for {
+		if _, err = io.ReadFull(rand, b); err != nil {
+			return
+		}
+		if excess := len(b)*8 - N.BitLen(); excess > 0 {
+			b[0] >>= excess
+		}
+		k = new(big.Int).SetBytes(b)
+		if k.Sign() != 0 && k.Cmp(N) < 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ed25519
+
+import (
+	"crypto/ed25519"
+	"strconv"
+	"unsafe"
+
+	"github.com/goplus/llgo/runtime/internal/clite/openssl"
+)
+
+// Only pure Ed25519 is bound to OpenSSL. PrivateKey.Sign and
+// VerifyWithOptions, which also implement Ed25519ph and Ed25519ctx, are the
+// upstream ones.
+//
+// llgo:skip Sign Verify
+type _ed25519 struct{}
+
+// Sign signs the message with privateKey and returns a signature. It will
+// panic if len(privateKey) is not [PrivateKeySize].
+func Sign(privateKey ed25519.PrivateKey, message []byte) []byte {
+	if l := len(privateKey); l != ed25519.PrivateKeySize {
+		panic("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+	pkey := openssl.EVP_PKEYNewRawPrivateKey(openssl.EVP_PKEY_ED25519, nil, unsafe.SliceData(privateKey), ed25519.SeedSize)
+	if pkey == nil {
+		panic("ed25519: OpenSSL failed to load the private key")
+	}
+	defer pkey.Free()
+	ctx := openssl.EVP_MD_CTXNew()
+	defer ctx.Free()
+	signature := make([]byte, ed25519.SignatureSize)
+	n := uintptr(ed25519.SignatureSize)
+	if ctx.DigestSignInit(nil, nil, nil, pkey) != 1 ||
+		ctx.DigestSign(&signature[0], &n, unsafe.SliceData(message), uintptr(len(message))) != 1 {
+		panic("ed25519: OpenSSL failed to sign the message")
+	}
+	return signature
+}
+
+// Verify reports whether sig is a valid signature of message by publicKey. It
+// will panic if len(publicKey) is not [PublicKeySize].
+func Verify(publicKey ed25519.PublicKey, message, sig []byte) bool {
+	if l := len(publicKey); l != ed25519.PublicKeySize {
+		panic("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	pkey := openssl.EVP_PKEYNewRawPublicKey(openssl.EVP_PKEY_ED25519, nil, unsafe.SliceData(publicKey), ed25519.PublicKeySize)
+	if pkey == nil {
+		return false
+	}
+	defer pkey.Free()
+	ctx := openssl.EVP_MD_CTXNew()
+	defer ctx.Free()
+	return ctx.DigestVerifyInit(nil, nil, nil, pkey) == 1 &&
+		ctx.DigestVerify(&sig[0], ed25519.SignatureSize, unsafe.SliceData(message), uintptr(len(message))) == 1
+}
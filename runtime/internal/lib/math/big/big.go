@@ -0,0 +1,24 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// By default math/big is compiled from the upstream sources, using their
+// pure Go arithmetic (llgo builds with the math_big_pure_go tag), so Int,
+// Rat and Float are all available and no C library is linked.
+//
+// Building with the llgo_big_openssl tag replaces the package with an Int
+// backed by OpenSSL's BIGNUM instead. It links libcrypto and provides
+// neither Rat nor Float.
+package big
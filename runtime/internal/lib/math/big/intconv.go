@@ -1,3 +1,5 @@
+//go:build llgo_big_openssl
+
 /*
  * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
  *
@@ -12,6 +12,7 @@ import (
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
 	"github.com/goplus/llgo/runtime/internal/clite/os"
+	"github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 // Getpagesize returns the underlying system's memory page size.
@@ -29,7 +30,9 @@ type File struct {
 // write writes len(b) bytes to the File.
 // It returns the number of bytes written and an error, if any.
 func (f *File) write(b []byte) (int, error) {
+	runtime.EnterSyscall()
 	ret := os.Write(c.Int(f.fd), unsafe.Pointer(unsafe.SliceData(b)), uintptr(len(b)))
+	runtime.ExitSyscall()
 	if ret >= 0 {
 		return int(ret), nil
 	}
@@ -49,7 +52,9 @@ func (f *File) write(b []byte) (n int, err error) {
 // read reads up to len(b) bytes from the File.
 // It returns the number of bytes read and an error, if any.
 func (f *File) read(b []byte) (int, error) {
+	runtime.EnterSyscall()
 	ret := os.Read(c.Int(f.fd), unsafe.Pointer(unsafe.SliceData(b)), uintptr(len(b)))
+	runtime.ExitSyscall()
 	if ret > 0 {
 		return int(ret), nil
 	}
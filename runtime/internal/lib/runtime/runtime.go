@@ -49,6 +49,11 @@ func Goexit() {
 	runtime.Goexit()
 }
 
+// NumGoroutine returns the number of goroutines that currently exist.
+func NumGoroutine() int {
+	return runtime.NumGoroutine()
+}
+
 func KeepAlive(x any) {
 }
 
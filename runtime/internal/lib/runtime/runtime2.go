@@ -4,7 +4,11 @@
 
 package runtime
 
-import "runtime"
+import (
+	"runtime"
+
+	rt "github.com/goplus/llgo/runtime/internal/runtime"
+)
 
 // Layout of in-memory per-function information prepared by linker
 // See https://golang.org/s/go12symtab.
@@ -14,20 +18,32 @@ type _func struct {
 	unused [8]byte
 }
 
+// Stack formats a stack trace of the calling goroutine into buf
+// and returns the number of bytes written to buf.
+// If all is true, Stack formats stack traces of all other goroutines
+// into buf after the trace for the current goroutine.
 func Stack(buf []byte, all bool) int {
-	panic("todo: runtime.Stack")
+	return rt.Stack(buf, all)
 }
 
+// StartTrace enables tracing for the current process. The trace is encoded
+// in the Chrome Trace Event Format rather than in the format of the gc
+// toolchain.
 func StartTrace() error {
-	panic("todo: runtime.StartTrace")
+	return rt.StartTrace()
 }
 
+// ReadTrace returns the next chunk of tracing data, blocking until data
+// is available. If tracing is turned off and all the data accumulated
+// while it was on has been returned, ReadTrace returns nil.
 func ReadTrace() []byte {
-	panic("todo: runtime.ReadTrace")
+	return rt.ReadTrace()
 }
 
+// StopTrace stops tracing, if it was previously enabled.
+// StopTrace only returns after all the reads for the trace have completed.
 func StopTrace() {
-	panic("todo: runtime.StopTrace")
+	rt.StopTrace()
 }
 
 func ReadMemStats(m *runtime.MemStats) {
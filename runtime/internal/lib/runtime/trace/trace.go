@@ -1,5 +1,25 @@
 package trace
 
+import (
+	"github.com/goplus/llgo/runtime/internal/runtime"
+)
+
+func userTaskCreate(id, parentID uint64, taskType string) {
+	runtime.TraceUserEvent("task create", id, taskType, "")
+}
+
 func userTaskEnd(id uint64) {
-	panic("todo: runtime/trace.userTaskEnd")
+	runtime.TraceUserEvent("task end", id, "", "")
+}
+
+func userRegion(id, mode uint64, regionType string) {
+	if mode == 0 {
+		runtime.TraceUserEvent("region start", id, regionType, "")
+	} else {
+		runtime.TraceUserEvent("region end", id, regionType, "")
+	}
+}
+
+func userLog(id uint64, category, message string) {
+	runtime.TraceUserEvent("log", id, category, message)
 }
@@ -19,11 +19,12 @@ package sync
 import (
 	"runtime"
 	gosync "sync"
-	_ "unsafe"
+	"unsafe"
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
 	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
 	"github.com/goplus/llgo/runtime/internal/lib/sync/atomic"
+	rt "github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 // llgo:skipall
@@ -56,7 +57,14 @@ func (m *Mutex) ensureInit() {
 
 func (m *Mutex) Lock() {
 	m.ensureInit()
-	(*sync.Mutex)(&m.Mutex).Lock()
+	if (*sync.Mutex)(&m.Mutex).TryLock() != 0 {
+		rt.GoBlock(rt.WaitReasonSyncMutexLock, unsafe.Pointer(m))
+		// try again once blocked, so that an Unlock in between wakes it up
+		if (*sync.Mutex)(&m.Mutex).TryLock() != 0 {
+			(*sync.Mutex)(&m.Mutex).Lock()
+		}
+		rt.GoUnblock()
+	}
 }
 
 func (m *Mutex) TryLock() bool {
@@ -69,6 +77,7 @@ func c_pthread_mutex_unlock(m *Mutex) c.Int
 
 func (m *Mutex) Unlock() {
 	c_pthread_mutex_unlock(m)
+	rt.GoReadyOne(unsafe.Pointer(m))
 }
 
 // -----------------------------------------------------------------------------
@@ -92,7 +101,13 @@ func (m *RWMutex) ensureInit() {
 
 func (rw *RWMutex) RLock() {
 	rw.ensureInit()
-	(*sync.RWLock)(&rw.RWLock).RLock()
+	if (*sync.RWLock)(&rw.RWLock).TryRLock() != 0 {
+		rt.GoBlock(rt.WaitReasonSyncRWMutexRLock, unsafe.Pointer(rw))
+		if (*sync.RWLock)(&rw.RWLock).TryRLock() != 0 {
+			(*sync.RWLock)(&rw.RWLock).RLock()
+		}
+		rt.GoUnblock()
+	}
 }
 
 func (rw *RWMutex) TryRLock() bool {
@@ -103,13 +118,22 @@ func (rw *RWMutex) TryRLock() bool {
 //go:linkname c_pthread_rwlock_unlock C.pthread_rwlock_unlock
 func c_pthread_rwlock_unlock(rw *RWMutex) c.Int
 
+// An unlock wakes up all the goroutines waiting for rw, which are no longer
+// checked for deadlocks if they keep waiting.
 func (rw *RWMutex) RUnlock() {
 	c_pthread_rwlock_unlock(rw)
+	rt.GoReady(unsafe.Pointer(rw))
 }
 
 func (rw *RWMutex) Lock() {
 	rw.ensureInit()
-	(*sync.RWLock)(&rw.RWLock).Lock()
+	if (*sync.RWLock)(&rw.RWLock).TryLock() != 0 {
+		rt.GoBlock(rt.WaitReasonSyncRWMutexLock, unsafe.Pointer(rw))
+		if (*sync.RWLock)(&rw.RWLock).TryLock() != 0 {
+			(*sync.RWLock)(&rw.RWLock).Lock()
+		}
+		rt.GoUnblock()
+	}
 }
 
 func (rw *RWMutex) TryLock() bool {
@@ -119,6 +143,7 @@ func (rw *RWMutex) TryLock() bool {
 
 func (rw *RWMutex) Unlock() {
 	c_pthread_rwlock_unlock(rw)
+	rt.GoReady(unsafe.Pointer(rw))
 }
 
 // -----------------------------------------------------------------------------
@@ -162,15 +187,19 @@ func c_pthread_cond_signal(c *Cond) c.Int
 func c_pthread_cond_broadcast(c *Cond) c.Int
 
 func (c *Cond) Signal() {
+	rt.GoReadyOne(unsafe.Pointer(c))
 	c_pthread_cond_signal(c)
 }
 
 func (c *Cond) Broadcast() {
+	rt.GoReady(unsafe.Pointer(c))
 	c_pthread_cond_broadcast(c)
 }
 
 func (c *Cond) Wait() {
+	rt.GoBlock(rt.WaitReasonSyncCondWait, unsafe.Pointer(c))
 	c.cond.Wait(c.m)
+	rt.GoUnblock()
 }
 
 // -----------------------------------------------------------------------------
@@ -205,6 +234,7 @@ func (wg *WaitGroup) Add(delta int) {
 	wg.mutex.Lock()
 	wg.count += delta
 	if wg.count <= 0 {
+		rt.GoReady(unsafe.Pointer(wg))
 		wg.cond.Broadcast()
 	}
 	wg.mutex.Unlock()
@@ -218,7 +248,9 @@ func (wg *WaitGroup) Wait() {
 	wg.ensureInit()
 	wg.mutex.Lock()
 	for wg.count > 0 {
+		rt.GoBlock(rt.WaitReasonSyncWaitGroupWait, unsafe.Pointer(wg))
 		wg.cond.Wait(&wg.mutex)
+		rt.GoUnblock()
 	}
 	wg.mutex.Unlock()
 }
@@ -0,0 +1,98 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build js && wasm
+
+package js
+
+import (
+	"sync"
+)
+
+var (
+	funcsMu    sync.Mutex
+	funcs             = make(map[uint32]func(Value, []Value) any)
+	nextFuncID uint32 = 1
+)
+
+// Func is a wrapped Go function to be called by JavaScript.
+type Func struct {
+	Value // the JavaScript function that invokes the Go function
+	id    uint32
+}
+
+// FuncOf returns a function to be used by JavaScript.
+//
+// The Go function fn is called with the value of JavaScript's "this" keyword and the
+// arguments of the invocation. The return value of the invocation is
+// the result of the Go function mapped back to JavaScript according to ValueOf.
+//
+// Invoking the wrapped Go function from JavaScript runs it synchronously on
+// the only thread of the program, which cannot be suspended: a blocking
+// operation (eg. on a channel) is a fatal error, in a wrapped function as
+// anywhere else.
+//
+// A program that only reacts to callbacks should end main with select {}:
+// this returns control to the event loop while keeping the program alive,
+// whereas returning from main exits the program.
+//
+// Func.Release must be called to free up resources when the function will not be invoked any more.
+func FuncOf(fn func(this Value, args []Value) any) Func {
+	funcsMu.Lock()
+	id := nextFuncID
+	nextFuncID++
+	funcs[id] = fn
+	funcsMu.Unlock()
+	return Func{
+		id:    id,
+		Value: jsGo.Call("_makeFuncWrapper", id),
+	}
+}
+
+// Release frees up resources allocated for the function.
+// The function must not be invoked after calling Release.
+// It is allowed to call Release while the function is still running.
+func (c Func) Release() {
+	funcsMu.Lock()
+	delete(funcs, c.id)
+	funcsMu.Unlock()
+}
+
+// resume is called by the loader when JavaScript invokes a wrapped function.
+//
+//go:wasmexport llgo_js_resume
+func resume() {
+	handleEvent()
+}
+
+func handleEvent() {
+	// Retrieve the event from js
+	cb := jsGo.Get("_pendingEvent")
+	if cb.IsNull() {
+		return
+	}
+	jsGo.Set("_pendingEvent", Null())
+
+	// Retrieve the associated js.Func
+	id := uint32(cb.Get("id").Int())
+	funcsMu.Lock()
+	f, ok := funcs[id]
+	funcsMu.Unlock()
+	if !ok {
+		Global().Get("console").Call("error", "call to released function")
+		return
+	}
+
+	// Call the js.Func with arguments
+	this := cb.Get("this")
+	argsObj := cb.Get("args")
+	args := make([]Value, argsObj.Length())
+	for i := range args {
+		args[i] = argsObj.Index(i)
+	}
+	result := f(this, args)
+
+	// Return the result to js
+	cb.Set("result", result)
+}
@@ -0,0 +1,59 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build js && wasm
+
+package js
+
+// The functions below are imported from the llgo_js module of the JavaScript
+// loader generated by llgo (see internal/build/wasm_exec.js). Strings and
+// slices are passed as a pointer and a length into the linear memory.
+
+//go:wasmimport llgo_js finalize_ref
+func finalizeRef(r ref)
+
+//go:wasmimport llgo_js string_val
+func stringVal(p *byte, n int) ref
+
+//go:wasmimport llgo_js value_get
+func valueGet(v ref, p *byte, n int) ref
+
+//go:wasmimport llgo_js value_set
+func valueSet(v ref, p *byte, n int, x ref)
+
+//go:wasmimport llgo_js value_delete
+func valueDelete(v ref, p *byte, n int)
+
+//go:wasmimport llgo_js value_index
+func valueIndex(v ref, i int) ref
+
+//go:wasmimport llgo_js value_set_index
+func valueSetIndex(v ref, i int, x ref)
+
+//go:wasmimport llgo_js value_length
+func valueLength(v ref) int
+
+//go:wasmimport llgo_js value_call
+func valueCall(v ref, m *byte, n int, args *ref, nargs int, ok *bool) ref
+
+//go:wasmimport llgo_js value_invoke
+func valueInvoke(v ref, args *ref, nargs int, ok *bool) ref
+
+//go:wasmimport llgo_js value_new
+func valueNew(v ref, args *ref, nargs int, ok *bool) ref
+
+//go:wasmimport llgo_js value_prepare_string
+func valuePrepareString(v ref, n *int) ref
+
+//go:wasmimport llgo_js value_load_string
+func valueLoadString(v ref, b *byte, n int)
+
+//go:wasmimport llgo_js value_instance_of
+func valueInstanceOf(v ref, t ref) bool
+
+//go:wasmimport llgo_js copy_bytes_to_go
+func copyBytesToGo(dst *byte, n int, src ref) int
+
+//go:wasmimport llgo_js copy_bytes_to_js
+func copyBytesToJS(dst ref, src *byte, n int) int
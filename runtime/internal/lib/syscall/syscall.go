@@ -24,6 +24,7 @@ import (
 	"github.com/goplus/llgo/runtime/internal/clite/os"
 	"github.com/goplus/llgo/runtime/internal/clite/syscall"
 	"github.com/goplus/llgo/runtime/internal/lib/internal/bytealg"
+	"github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 // llgo:skipall
@@ -84,7 +85,9 @@ func Getpid() (pid int) {
 }
 
 func Open(path string, mode int, perm uint32) (fd int, err error) {
+	runtime.EnterSyscall()
 	ret := os.Open(c.AllocaCStr(path), c.Int(mode), os.ModeT(perm))
+	runtime.ExitSyscall()
 	if ret >= 0 {
 		return int(ret), nil
 	}
@@ -92,7 +95,9 @@ func Open(path string, mode int, perm uint32) (fd int, err error) {
 }
 
 func Seek(fd int, offset int64, whence int) (newoffset int64, err error) {
+	runtime.EnterSyscall()
 	ret := os.Lseek(c.Int(fd), os.OffT(offset), c.Int(whence))
+	runtime.ExitSyscall()
 	if ret >= 0 {
 		return int64(ret), nil
 	}
@@ -100,7 +105,9 @@ func Seek(fd int, offset int64, whence int) (newoffset int64, err error) {
 }
 
 func Read(fd int, p []byte) (n int, err error) {
+	runtime.EnterSyscall()
 	ret := os.Read(c.Int(fd), unsafe.Pointer(unsafe.SliceData(p)), uintptr(len(p)))
+	runtime.ExitSyscall()
 	if ret >= 0 {
 		return ret, nil // TODO(xsw): confirm err == nil (not io.EOF) when ret == 0
 	}
@@ -108,7 +115,9 @@ func Read(fd int, p []byte) (n int, err error) {
 }
 
 func readlen(fd int, buf *byte, nbuf int) (n int, err error) {
+	runtime.EnterSyscall()
 	ret := os.Read(c.Int(fd), unsafe.Pointer(buf), uintptr(nbuf))
+	runtime.ExitSyscall()
 	if ret >= 0 {
 		return ret, nil // TODO(xsw): confirm err == nil (not io.EOF) when ret == 0
 	}
@@ -116,7 +125,9 @@ func readlen(fd int, buf *byte, nbuf int) (n int, err error) {
 }
 
 func Close(fd int) (err error) {
+	runtime.EnterSyscall()
 	ret := os.Close(c.Int(fd))
+	runtime.ExitSyscall()
 	if ret == 0 {
 		return nil
 	}
@@ -126,7 +137,9 @@ func Close(fd int) (err error) {
 type Stat_t = syscall.Stat_t
 
 func Lstat(path string, stat *Stat_t) (err error) {
+	runtime.EnterSyscall()
 	ret := os.Lstat(c.AllocaCStr(path), stat)
+	runtime.ExitSyscall()
 	if ret == 0 {
 		return nil
 	}
@@ -134,7 +147,9 @@ func Lstat(path string, stat *Stat_t) (err error) {
 }
 
 func Stat(path string, stat *Stat_t) (err error) {
+	runtime.EnterSyscall()
 	ret := os.Stat(c.AllocaCStr(path), stat)
+	runtime.ExitSyscall()
 	if ret == 0 {
 		return nil
 	}
@@ -3,9 +3,12 @@
 package syscall
 
 import (
+	"unsafe"
+
 	c "github.com/goplus/llgo/runtime/internal/clite"
 	"github.com/goplus/llgo/runtime/internal/clite/os"
 	"github.com/goplus/llgo/runtime/internal/clite/syscall"
+	"github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 type Rlimit syscall.Rlimit
@@ -26,8 +29,20 @@ func setrlimit(which int, lim *Rlimit) (err error) {
 	return Errno(ret)
 }
 
+func Write(fd int, p []byte) (n int, err error) {
+	runtime.EnterSyscall()
+	ret := os.Write(c.Int(fd), unsafe.Pointer(unsafe.SliceData(p)), uintptr(len(p)))
+	runtime.ExitSyscall()
+	if ret >= 0 {
+		return ret, nil
+	}
+	return 0, Errno(os.Errno())
+}
+
 func wait4(pid int, wstatus *c.Int, options int, rusage *syscall.Rusage) (wpid int, err error) {
+	runtime.EnterSyscall()
 	ret := os.Wait4(os.PidT(pid), wstatus, c.Int(options), rusage)
+	runtime.ExitSyscall()
 	if ret >= 0 {
 		return int(ret), nil
 	}
@@ -0,0 +1,95 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unique
+
+import (
+	"strings"
+	"sync"
+	"unsafe"
+	"weak"
+)
+
+// llgo:skipall
+type _unique struct{}
+
+var zero uintptr
+
+// Handle is a globally unique identity for some value of type T.
+//
+// Two handles compare equal exactly if the two values used to create the handles
+// would have also compared equal. The comparison of two handles is trivial and
+// typically much more efficient than comparing the values used to create them.
+type Handle[T comparable] struct {
+	value *T
+}
+
+// Value returns a shallow copy of the T value that produced the Handle.
+// Value is safe for concurrent use by multiple goroutines.
+func (h Handle[T]) Value() T {
+	return *h.value
+}
+
+// Make returns a globally unique handle for a value of type T. Handles
+// are equal if and only if the values used to produce them are equal.
+// Make is safe for concurrent use by multiple goroutines.
+func Make[T comparable](value T) Handle[T] {
+	if unsafe.Sizeof(value) == 0 {
+		return Handle[T]{(*T)(unsafe.Pointer(&zero))}
+	}
+	// Find the map for type T, keyed by a nil *T.
+	ma, ok := uniqueMaps.Load((*T)(nil))
+	if !ok {
+		ma, _ = uniqueMaps.LoadOrStore((*T)(nil), &uniqueMap[T]{m: make(map[T]weak.Pointer[T])})
+	}
+	m := ma.(*uniqueMap[T])
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ptr := m.m[value].Value(); ptr != nil {
+		return Handle[T]{ptr}
+	}
+	m.prune()
+	ptr := new(T)
+	*ptr = clone(value)
+	m.m[*ptr] = weak.Make(ptr)
+	return Handle[T]{ptr}
+}
+
+// uniqueMaps is an index of type-specific maps used for unique.Make.
+var uniqueMaps sync.Map // *T => *uniqueMap[T]
+
+// A uniqueMap maps the values of type T to their canonical pointers. It only
+// holds them weakly: the entries of the pointers reclaimed by the garbage
+// collector are deleted by prune.
+type uniqueMap[T comparable] struct {
+	mu   sync.Mutex
+	m    map[T]weak.Pointer[T]
+	gcNo uintptr // gcCycles() when m was pruned for the last time
+}
+
+// prune deletes the entries of the canonical pointers which were reclaimed,
+// once per garbage collection.
+func (m *uniqueMap[T]) prune() {
+	if n := gcCycles(); n != m.gcNo {
+		m.gcNo = n
+		for value, wp := range m.m {
+			if wp.Value() == nil {
+				delete(m.m, value)
+			}
+		}
+	}
+}
+
+// clone makes a copy of value, with a cloned version of value if it is a
+// string. The purpose of explicitly cloning strings is to avoid accidentally
+// giving a large string a long lifetime.
+//
+// Unlike gc, strings in structs and arrays are not cloned.
+func clone[T comparable](value T) T {
+	if s, ok := any(value).(string); ok {
+		return any(strings.Clone(s)).(T)
+	}
+	return value
+}
@@ -0,0 +1,93 @@
+//go:build !nogc
+// +build !nogc
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package weak
+
+import (
+	"sync"
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/bdwgc"
+)
+
+// A handle is shared by the weak pointers made from the same pointer. It is
+// allocated by bdwgc.MallocAtomic, so that link does not keep the object
+// alive, and the collector clears link when the object becomes unreachable.
+type handle struct {
+	link   c.Pointer // start of the object, or the pointer if not in the heap
+	offset uintptr   // of the pointer in the object
+}
+
+// handles maps the pointers weak pointers are made from, hidden from the
+// collector, to their handles.
+var handles struct {
+	mu    sync.Mutex
+	m     map[uintptr]*handle
+	limit int // size of m which triggers the pruning of cleared handles
+}
+
+func hidePointer(p unsafe.Pointer) uintptr {
+	return ^uintptr(p)
+}
+
+func registerWeakPointer(p unsafe.Pointer) unsafe.Pointer {
+	key := hidePointer(p)
+	handles.mu.Lock()
+	defer handles.mu.Unlock()
+	if h, ok := handles.m[key]; ok && makeStrongFromWeak(unsafe.Pointer(h)) == p {
+		return unsafe.Pointer(h)
+	}
+	h := (*handle)(bdwgc.MallocAtomic(unsafe.Sizeof(handle{})))
+	if base := bdwgc.Base(p); base != nil {
+		h.link, h.offset = base, uintptr(p)-uintptr(base)
+		bdwgc.GeneralRegisterDisappearingLink(&h.link, base)
+	} else {
+		h.link, h.offset = p, 0
+	}
+	if handles.m == nil {
+		handles.m = make(map[uintptr]*handle)
+	}
+	if len(handles.m) >= handles.limit {
+		for key, h := range handles.m {
+			if makeStrongFromWeak(unsafe.Pointer(h)) == nil {
+				delete(handles.m, key)
+			}
+		}
+		handles.limit = 2 * len(handles.m)
+		if handles.limit < 64 {
+			handles.limit = 64
+		}
+	}
+	handles.m[key] = h
+	return unsafe.Pointer(h)
+}
+
+func makeStrongFromWeak(h unsafe.Pointer) unsafe.Pointer {
+	// The collector may clear the link of h at any time, so it is read with
+	// the allocation lock held.
+	return bdwgc.CallWithAllocLock(strongFromWeak, h)
+}
+
+func strongFromWeak(h c.Pointer) c.Pointer {
+	if p := (*handle)(h).link; p != nil {
+		return unsafe.Add(p, (*handle)(h).offset)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build nogc
+// +build nogc
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package weak
+
+import "unsafe"
+
+// Without a collector nothing is ever reclaimed, so a weak pointer is the
+// pointer itself.
+
+func registerWeakPointer(p unsafe.Pointer) unsafe.Pointer {
+	return p
+}
+
+func makeStrongFromWeak(p unsafe.Pointer) unsafe.Pointer {
+	return p
+}
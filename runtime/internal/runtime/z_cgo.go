@@ -22,6 +22,23 @@ import (
 	c "github.com/goplus/llgo/runtime/internal/clite"
 )
 
+func init() {
+	cgoThreadInit()
+}
+
+// CgoCallback is called by the Go functions exported to C before they run.
+// A thread not created by Go is attached to the runtime on its first call
+// into Go: it is registered with the collector and runs as a goroutine until
+// it exits.
+func CgoCallback() {
+	if gKey.Get() == nil {
+		gc := cgoThreadAttach()
+		gp := newg(0, 0)
+		gp.cgo = true
+		gp.cgoGC = gc
+	}
+}
+
 func CString(s string) *int8 {
 	p := c.Malloc(uintptr(len(s)) + 1)
 	return CStrCopy(p, *(*String)(unsafe.Pointer(&s)))
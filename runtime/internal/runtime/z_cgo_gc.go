@@ -0,0 +1,48 @@
+//go:build !nogc && !wasm
+// +build !nogc,!wasm
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/bdwgc"
+)
+
+// cgoThreadInit allows the threads not created by Go to be attached by
+// cgoThreadAttach.
+func cgoThreadInit() {
+	bdwgc.Init()
+	bdwgc.AllowRegisterThreads()
+}
+
+// cgoThreadAttach registers the calling thread, which was not created by Go,
+// with the collector. It reports whether the thread has to be detached before
+// it exits.
+func cgoThreadAttach() bool {
+	var sb bdwgc.StackBase
+	if bdwgc.GetStackBase(&sb) != bdwgc.SUCCESS {
+		fatal("cgo callback: failed to get the stack of the thread")
+		c.Exit(2)
+	}
+	return bdwgc.RegisterMyThread(&sb) == bdwgc.SUCCESS
+}
+
+func cgoThreadDetach() {
+	bdwgc.UnregisterMyThread()
+}
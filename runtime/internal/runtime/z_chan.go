@@ -69,6 +69,17 @@ func ChanCap(p *Chan) int {
 	return p.cap
 }
 
+func (p *Chan) wait(reason WaitReason) {
+	GoBlock(reason, unsafe.Pointer(p))
+	p.cond.Wait(&p.mutex)
+	GoUnblock()
+}
+
+func (p *Chan) broadcast() {
+	GoReady(unsafe.Pointer(p))
+	p.cond.Broadcast()
+}
+
 func notifyOps(p *Chan) {
 	for _, sop := range p.sops {
 		sop.notify()
@@ -76,11 +87,12 @@ func notifyOps(p *Chan) {
 }
 
 func ChanClose(p *Chan) {
+	traceChan("chan close", p)
 	p.mutex.Lock()
 	p.close = true
 	notifyOps(p)
 	p.mutex.Unlock()
-	p.cond.Broadcast()
+	p.broadcast()
 }
 
 func ChanTrySend(p *Chan, v unsafe.Pointer, eltSize int) bool {
@@ -106,17 +118,18 @@ func ChanTrySend(p *Chan, v unsafe.Pointer, eltSize int) bool {
 	}
 	notifyOps(p)
 	p.mutex.Unlock()
-	p.cond.Broadcast()
+	p.broadcast()
 	return true
 }
 
 func ChanSend(p *Chan, v unsafe.Pointer, eltSize int) bool {
+	traceChan("chan send", p)
 	n := p.cap
 	p.mutex.Lock()
 	if n == 0 {
 		for p.getp != chanHasRecv && !p.close {
 			p.sends++
-			p.cond.Wait(&p.mutex)
+			p.wait(WaitReasonChanSend)
 			p.sends--
 		}
 		if p.close {
@@ -129,7 +142,7 @@ func ChanSend(p *Chan, v unsafe.Pointer, eltSize int) bool {
 		p.getp = chanNoSendRecv
 	} else {
 		for p.len == n {
-			p.cond.Wait(&p.mutex)
+			p.wait(WaitReasonChanSend)
 		}
 		if p.close {
 			p.mutex.Unlock()
@@ -141,7 +154,7 @@ func ChanSend(p *Chan, v unsafe.Pointer, eltSize int) bool {
 	}
 	notifyOps(p)
 	p.mutex.Unlock()
-	p.cond.Broadcast()
+	p.broadcast()
 	return true
 }
 
@@ -170,11 +183,11 @@ func ChanTryRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool, tryOK boo
 	}
 	notifyOps(p)
 	p.mutex.Unlock()
-	p.cond.Broadcast()
+	p.broadcast()
 	if n == 0 {
 		p.mutex.Lock()
 		for p.getp == chanHasRecv && !p.close {
-			p.cond.Wait(&p.mutex)
+			p.wait(WaitReasonChanReceive)
 		}
 		recvOK = !p.close
 		tryOK = recvOK
@@ -186,11 +199,12 @@ func ChanTryRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool, tryOK boo
 }
 
 func ChanRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool) {
+	traceChan("chan recv", p)
 	n := p.cap
 	p.mutex.Lock()
 	if n == 0 {
 		for p.getp == chanHasRecv && !p.close {
-			p.cond.Wait(&p.mutex)
+			p.wait(WaitReasonChanReceive)
 		}
 		if p.close {
 			p.mutex.Unlock()
@@ -204,7 +218,7 @@ func ChanRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool) {
 				p.mutex.Unlock()
 				return false
 			}
-			p.cond.Wait(&p.mutex)
+			p.wait(WaitReasonChanReceive)
 		}
 		if v != nil {
 			c.Memcpy(v, c.Advance(p.data, p.getp*eltSize), uintptr(eltSize))
@@ -214,11 +228,11 @@ func ChanRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool) {
 	}
 	notifyOps(p)
 	p.mutex.Unlock()
-	p.cond.Broadcast()
+	p.broadcast()
 	if n == 0 {
 		p.mutex.Lock()
 		for p.getp == chanHasRecv && !p.close {
-			p.cond.Wait(&p.mutex)
+			p.wait(WaitReasonChanReceive)
 		}
 		recvOK = !p.close
 		p.mutex.Unlock()
@@ -251,13 +265,16 @@ func (p *selectOp) notify() {
 	p.mutex.Lock()
 	p.sem = true
 	p.mutex.Unlock()
+	GoReady(unsafe.Pointer(p))
 	p.cond.Signal()
 }
 
-func (p *selectOp) wait() {
+func (p *selectOp) wait(reason WaitReason) {
 	p.mutex.Lock()
 	if !p.sem {
+		GoBlock(reason, unsafe.Pointer(p))
 		p.cond.Wait(&p.mutex)
+		GoUnblock()
 	}
 	p.sem = false
 	p.mutex.Unlock()
@@ -292,17 +309,25 @@ func TrySelect(ops ...ChanOp) (isel int, recvOK, tryOK bool) {
 
 // Select executes a blocking select operation.
 func Select(ops ...ChanOp) (isel int, recvOK bool) {
+	traceChan("select", nil)
+	if len(ops) == 0 {
+		selectNoCases()
+	}
 	selOp := new(selectOp) // TODO(xsw): use c.AllocaNew[selectOp]()
 	selOp.init()
 	for _, op := range ops {
 		prepareSelect(op.C, selOp)
 	}
+	reason := WaitReasonSelect
+	if len(ops) == 0 {
+		reason = WaitReasonSelectNoCases
+	}
 	var tryOK bool
 	for {
 		if isel, recvOK, tryOK = TrySelect(ops...); tryOK {
 			break
 		}
-		selOp.wait()
+		selOp.wait(reason)
 	}
 	for _, op := range ops {
 		endSelect(op.C, selOp)
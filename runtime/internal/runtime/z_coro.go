@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+)
+
+// -----------------------------------------------------------------------------
+
+// A Coro is a coroutine, as used by iter.Pull. Its function runs on a thread
+// of its own, which takes turns with the goroutine resuming it: exactly one
+// of them runs at a time, so the function does not run concurrently with its
+// caller.
+//
+// The thread exits only when the function returns. A coroutine which is
+// abandoned while its function is blocked in CoroSwitch, such as an iterator
+// of iter.Pull whose stop is never called, keeps its thread, and everything
+// its stack references, until the program exits: the thread stack is a root
+// of the garbage collector, so the coroutine can't be reclaimed.
+type Coro struct {
+	mutex   sync.Mutex
+	cond    sync.Cond
+	f       func(*Coro)
+	started bool
+	inside  bool // the coroutine has the turn
+}
+
+// NewCoro returns a coroutine that runs f(co) from the first CoroSwitch(co).
+func NewCoro(f func(*Coro)) *Coro {
+	co := &Coro{f: f}
+	co.cond.Init(nil)
+	return co
+}
+
+// CoroSwitch switches from the goroutine resuming co to the coroutine, or
+// from the coroutine back to the goroutine which resumed it, and returns when
+// the turn comes back. When the function of co returns, the turn goes back to
+// the goroutine which resumed it for the last time.
+func CoroSwitch(co *Coro) {
+	co.mutex.Lock()
+	inside := co.inside
+	co.inside = !inside
+	if !co.started {
+		co.started = true
+		var th pthread.Thread
+		CreateThread(&th, nil, coroStart, c.Pointer(co))
+	} else {
+		GoReady(unsafe.Pointer(co))
+		co.cond.Broadcast()
+	}
+	GoBlock(waitReasonCoroutine, unsafe.Pointer(co))
+	for co.inside != inside {
+		co.cond.Wait(&co.mutex)
+	}
+	GoUnblock()
+	co.mutex.Unlock()
+}
+
+func coroStart(arg c.Pointer) c.Pointer {
+	co := (*Coro)(arg)
+	defer co.exit() // also when f calls Goexit
+	co.f(co)
+	return nil
+}
+
+// exit gives the turn back for good when the function of co returns.
+func (co *Coro) exit() {
+	co.mutex.Lock()
+	co.inside = false
+	GoReady(unsafe.Pointer(co))
+	co.cond.Broadcast()
+	co.mutex.Unlock()
+}
+
+// -----------------------------------------------------------------------------
@@ -36,3 +36,16 @@ func AllocZ(size uintptr) unsafe.Pointer {
 	ret := bdwgc.Malloc(size)
 	return c.Memset(ret, 0, size)
 }
+
+func traceGCInit() {
+	bdwgc.SetOnCollectionEvent(traceGCCallback)
+}
+
+func traceGCCallback(ev bdwgc.EventType) {
+	switch ev {
+	case bdwgc.EVENT_START:
+		traceGCEvent(true)
+	case bdwgc.EVENT_END:
+		traceGCEvent(false)
+	}
+}
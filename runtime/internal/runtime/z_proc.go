@@ -0,0 +1,314 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/debug"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+	"github.com/goplus/llgo/runtime/internal/clite/sync/atomic"
+)
+
+// -----------------------------------------------------------------------------
+
+// WaitReason explains why a goroutine is blocked.
+type WaitReason uint8
+
+const (
+	waitReasonZero WaitReason = iota // running
+	WaitReasonChanReceive
+	WaitReasonChanSend
+	WaitReasonSelect
+	WaitReasonSelectNoCases
+	WaitReasonSyncMutexLock
+	WaitReasonSyncRWMutexLock
+	WaitReasonSyncRWMutexRLock
+	WaitReasonSyncCondWait
+	WaitReasonSyncWaitGroupWait
+	waitReasonCoroutine
+	waitReasonTraceReaderBlocked
+	waitReasonTraceStop
+)
+
+var waitReasonStrings = [...]string{
+	waitReasonZero:               "running",
+	WaitReasonChanReceive:        "chan receive",
+	WaitReasonChanSend:           "chan send",
+	WaitReasonSelect:             "select",
+	WaitReasonSelectNoCases:      "select (no cases)",
+	WaitReasonSyncMutexLock:      "sync.Mutex.Lock",
+	WaitReasonSyncRWMutexLock:    "sync.RWMutex.Lock",
+	WaitReasonSyncRWMutexRLock:   "sync.RWMutex.RLock",
+	WaitReasonSyncCondWait:       "sync.Cond.Wait",
+	WaitReasonSyncWaitGroupWait:  "sync.WaitGroup.Wait",
+	waitReasonCoroutine:          "coroutine",
+	waitReasonTraceReaderBlocked: "trace reader (blocked)",
+	waitReasonTraceStop:          "trace stop",
+}
+
+func (w WaitReason) String() string {
+	if int(w) < len(waitReasonStrings) {
+		return waitReasonStrings[w]
+	}
+	return "unknown wait reason"
+}
+
+// -----------------------------------------------------------------------------
+
+// g represents a goroutine. Every goroutine runs on its own thread, so a g
+// lives in thread local storage from the moment its thread starts until the
+// thread exits.
+type g struct {
+	goid   int64
+	wait   WaitReason
+	thread pthread.Thread
+	parent int64          // goid of the goroutine executing the go statement
+	gopc   uintptr        // pc of the go statement that created this goroutine
+	trace  unsafe.Pointer // *gtrace requested by a traceback, see sigquit
+	cgo    bool           // thread not created by Go, attached by CgoCallback
+	cgoGC  bool           // thread registered with the collector by CgoCallback
+	prev   *g
+	next   *g
+
+	waitObj unsafe.Pointer // object the goroutine is blocked on
+	woken   bool           // woken up by GoReady, but not running yet
+	wprev   *g             // list of blocked goroutines
+	wnext   *g
+}
+
+var (
+	gKey   pthread.Key
+	allgs  *g // list of live goroutines in creation order, protected by sched.mutex
+	lastg  *g
+	maxgid int64
+
+	sched struct {
+		mutex     sync.Mutex
+		ngo       int    // number of live goroutines
+		nstarting int    // number of goroutines created but not yet running
+		nblocked  int    // number of goroutines blocked and not woken up
+		nwait     uint32 // number of goroutines in waitq, read without mutex by GoReady
+		waitq     *g     // goroutines blocked in channel or sync operations
+		epoch     uint32 // changes whenever a goroutine is woken up or starts
+		checking  bool   // deadlock checker started
+	}
+)
+
+func init() {
+	sched.mutex.Init(nil)
+	gKey.Create(gdestroy)
+	newg(0, 0)
+	if debug.HandleSigquit(sigquit) == 0 {
+		var th pthread.Thread
+		pthread.Create(&th, nil, sigquitDumper, nil)
+	}
+}
+
+// newg registers the current thread as a new goroutine and adds it to allgs.
+func newg(parent int64, gopc uintptr) *g {
+	gp := (*g)(c.Calloc(1, unsafe.Sizeof(g{})))
+	gp.thread = pthread.Self()
+	gp.parent = parent
+	gp.gopc = gopc
+	gKey.Set(unsafe.Pointer(gp))
+	sched.mutex.Lock()
+	maxgid++
+	gp.goid = maxgid
+	gp.prev = lastg
+	if lastg != nil {
+		lastg.next = gp
+	} else {
+		allgs = gp
+	}
+	lastg = gp
+	sched.ngo++
+	atomic.Add(&sched.epoch, 1)
+	sched.mutex.Unlock()
+	traceGoStart(gp)
+	return gp
+}
+
+// gdestroy is called when the thread of a goroutine exits.
+func gdestroy(ptr c.Pointer) {
+	gp := (*g)(ptr)
+	traceGoEnd(gp)
+	sched.mutex.Lock()
+	if gp.prev != nil {
+		gp.prev.next = gp.next
+	} else {
+		allgs = gp.next
+	}
+	if gp.next != nil {
+		gp.next.prev = gp.prev
+	} else {
+		lastg = gp.prev
+	}
+	sched.ngo--
+	sched.mutex.Unlock()
+	if gp.cgoGC {
+		cgoThreadDetach()
+	}
+	c.Free(ptr)
+}
+
+// getg returns the current goroutine. Threads not started by a go statement
+// (eg. callbacks from C libraries) are registered on first use.
+func getg() *g {
+	gp := (*g)(gKey.Get())
+	if gp == nil {
+		gp = newg(0, 0)
+	}
+	return gp
+}
+
+// -----------------------------------------------------------------------------
+
+// GoBlock marks the current goroutine as blocked on obj for the given
+// reason. It must be called before the goroutine checks for the last time
+// whether it has to wait, or with the lock held which protects what it waits
+// for, so that the GoReady(obj) following the change it waits for finds it.
+// It must be paired with GoUnblock once the blocking operation returns.
+func GoBlock(reason WaitReason, obj unsafe.Pointer) {
+	gp := getg()
+	goblock(gp, reason, obj)
+	traceGoState(gp, reason.String())
+}
+
+// goblock is GoBlock without tracing.
+func goblock(gp *g, reason WaitReason, obj unsafe.Pointer) {
+	sched.mutex.Lock()
+	gp.wait = reason
+	gp.waitObj = obj
+	gp.woken = false
+	gp.wprev = nil
+	gp.wnext = sched.waitq
+	if sched.waitq != nil {
+		sched.waitq.wprev = gp
+	}
+	sched.waitq = gp
+	atomic.Add(&sched.nwait, 1)
+	sched.nblocked++
+	if !sched.checking && checkdeadEnabled {
+		sched.checking = true
+		var th pthread.Thread
+		pthread.Create(&th, nil, checkdead, nil)
+	}
+	sched.mutex.Unlock()
+}
+
+// GoUnblock marks the current goroutine as running again.
+func GoUnblock() {
+	gp := getg()
+	gounblock(gp)
+	traceGoState(gp, "running")
+}
+
+// gounblock is GoUnblock without tracing.
+func gounblock(gp *g) {
+	sched.mutex.Lock()
+	if gp.wprev != nil {
+		gp.wprev.wnext = gp.wnext
+	} else {
+		sched.waitq = gp.wnext
+	}
+	if gp.wnext != nil {
+		gp.wnext.wprev = gp.wprev
+	}
+	atomic.Add(&sched.nwait, ^uint32(0))
+	if !gp.woken {
+		// A mutex woken by GoReadyOne goes to one of its waiters, which may
+		// not be the one GoReadyOne chose.
+		if other := wokenOn(gp); gp.wait == WaitReasonSyncMutexLock && other != nil {
+			other.woken = false
+		} else {
+			sched.nblocked--
+		}
+	}
+	gp.wait = waitReasonZero
+	gp.waitObj = nil
+	gp.woken = false
+	sched.mutex.Unlock()
+}
+
+// wokenOn returns another goroutine blocked on the object of gp which is woken
+// up, or nil. It must be called with sched.mutex held.
+func wokenOn(gp *g) *g {
+	for w := sched.waitq; w != nil; w = w.wnext {
+		if w != gp && w.woken && w.waitObj == gp.waitObj {
+			return w
+		}
+	}
+	return nil
+}
+
+// GoReady notes that the goroutines blocked on obj are woken up. They are
+// no longer counted as blocked from now on, even if they aren't scheduled
+// yet.
+func GoReady(obj unsafe.Pointer) {
+	goReady(obj, false)
+}
+
+// GoReadyOne notes that one of the goroutines blocked on obj is woken up.
+func GoReadyOne(obj unsafe.Pointer) {
+	goReady(obj, true)
+}
+
+func goReady(obj unsafe.Pointer, one bool) {
+	if atomic.Load(&sched.nwait) == 0 {
+		return
+	}
+	sched.mutex.Lock()
+	for gp := sched.waitq; gp != nil; gp = gp.wnext {
+		if gp.waitObj == obj && !gp.woken {
+			gp.woken = true
+			sched.nblocked--
+			if one {
+				break
+			}
+		}
+	}
+	atomic.Add(&sched.epoch, 1)
+	sched.mutex.Unlock()
+}
+
+// checkdeadInterval is the interval between two deadlock checks, in
+// microseconds. A deadlock is reported only if it is observed by two
+// consecutive checks with no goroutine woken up or started in between.
+const checkdeadInterval = 50000
+
+func checkdead(c.Pointer) c.Pointer {
+	var epoch uint32
+	var suspect bool
+	for {
+		c.Usleep(checkdeadInterval)
+		sched.mutex.Lock()
+		dead := sched.ngo > 0 && sched.nstarting == 0 && sched.nblocked == sched.ngo
+		cur := atomic.Load(&sched.epoch)
+		if dead && suspect && cur == epoch {
+			fatal("all goroutines are asleep - deadlock!")
+			tracebackothers(&tracebuf{}, nil, new(gtrace))
+			c.Exit(2)
+		}
+		suspect, epoch = dead, cur
+		sched.mutex.Unlock()
+	}
+}
+
+// -----------------------------------------------------------------------------
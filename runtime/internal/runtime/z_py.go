@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	c "github.com/goplus/llgo/runtime/internal/clite"
+)
+
+// -----------------------------------------------------------------------------
+
+// A PyError is a Python exception raised by a Python call. It is the error
+// result of a call whose Go signature has one, or the value of the panic of
+// a call without one.
+type PyError struct {
+	typ, msg, tb string
+}
+
+// NewPyError returns the PyError of the exception of class typ, of message
+// msg and of traceback tb. They are C strings, nil if unknown.
+func NewPyError(typ, msg, tb *c.Char) error {
+	return &PyError{pyErrStr(typ), pyErrStr(msg), pyErrStr(tb)}
+}
+
+func pyErrStr(s *c.Char) string {
+	if s == nil {
+		return ""
+	}
+	return c.GoString(s)
+}
+
+func (e *PyError) Error() string {
+	return e.typ + ": " + e.msg
+}
+
+// PyType returns the name of the class of the exception, such as
+// "ValueError".
+func (e *PyError) PyType() string {
+	return e.typ
+}
+
+// Message returns the message of the exception, str() of it.
+func (e *PyError) Message() string {
+	return e.msg
+}
+
+// Traceback returns the traceback of the exception, as Python prints it.
+func (e *PyError) Traceback() string {
+	return e.tb
+}
+
+// -----------------------------------------------------------------------------
@@ -79,6 +79,10 @@ func Rethrow(link *Defer) {
 			fatal("no goroutines (main called runtime.Goexit) - deadlock!")
 			c.Exit(2)
 		}
+		if getg().cgo {
+			fatal("runtime.Goexit called in a thread that was not created by the Go runtime")
+			c.Exit(2)
+		}
 		pthread.Exit(nil)
 	}
 }
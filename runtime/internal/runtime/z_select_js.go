@@ -0,0 +1,29 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+// selectNoCases gives control back to the event loop of JavaScript for good
+// when select {} is reached, eg. at the end of a main that only reacts to
+// callbacks. The loader goes on running the callbacks, and select {} never
+// needs its frames again. This happens before select {} blocks, so that no
+// goroutine is counted as blocked.
+func selectNoCases() {
+	jsPark()
+}
+
+//go:wasmimport llgo_js park
+func jsPark()
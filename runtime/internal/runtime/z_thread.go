@@ -17,12 +17,44 @@
 package runtime
 
 import (
-	_ "unsafe"
+	"unsafe"
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
 	"github.com/goplus/llgo/runtime/internal/clite/pthread"
 )
 
+type routineArgs struct {
+	routine pthread.RoutineFunc
+	arg     c.Pointer
+	parent  int64
+	gopc    uintptr
+}
+
+// CreateThread starts a new goroutine.
 func CreateThread(th *pthread.Thread, attr *pthread.Attr, routine pthread.RoutineFunc, arg c.Pointer) c.Int {
-	return pthread.Create(th, attr, routine, arg)
+	ra := (*routineArgs)(AllocU(unsafe.Sizeof(routineArgs{})))
+	ra.routine = routine
+	ra.arg = arg
+	ra.parent = getg().goid
+	ra.gopc = uintptr(c.ReturnAddress())
+	// the goroutine counts as running until its thread starts
+	sched.mutex.Lock()
+	sched.nstarting++
+	sched.mutex.Unlock()
+	ret := pthread.Create(th, attr, startRoutine, c.Pointer(ra))
+	if ret != 0 {
+		sched.mutex.Lock()
+		sched.nstarting--
+		sched.mutex.Unlock()
+	}
+	return ret
+}
+
+func startRoutine(arg c.Pointer) c.Pointer {
+	ra := (*routineArgs)(arg)
+	newg(ra.parent, ra.gopc)
+	sched.mutex.Lock()
+	sched.nstarting--
+	sched.mutex.Unlock()
+	return ra.routine(ra.arg)
 }
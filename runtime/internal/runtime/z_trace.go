@@ -0,0 +1,460 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+	"github.com/goplus/llgo/runtime/internal/clite/sync/atomic"
+	"github.com/goplus/llgo/runtime/internal/clite/time"
+)
+
+// -----------------------------------------------------------------------------
+// Execution tracer.
+//
+// Events are encoded in the Chrome Trace Event Format, which can be opened by
+// chrome://tracing and https://ui.perfetto.dev. Every goroutine is shown as a
+// thread whose slices are its running, blocked and syscall periods; channel
+// operations are instant events and GC cycles are slices of thread 0.
+//
+// Tracing code never allocates from the GC heap while holding trace.mutex:
+// a collection would call traceGCEvent, which must not block on the tracer.
+
+const (
+	traceFlushSize  = 64 << 10 // ReadTrace returns once this much data is pending
+	traceFlushDelay = 100e6    // or once the oldest pending data is this old, in ns
+	traceGCRingSize = 64
+)
+
+// traceBuf is a growable buffer allocated outside the GC heap.
+type traceBuf struct {
+	data c.Pointer
+	len  int
+	cap  int
+}
+
+func (b *traceBuf) str(s string) {
+	if n := b.len + len(s); n > b.cap {
+		b.cap = 2*b.cap + len(s) + 4096
+		b.data = c.Realloc(b.data, uintptr(b.cap))
+	}
+	c.Memcpy(c.Advance(b.data, b.len), c.Pointer(unsafe.StringData(s)), uintptr(len(s)))
+	b.len += len(s)
+}
+
+func (b *traceBuf) int(v int64) {
+	var buf [20]byte
+	if v < 0 {
+		b.str("-")
+		v = -v
+	}
+	s := itoa(buf[:], uint64(v))
+	b.str(unsafe.String(&s[0], len(s)))
+}
+
+// quote writes s as a JSON string.
+func (b *traceBuf) quote(s string) {
+	const hex = "0123456789abcdef"
+	b.str("\"")
+	start := 0
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= 0x20 && ch != '"' && ch != '\\' {
+			continue
+		}
+		b.str(s[start:i])
+		b.str("\\u00")
+		b.str(hex[ch>>4 : ch>>4+1])
+		b.str(hex[ch&15 : ch&15+1])
+		start = i + 1
+	}
+	b.str(s[start:])
+	b.str("\"")
+}
+
+// ts writes a duration in nanoseconds as microseconds, the unit of the
+// Chrome Trace Event Format.
+func (b *traceBuf) ts(ns int64) {
+	const digits = "0123456789"
+	b.int(ns / 1000)
+	frac := ns % 1000
+	if frac < 0 {
+		frac = -frac
+	}
+	b.str(".")
+	b.str(digits[frac/100 : frac/100+1])
+	b.str(digits[frac/10%10 : frac/10%10+1])
+	b.str(digits[frac%10 : frac%10+1])
+}
+
+type traceGC struct {
+	start int64
+	end   int64
+}
+
+var trace struct {
+	mutex    sync.Mutex
+	cond     sync.Cond
+	enabled  int32 // accessed atomically
+	shutdown bool  // StopTrace is waiting for the reader
+	done     bool  // the reader has seen the end of the trace
+	nevents  int
+	start    int64
+	buf      traceBuf
+
+	// GC cycles recorded by traceGCEvent, a single producer ring consumed
+	// with trace.mutex held.
+	gcStart int64
+	gcHead  uint32
+	gcTail  uint32
+	gcRing  [traceGCRingSize]traceGC
+}
+
+func init() {
+	trace.mutex.Init(nil)
+	trace.cond.Init(nil)
+}
+
+func nanotime() int64 {
+	tv := (*time.Timespec)(c.Alloca(unsafe.Sizeof(time.Timespec{})))
+	time.ClockGettime(time.CLOCK_MONOTONIC, tv)
+	return int64(tv.Sec)*1e9 + int64(tv.Nsec)
+}
+
+func traceEnabled() bool {
+	return atomic.Load(&trace.enabled) != 0
+}
+
+// traceEvent starts an event. It must be called with trace.mutex held and
+// the event must be completed by traceEventEnd.
+func traceEvent(ph, name string, tid int64, ts int64) *traceBuf {
+	b := &trace.buf
+	if trace.nevents > 0 {
+		b.str(",")
+	}
+	trace.nevents++
+	b.str("\n{\"ph\":\"")
+	b.str(ph)
+	b.str("\",\"name\":\"")
+	b.str(name)
+	b.str("\",\"pid\":1,\"tid\":")
+	b.int(tid)
+	b.str(",\"ts\":")
+	b.ts(ts - trace.start)
+	if ph == "i" {
+		b.str(",\"s\":\"t\"")
+	}
+	return b
+}
+
+func traceEventEnd(b *traceBuf) {
+	b.str("}")
+}
+
+func traceFlushGC() {
+	tail := atomic.Load(&trace.gcTail)
+	for ; trace.gcHead != tail; trace.gcHead++ {
+		gc := &trace.gcRing[trace.gcHead%traceGCRingSize]
+		b := traceEvent("X", "GC", 0, gc.start)
+		b.str(",\"dur\":")
+		b.ts(gc.end - gc.start)
+		traceEventEnd(b)
+	}
+}
+
+// traceLock acquires trace.mutex and starts an event if tracing is enabled.
+// If it returns a non-nil buffer, the caller must complete the event with
+// traceEventEnd and call traceUnlock.
+func traceLock(ph, name string, tid int64) *traceBuf {
+	ts := nanotime()
+	trace.mutex.Lock()
+	if !traceEnabled() {
+		trace.mutex.Unlock()
+		return nil
+	}
+	traceFlushGC()
+	return traceEvent(ph, name, tid, ts)
+}
+
+func traceUnlock() {
+	trace.mutex.Unlock()
+	traceReady()
+}
+
+// traceWait waits on trace.cond until ready returns true. It must be called
+// with trace.mutex held. The goroutine is marked as blocked meanwhile, which
+// is done without trace.mutex held since StartTrace acquires trace.mutex
+// with sched.mutex held. Blocking is not traced: the events would wake up
+// the reader.
+func traceWait(reason WaitReason, ready func() bool) {
+	gp := getg()
+	for !ready() {
+		trace.mutex.Unlock()
+		goblock(gp, reason, unsafe.Pointer(&trace))
+		trace.mutex.Lock()
+		if !ready() {
+			trace.cond.Wait(&trace.mutex)
+		}
+		trace.mutex.Unlock()
+		gounblock(gp)
+		trace.mutex.Lock()
+	}
+}
+
+// traceReady wakes up the goroutines in traceWait. It must be called without
+// trace.mutex held.
+func traceReady() {
+	trace.cond.Broadcast()
+	GoReady(unsafe.Pointer(&trace))
+}
+
+func traceReadable() bool {
+	return trace.buf.len != 0 || !traceEnabled()
+}
+
+func traceReadDone() bool {
+	return trace.done
+}
+
+func traceThreadName(gp *g) {
+	b := traceEvent("M", "thread_name", gp.goid, trace.start)
+	b.str(",\"args\":{\"name\":\"goroutine ")
+	b.int(gp.goid)
+	b.str("\"}")
+	traceEventEnd(b)
+}
+
+// traceGoState ends the current slice of gp and begins a new one.
+func traceGoState(gp *g, state string) {
+	if !traceEnabled() {
+		return
+	}
+	if b := traceLock("E", "", gp.goid); b != nil {
+		traceEventEnd(b)
+		traceEventEnd(traceEvent("B", state, gp.goid, nanotime()))
+		traceUnlock()
+	}
+}
+
+// traceGoStart records the creation and the start of gp, which is called by
+// the new goroutine itself.
+func traceGoStart(gp *g) {
+	if !traceEnabled() {
+		return
+	}
+	if gp.parent != 0 {
+		traceGoCreate(gp)
+	}
+	if b := traceLock("B", "running", gp.goid); b != nil {
+		traceEventEnd(b)
+		traceThreadName(gp)
+		traceUnlock()
+	}
+}
+
+func traceGoCreate(gp *g) {
+	if b := traceLock("i", "go", gp.parent); b != nil {
+		b.str(",\"args\":{\"goid\":")
+		b.int(gp.goid)
+		b.str("}")
+		traceEventEnd(b)
+		traceUnlock()
+	}
+}
+
+func traceGoEnd(gp *g) {
+	if !traceEnabled() {
+		return
+	}
+	if b := traceLock("E", "", gp.goid); b != nil {
+		traceEventEnd(b)
+		traceUnlock()
+	}
+}
+
+func traceChan(name string, p *Chan) {
+	if !traceEnabled() {
+		return
+	}
+	if b := traceLock("i", name, getg().goid); b != nil {
+		b.str(",\"args\":{\"chan\":")
+		b.int(int64(uintptr(unsafe.Pointer(p))))
+		b.str("}")
+		traceEventEnd(b)
+		traceUnlock()
+	}
+}
+
+// TraceUserEvent records an annotation of the runtime/trace package, such as
+// a task, a region or a log message, as an instant event of the current
+// goroutine.
+func TraceUserEvent(kind string, id uint64, category, message string) {
+	if !traceEnabled() {
+		return
+	}
+	if b := traceLock("i", kind, getg().goid); b != nil {
+		b.str(",\"args\":{\"id\":")
+		b.int(int64(id))
+		if category != "" {
+			b.str(",\"category\":")
+			b.quote(category)
+		}
+		if message != "" {
+			b.str(",\"message\":")
+			b.quote(message)
+		}
+		b.str("}")
+		traceEventEnd(b)
+		traceUnlock()
+	}
+}
+
+// traceGCEvent is called by the garbage collector at the start and the end
+// of a collection, with the GC lock held.
+func traceGCEvent(start bool) {
+	if !traceEnabled() {
+		return
+	}
+	now := nanotime()
+	if start {
+		atomic.Store(&trace.gcStart, now)
+		return
+	}
+	tail := atomic.Load(&trace.gcTail)
+	if tail-atomic.Load(&trace.gcHead) < traceGCRingSize {
+		trace.gcRing[tail%traceGCRingSize] = traceGC{atomic.Load(&trace.gcStart), now}
+		atomic.Store(&trace.gcTail, tail+1)
+	}
+}
+
+// EnterSyscall records that the current goroutine is entering a system call.
+func EnterSyscall() {
+	if !traceEnabled() {
+		return
+	}
+	if b := traceLock("B", "syscall", getg().goid); b != nil {
+		traceEventEnd(b)
+		traceUnlock()
+	}
+}
+
+// ExitSyscall records that the current goroutine has returned from a system
+// call.
+func ExitSyscall() {
+	if !traceEnabled() {
+		return
+	}
+	if b := traceLock("E", "", getg().goid); b != nil {
+		traceEventEnd(b)
+		traceUnlock()
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// StartTrace enables tracing for the current process.
+func StartTrace() error {
+	sched.mutex.Lock()
+	trace.mutex.Lock()
+	if traceEnabled() || trace.shutdown {
+		trace.mutex.Unlock()
+		sched.mutex.Unlock()
+		return errorString("tracing is already enabled")
+	}
+	trace.start = nanotime()
+	trace.nevents = 0
+	trace.done = false
+	trace.gcHead = atomic.Load(&trace.gcTail)
+	trace.buf.str("{\"displayTimeUnit\":\"ns\",\"traceEvents\":[")
+	b := traceEvent("M", "thread_name", 0, trace.start)
+	b.str(",\"args\":{\"name\":\"GC\"}")
+	traceEventEnd(b)
+	for gp := allgs; gp != nil; gp = gp.next {
+		traceThreadName(gp)
+		state := "running"
+		if gp.wait != waitReasonZero {
+			state = gp.wait.String()
+		}
+		traceEventEnd(traceEvent("B", state, gp.goid, trace.start))
+	}
+	atomic.Store(&trace.enabled, 1)
+	trace.mutex.Unlock()
+	sched.mutex.Unlock()
+	traceGCInit()
+	return nil
+}
+
+// StopTrace stops tracing, if it was previously enabled. StopTrace only
+// returns after all the reads for the trace have completed.
+func StopTrace() {
+	trace.mutex.Lock()
+	if !traceEnabled() {
+		trace.mutex.Unlock()
+		return
+	}
+	traceFlushGC()
+	atomic.Store(&trace.enabled, 0)
+	trace.buf.str("\n]}\n")
+	trace.shutdown = true
+	trace.mutex.Unlock()
+	traceReady()
+	trace.mutex.Lock()
+	traceWait(waitReasonTraceStop, traceReadDone)
+	trace.shutdown = false
+	trace.mutex.Unlock()
+}
+
+// ReadTrace returns the next chunk of binary tracing data, blocking until data
+// is available. If tracing is turned off and all the data accumulated while it
+// was on has been returned, ReadTrace returns nil.
+func ReadTrace() []byte {
+	deadline := (*time.Timespec)(c.Alloca(unsafe.Sizeof(time.Timespec{})))
+	time.ClockGettime(time.CLOCK_REALTIME, deadline)
+	deadline.Sec += time.TimeT((int64(deadline.Nsec) + traceFlushDelay) / 1e9)
+	deadline.Nsec = c.Long((int64(deadline.Nsec) + traceFlushDelay) % 1e9)
+
+	trace.mutex.Lock()
+	for trace.buf.len < traceFlushSize && traceEnabled() {
+		if trace.buf.len == 0 {
+			traceWait(waitReasonTraceReaderBlocked, traceReadable)
+		} else if trace.cond.TimedWait(&trace.mutex, deadline) != 0 {
+			break // timed out with pending data
+		}
+	}
+	data, n := trace.buf.data, trace.buf.len
+	if n == 0 {
+		if trace.shutdown && !trace.done {
+			trace.done = true
+			trace.mutex.Unlock()
+			traceReady()
+			return nil
+		}
+		trace.mutex.Unlock()
+		return nil
+	}
+	trace.buf = traceBuf{}
+	trace.mutex.Unlock()
+
+	ret := make([]byte, n)
+	c.Memcpy(c.Pointer(unsafe.SliceData(ret)), data, uintptr(n))
+	c.Free(data)
+	return ret
+}
+
+// -----------------------------------------------------------------------------
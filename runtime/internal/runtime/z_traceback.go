@@ -0,0 +1,283 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/debug"
+	"github.com/goplus/llgo/runtime/internal/clite/sync/atomic"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	maxStackFrames = 64
+
+	// tracebackTimeout is how long, in units of 100 microseconds, we wait
+	// for another goroutine to capture its stack.
+	tracebackTimeout = 1000
+
+	rtPkgPrefix = "github.com/goplus/llgo/runtime/internal/runtime."
+)
+
+// gtrace receives the stack of a goroutine. Stacks of other goroutines are
+// captured by the goroutines themselves: we send SIGQUIT to their threads and
+// the signal handler fills the gtrace they find in g.trace. Only the pcs are
+// captured there, the frames are symbolized when the trace is written.
+type gtrace struct {
+	pcs  [maxStackFrames]uintptr
+	n    int32
+	done int32
+}
+
+func (tr *gtrace) capture(skip int) {
+	tr.n = int32(debug.Backtrace(skip+1, tr.pcs[:]))
+	atomic.Store(&tr.done, 1)
+}
+
+// tracebuf writes a traceback to buf, or to stderr if buf is nil. It never
+// allocates and writes to stderr without stdio, so it can be used while other
+// threads are stopped in the middle of anything.
+type tracebuf struct {
+	buf []byte
+	n   int
+}
+
+//go:linkname c_write C.write
+func c_write(fd c.Int, p unsafe.Pointer, n uintptr) int
+
+func (w *tracebuf) str(s string) {
+	if w.buf == nil {
+		c_write(2, unsafe.Pointer(unsafe.StringData(s)), uintptr(len(s)))
+		return
+	}
+	w.n += copy(w.buf[w.n:], s)
+}
+
+func (w *tracebuf) int(v int64) {
+	var buf [20]byte
+	b := itoa(buf[:], uint64(v))
+	w.str(unsafe.String(&b[0], len(b)))
+}
+
+func (w *tracebuf) hex(v uint64) {
+	const digits = "0123456789abcdef"
+	var buf [18]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = digits[v&15]
+		v >>= 4
+		if v == 0 {
+			break
+		}
+	}
+	i--
+	buf[i] = 'x'
+	i--
+	buf[i] = '0'
+	w.str(unsafe.String(&buf[i], len(buf)-i))
+}
+
+func frameName(fr *debug.RawFrame) string {
+	return unsafe.String((*byte)(unsafe.Pointer(&fr.Name[0])), c.Strlen(&fr.Name[0]))
+}
+
+func hasPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+}
+
+func contains(s, substr string) bool {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if s[i:i+len(substr)] == substr {
+			return true
+		}
+	}
+	return false
+}
+
+// isUserFrame reports whether the frame belongs to Go code that should be
+// shown in tracebacks. Runtime internals and C functions are hidden.
+func isUserFrame(name string) bool {
+	if hasPrefix(name, rtPkgPrefix) || hasPrefix(name, "runtime.") {
+		return false
+	}
+	return contains(name, ".")
+}
+
+// isEntryFrame reports whether the frame is the entry of a goroutine, ie.
+// the C main function or the routine generated for a go statement.
+func isEntryFrame(name string) bool {
+	return name == "main" || name == "__main_argc_argv" || contains(name, "._llgo_routine$")
+}
+
+func (w *tracebuf) frames(pcs []uintptr) {
+	var fr debug.RawFrame
+	for _, pc := range pcs {
+		// pc is a return address: pc-1 is in the call instruction, which
+		// may be the last one of the function.
+		if !debug.Symbolize(pc-1, &fr) {
+			continue
+		}
+		name := frameName(&fr)
+		if isEntryFrame(name) {
+			break
+		}
+		if !isUserFrame(name) {
+			continue
+		}
+		w.str(name)
+		w.str("(...)\n\t+")
+		w.hex(uint64(fr.Offset + 1))
+		w.str("\n")
+	}
+}
+
+func (w *tracebuf) goroutine(gp *g, status string, tr *gtrace) {
+	w.str("goroutine ")
+	w.int(gp.goid)
+	w.str(" [")
+	w.str(status)
+	w.str("]:\n")
+	if tr != nil {
+		w.frames(tr.pcs[:tr.n])
+	} else {
+		w.str("\t(stack unavailable)\n")
+	}
+	if gp.parent != 0 {
+		// The go statement is symbolized only now: doing it when every
+		// goroutine starts would be too slow.
+		var fr debug.RawFrame
+		w.str("created by ")
+		if debug.Symbolize(gp.gopc, &fr) {
+			w.str(frameName(&fr))
+		} else {
+			w.str("?")
+		}
+		w.str(" in goroutine ")
+		w.int(gp.parent)
+		w.str("\n\t+")
+		w.hex(uint64(fr.Offset))
+		w.str("\n")
+	}
+}
+
+// gtraceback captures the stack of another goroutine. It must be called with
+// sched.mutex held so that gp cannot exit.
+func gtraceback(gp *g, tr *gtrace) *gtrace {
+	tr.n, tr.done = 0, 0
+	atomic.Store(&gp.trace, unsafe.Pointer(tr))
+	if debug.SendSigquit(gp.thread) == 0 {
+		for i := 0; i < tracebackTimeout; i++ {
+			if atomic.Load(&tr.done) != 0 {
+				return tr
+			}
+			c.Usleep(100)
+		}
+	}
+	if atomic.Exchange(&gp.trace, nil) != nil {
+		return nil // the request was never served
+	}
+	for atomic.Load(&tr.done) == 0 { // the handler is running
+		c.Usleep(100)
+	}
+	return tr
+}
+
+// tracebackothers writes the stacks of all goroutines except self. It must
+// be called with sched.mutex held.
+func tracebackothers(w *tracebuf, self *g, tr *gtrace) {
+	for gp := allgs; gp != nil; gp = gp.next {
+		if gp == self {
+			continue
+		}
+		w.str("\n")
+		w.goroutine(gp, gp.wait.String(), gtraceback(gp, tr))
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// Stack formats a stack trace of the calling goroutine into buf and returns
+// the number of bytes written to buf. If all is true, Stack formats stack
+// traces of all other goroutines into buf after the trace for the current
+// goroutine.
+func Stack(buf []byte, all bool) int {
+	if len(buf) == 0 {
+		return 0
+	}
+	w := &tracebuf{buf: buf}
+	self := getg()
+	tr := new(gtrace)
+	tr.capture(1)
+	w.goroutine(self, "running", tr)
+	if all {
+		sched.mutex.Lock()
+		tracebackothers(w, self, tr)
+		sched.mutex.Unlock()
+	}
+	return w.n
+}
+
+// NumGoroutine returns the number of goroutines that currently exist.
+func NumGoroutine() int {
+	sched.mutex.Lock()
+	n := sched.ngo
+	sched.mutex.Unlock()
+	return n
+}
+
+// sigquit handles SIGQUIT. It must only call async-signal-safe functions, so
+// a SIGQUIT sent to the process is handed over to sigquitDumper.
+func sigquit(c.Int) {
+	gp := (*g)(gKey.Get())
+	if gp != nil {
+		if tr := atomic.Exchange(&gp.trace, nil); tr != nil {
+			(*gtrace)(tr).capture(1)
+			return
+		}
+	}
+	debug.NotifySigquit()
+}
+
+var (
+	quitBuf   tracebuf
+	quitTrace gtrace
+)
+
+// sigquitDumper waits for SIGQUIT to be sent to the process, then dumps all
+// goroutines and exits.
+func sigquitDumper(c.Pointer) c.Pointer {
+	debug.WaitSigquit()
+	w := &quitBuf
+	w.str("SIGQUIT: quit\n")
+	locked := false
+	for i := 0; i < tracebackTimeout && !locked; i++ {
+		if locked = sched.mutex.TryLock() == 0; !locked {
+			c.Usleep(100)
+		}
+	}
+	if locked {
+		tracebackothers(w, nil, &quitTrace)
+	}
+	debug.Exit(2)
+	return nil
+}
+
+// -----------------------------------------------------------------------------
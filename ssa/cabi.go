@@ -0,0 +1,662 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"go/types"
+	"runtime"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// LLVM passes structs and arrays by value as first class aggregates, which is
+// not how C passes them: clang lowers them in its frontend, according to the C
+// ABI of the target. The functions called from C or calling C do the same
+// here, so that C functions taking or returning structs by value can be bound.
+
+// SetCABI sets whether the structs and arrays passed to or returned by C
+// functions follow the C ABI of the target. It applies to the C functions
+// called, to the C function pointers called, and to the Go functions called
+// from C: the exported ones and the ones passed to C as callbacks.
+func (p Program) SetCABI(on bool) {
+	p.cabi = on
+}
+
+// cabiKind is how a parameter or a result is passed to a C function.
+type cabiKind int
+
+const (
+	cabiDirect   cabiKind = iota // as is
+	cabiIgnore                   // not at all, it is empty
+	cabiCoerce                   // as a value of another type, with the same bytes
+	cabiIndirect                 // as a pointer to a copy, the sret pointer for a result
+)
+
+type cabiArg struct {
+	kind  cabiKind
+	typ   llvm.Type // the type of cabiCoerce, the aggregate of cabiIndirect
+	byval bool      // the copy of cabiIndirect is made on the stack, by the callee
+	align int       // the alignment of the copy of cabiIndirect
+}
+
+// cabiFunc is an LLVM function type lowered for the C ABI of the target.
+type cabiFunc struct {
+	ft     llvm.Type // the lowered function type
+	ret    cabiArg
+	params []cabiArg
+}
+
+// cabiClassifier classifies the result, then the parameters of a function for
+// the C ABI of a target.
+type cabiClassifier interface {
+	ret(t llvm.Type) cabiArg
+	param(t llvm.Type) cabiArg
+}
+
+func (p Program) cabiClassifier() cabiClassifier {
+	goos, goarch := p.target.GOOS, p.target.GOARCH
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	switch goarch {
+	case "amd64":
+		if goos == "windows" {
+			return win64ABI{p}
+		}
+		return &sysvABI{p, 6, 8}
+	case "arm64":
+		return aapcs64ABI{p}
+	case "wasm":
+		return wasmABI{p}
+	}
+	return nil // TODO: other targets pass aggregates as LLVM does
+}
+
+// cabiOf returns ft lowered for the C ABI of the target, or nil if it is
+// unchanged. vargs are the types of the variadic arguments of a call of ft:
+// they are lowered as the named parameters, as clang does, and the backend
+// passes them where the C ABI of the target puts variadic arguments, such as
+// on the stack on Darwin ARM64.
+func (p Program) cabiOf(ft llvm.Type, vargs ...llvm.Type) *cabiFunc {
+	if !p.cabi {
+		return nil
+	}
+	abi := p.cabiClassifier()
+	if abi == nil {
+		return nil
+	}
+	tret := ft.ReturnType()
+	params := ft.ParamTypes()
+	n := len(params)
+	params = append(params, vargs...)
+	fn := &cabiFunc{ret: abi.ret(tret), params: make([]cabiArg, len(params))}
+	lowered := fn.ret.kind != cabiDirect
+	for i, t := range params {
+		fn.params[i] = abi.param(t)
+		if fn.params[i].kind != cabiDirect {
+			lowered = true
+		}
+	}
+	if !lowered {
+		return nil
+	}
+	var ins []llvm.Type
+	switch fn.ret.kind {
+	case cabiCoerce:
+		tret = fn.ret.typ
+	case cabiIndirect:
+		ins = append(ins, llvm.PointerType(tret, 0))
+		fallthrough
+	case cabiIgnore:
+		tret = p.tyVoid()
+	}
+	for i, arg := range fn.params[:n] {
+		switch arg.kind {
+		case cabiDirect:
+			ins = append(ins, params[i])
+		case cabiCoerce:
+			ins = append(ins, cabiFlatten(arg.typ)...)
+		case cabiIndirect:
+			ins = append(ins, llvm.PointerType(params[i], 0))
+		}
+	}
+	fn.ft = llvm.FunctionType(tret, ins, ft.IsFunctionVarArg())
+	return fn
+}
+
+// cabiFlatten returns the types of the parameters a coerced type is passed
+// as: the fields of a struct, as clang does, or the type itself.
+func cabiFlatten(t llvm.Type) []llvm.Type {
+	if t.TypeKind() == llvm.StructTypeKind {
+		return t.StructElementTypes()
+	}
+	return []llvm.Type{t}
+}
+
+// setAttrs sets the sret and byval attributes of the lowered parameters, by
+// calling set(i, attr) with the attribute index i of the parameter.
+func (p *cabiFunc) setAttrs(ctx llvm.Context, set func(i int, attr llvm.Attribute)) {
+	i := 1 // attribute index of the first parameter
+	if p.ret.kind == cabiIndirect {
+		set(i, ctx.CreateTypeAttribute(llvm.AttributeKindID("sret"), p.ret.typ))
+		i++
+	}
+	for _, arg := range p.params {
+		switch arg.kind {
+		case cabiDirect, cabiIndirect:
+			if arg.byval {
+				set(i, ctx.CreateTypeAttribute(llvm.AttributeKindID("byval"), arg.typ))
+				set(i, ctx.CreateEnumAttribute(llvm.AttributeKindID("align"), uint64(arg.align)))
+			}
+			i++
+		case cabiCoerce:
+			i += len(cabiFlatten(arg.typ))
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func isAggregate(t llvm.Type) bool {
+	switch t.TypeKind() {
+	case llvm.StructTypeKind, llvm.ArrayTypeKind:
+		return true
+	}
+	return false
+}
+
+func isFloat(t llvm.Type) bool {
+	switch t.TypeKind() {
+	case llvm.FloatTypeKind, llvm.DoubleTypeKind:
+		return true
+	}
+	return false
+}
+
+// cabiLeaf is a scalar of an aggregate, at offset off.
+type cabiLeaf struct {
+	typ llvm.Type
+	off uint64
+}
+
+// leaves appends the scalars of t, at offset off, to ret.
+func (p Program) leaves(ret []cabiLeaf, t llvm.Type, off uint64) []cabiLeaf {
+	switch t.TypeKind() {
+	case llvm.StructTypeKind:
+		for i, elem := range t.StructElementTypes() {
+			ret = p.leaves(ret, elem, off+p.td.ElementOffset(t, i))
+		}
+	case llvm.ArrayTypeKind:
+		elem := t.ElementType()
+		size := p.td.TypeAllocSize(elem)
+		for i, n := 0, t.ArrayLength(); i < n; i++ {
+			ret = p.leaves(ret, elem, off+uint64(i)*size)
+		}
+	default:
+		ret = append(ret, cabiLeaf{t, off})
+	}
+	return ret
+}
+
+// homogeneous returns the type and the number of the scalars of t if they are
+// all floats or all doubles.
+func (p Program) homogeneous(t llvm.Type) (elem llvm.Type, n int) {
+	leaves := p.leaves(nil, t, 0)
+	for _, leaf := range leaves {
+		if !isFloat(leaf.typ) || (n > 0 && leaf.typ != elem) {
+			return elem, 0
+		}
+		elem = leaf.typ
+		n++
+	}
+	return
+}
+
+// singleElement returns the scalar of t if t is a struct or an array of a
+// single scalar, padding aside.
+func (p Program) singleElement(t llvm.Type) (elem llvm.Type, ok bool) {
+	if p.td.TypeAllocSize(t) > 8 {
+		return
+	}
+	leaves := p.leaves(nil, t, 0)
+	for _, leaf := range leaves {
+		if p.td.TypeAllocSize(leaf.typ) != 0 {
+			if ok {
+				return elem, false
+			}
+			elem, ok = leaf.typ, true
+		}
+	}
+	if ok && p.td.TypeAllocSize(elem) != p.td.TypeAllocSize(t) {
+		return elem, false
+	}
+	return
+}
+
+func (p Program) indirectArg(t llvm.Type, byval bool, minAlign int) cabiArg {
+	return cabiArg{kind: cabiIndirect, typ: t, byval: byval, align: max(p.td.ABITypeAlignment(t), minAlign)}
+}
+
+func (p Program) coerceArg(t llvm.Type) cabiArg {
+	return cabiArg{kind: cabiCoerce, typ: t}
+}
+
+// -----------------------------------------------------------------------------
+
+// sysvABI is the System V ABI of x86-64: aggregates up to 16 bytes are passed
+// in the integer or SSE registers of their eightbytes, if there are enough
+// left, and on the stack otherwise.
+type sysvABI struct {
+	Program
+	ints int // integer registers left
+	sses int // SSE registers left
+}
+
+const (
+	sysvNone = iota
+	sysvInteger
+	sysvSSE
+)
+
+func (p *sysvABI) ret(t llvm.Type) cabiArg {
+	if !isAggregate(t) {
+		return cabiArg{}
+	}
+	size := p.td.TypeAllocSize(t)
+	if size == 0 {
+		return cabiArg{kind: cabiIgnore}
+	}
+	if size > 16 {
+		p.ints-- // the sret pointer
+		return p.indirectArg(t, false, 0)
+	}
+	typ, _, _ := p.eightbytes(t, size)
+	return p.coerceArg(typ)
+}
+
+func (p *sysvABI) param(t llvm.Type) cabiArg {
+	if !isAggregate(t) {
+		if isFloat(t) {
+			p.sses--
+		} else {
+			p.ints--
+		}
+		return cabiArg{}
+	}
+	size := p.td.TypeAllocSize(t)
+	if size == 0 {
+		return cabiArg{kind: cabiIgnore}
+	}
+	if size <= 16 {
+		typ, ints, sses := p.eightbytes(t, size)
+		if ints <= p.ints && sses <= p.sses {
+			p.ints -= ints
+			p.sses -= sses
+			return p.coerceArg(typ)
+		}
+	}
+	return p.indirectArg(t, true, 8)
+}
+
+// eightbytes returns the type t of size bytes is coerced to, and the number of
+// integer and SSE registers it takes.
+func (p *sysvABI) eightbytes(t llvm.Type, size uint64) (typ llvm.Type, ints, sses int) {
+	leaves := p.leaves(nil, t, 0)
+	n := int((size + 7) / 8)
+	var classes [2]int
+	for _, leaf := range leaves {
+		i := leaf.off / 8
+		if isFloat(leaf.typ) {
+			if classes[i] == sysvNone {
+				classes[i] = sysvSSE
+			}
+		} else {
+			classes[i] = sysvInteger
+		}
+	}
+	ctx := p.ctx
+	elems := make([]llvm.Type, n)
+	for i := range elems {
+		lo := uint64(i) * 8
+		if classes[i] == sysvSSE {
+			sses++
+			var floats int
+			elems[i] = ctx.DoubleType()
+			for _, leaf := range leaves {
+				if leaf.off >= lo && leaf.off < lo+8 && leaf.typ.TypeKind() == llvm.FloatTypeKind {
+					floats++
+				}
+			}
+			switch floats {
+			case 1:
+				elems[i] = ctx.FloatType()
+			case 2:
+				elems[i] = llvm.VectorType(ctx.FloatType(), 2)
+			}
+		} else {
+			ints++
+			elems[i] = ctx.IntType(int(min(8, size-lo) * 8))
+		}
+	}
+	if n == 1 {
+		return elems[0], ints, sses
+	}
+	return ctx.StructType(elems, false), ints, sses
+}
+
+// win64ABI is the x64 ABI of Windows: aggregates of 1, 2, 4 or 8 bytes are
+// passed as integers, and the other ones by pointer to a copy.
+type win64ABI struct {
+	Program
+}
+
+func (p win64ABI) ret(t llvm.Type) cabiArg {
+	return p.param(t)
+}
+
+func (p win64ABI) param(t llvm.Type) cabiArg {
+	if !isAggregate(t) {
+		return cabiArg{}
+	}
+	switch size := p.td.TypeAllocSize(t); size {
+	case 0:
+		return cabiArg{kind: cabiIgnore}
+	case 1, 2, 4, 8:
+		return p.coerceArg(p.ctx.IntType(int(size * 8)))
+	}
+	return p.indirectArg(t, false, 0)
+}
+
+// aapcs64ABI is the procedure call standard of ARM64: homogeneous aggregates
+// of up to 4 floats or doubles are passed in floating-point registers, other
+// aggregates up to 16 bytes in integer registers, and the larger ones by
+// pointer to a copy.
+type aapcs64ABI struct {
+	Program
+}
+
+func (p aapcs64ABI) ret(t llvm.Type) cabiArg {
+	if !isAggregate(t) {
+		return cabiArg{}
+	}
+	if size := p.td.TypeAllocSize(t); size > 0 && size <= 8 {
+		if _, n := p.homogeneous(t); n == 0 {
+			return p.coerceArg(p.ctx.IntType(int(size * 8)))
+		}
+	}
+	return p.param(t)
+}
+
+func (p aapcs64ABI) param(t llvm.Type) cabiArg {
+	if !isAggregate(t) {
+		return cabiArg{}
+	}
+	size := p.td.TypeAllocSize(t)
+	switch {
+	case size == 0:
+		return cabiArg{kind: cabiIgnore}
+	case size <= 32:
+		if elem, n := p.homogeneous(t); n > 0 && n <= 4 {
+			return p.coerceArg(llvm.ArrayType(elem, n))
+		}
+		if size <= 8 {
+			return p.coerceArg(p.ctx.Int64Type())
+		} else if size <= 16 {
+			// clang passes the aggregates aligned to 16 bytes as i128, which
+			// starts at an even register
+			if p.td.ABITypeAlignment(t) >= 16 {
+				return p.coerceArg(p.ctx.IntType(128))
+			}
+			return p.coerceArg(llvm.ArrayType(p.ctx.Int64Type(), 2))
+		}
+	}
+	return p.indirectArg(t, false, 0)
+}
+
+// wasmABI is the C ABI of WebAssembly: aggregates of a single scalar are
+// passed as the scalar, and the other ones by pointer to a copy.
+type wasmABI struct {
+	Program
+}
+
+func (p wasmABI) ret(t llvm.Type) cabiArg {
+	return p.param(t)
+}
+
+func (p wasmABI) param(t llvm.Type) cabiArg {
+	if !isAggregate(t) {
+		return cabiArg{}
+	}
+	if p.td.TypeAllocSize(t) == 0 {
+		return cabiArg{kind: cabiIgnore}
+	}
+	if elem, ok := p.singleElement(t); ok {
+		return p.coerceArg(elem)
+	}
+	return p.indirectArg(t, true, 0)
+}
+
+// -----------------------------------------------------------------------------
+
+// cabiSlot allocates a stack slot large and aligned enough for the types ts,
+// in the entry block of the function, so that calls in loops do not grow the
+// stack.
+func (b Builder) cabiSlot(ts ...llvm.Type) llvm.Value {
+	td := b.Prog.td
+	t, align := ts[0], 0
+	for _, typ := range ts {
+		if td.TypeAllocSize(typ) > td.TypeAllocSize(t) {
+			t = typ
+		}
+		align = max(align, td.ABITypeAlignment(typ))
+	}
+	entry := b.Func.impl.EntryBasicBlock()
+	eb := b.Prog.ctx.NewBuilder()
+	defer eb.Dispose()
+	if first := entry.FirstInstruction(); first.IsNil() {
+		eb.SetInsertPointAtEnd(entry)
+	} else {
+		eb.SetInsertPointBefore(first)
+	}
+	slot := llvm.CreateAlloca(eb, t)
+	slot.SetAlignment(align)
+	return slot
+}
+
+// store stores v at ptr, whatever the type ptr points to.
+func (b Builder) storeAs(v, ptr llvm.Value) {
+	b.impl.CreateStore(v, llvm.CreatePointerCast(b.impl, ptr, llvm.PointerType(v.Type(), 0)))
+}
+
+// loadAs loads a value of type t from ptr, whatever the type ptr points to.
+func (b Builder) loadAs(t llvm.Type, ptr llvm.Value) llvm.Value {
+	return llvm.CreateLoad(b.impl, t, llvm.CreatePointerCast(b.impl, ptr, llvm.PointerType(t, 0)))
+}
+
+// coerce reinterprets the bytes of v as a value of type t.
+func (b Builder) coerce(v llvm.Value, t llvm.Type) llvm.Value {
+	slot := b.cabiSlot(v.Type(), t)
+	b.storeAs(v, slot)
+	return b.loadAs(t, slot)
+}
+
+// cabiCall calls fn, of the lowered function type p, with the arguments args
+// of the function type ft it is lowered from. It returns the result of ft.
+func (b Builder) cabiCall(p *cabiFunc, fn llvm.Value, args []llvm.Value, ft llvm.Type) llvm.Value {
+	tret := ft.ReturnType()
+	var sret llvm.Value
+	var ins []llvm.Value
+	if p.ret.kind == cabiIndirect {
+		sret = b.cabiSlot(tret)
+		ins = append(ins, sret)
+	}
+	for i, arg := range args {
+		if i >= len(p.params) { // variadic arguments
+			ins = append(ins, arg)
+			continue
+		}
+		switch param := p.params[i]; param.kind {
+		case cabiDirect:
+			ins = append(ins, arg)
+		case cabiCoerce:
+			v := b.coerce(arg, param.typ)
+			if param.typ.TypeKind() != llvm.StructTypeKind {
+				ins = append(ins, v)
+				break
+			}
+			for k := range param.typ.StructElementTypesCount() {
+				ins = append(ins, llvm.CreateExtractValue(b.impl, v, k))
+			}
+		case cabiIndirect:
+			slot := b.cabiSlot(arg.Type())
+			b.storeAs(arg, slot)
+			ins = append(ins, slot)
+		}
+	}
+	fn = llvm.CreatePointerCast(b.impl, fn, llvm.PointerType(p.ft, 0))
+	call := llvm.CreateCall(b.impl, p.ft, fn, ins)
+	p.setAttrs(b.Prog.ctx, call.AddCallSiteAttribute)
+	switch p.ret.kind {
+	case cabiIgnore:
+		return llvm.ConstNull(tret)
+	case cabiCoerce:
+		return b.coerce(call, tret)
+	case cabiIndirect:
+		return llvm.CreateLoad(b.impl, tret, sret)
+	}
+	return call
+}
+
+// cabiParams returns the parameters of the function type fn is lowered from,
+// made of the parameters ins of fn, of the lowered function type p.
+func (b Builder) cabiParams(p *cabiFunc, ins []llvm.Value, params []llvm.Type) []llvm.Value {
+	if p.ret.kind == cabiIndirect {
+		ins = ins[1:]
+	}
+	ret := make([]llvm.Value, len(params))
+	for i, t := range params {
+		switch param := p.params[i]; param.kind {
+		case cabiDirect:
+			ret[i], ins = ins[0], ins[1:]
+		case cabiIgnore:
+			ret[i] = llvm.ConstNull(t)
+		case cabiCoerce:
+			v := ins[0]
+			if param.typ.TypeKind() == llvm.StructTypeKind {
+				v = llvm.Undef(param.typ)
+				for k := range param.typ.StructElementTypesCount() {
+					v = b.impl.CreateInsertValue(v, ins[k], k, "")
+				}
+			}
+			ins = ins[len(cabiFlatten(param.typ)):]
+			ret[i] = b.coerce(v, t)
+		case cabiIndirect:
+			ret[i], ins = llvm.CreateLoad(b.impl, t, ins[0]), ins[1:]
+		}
+	}
+	return ret
+}
+
+// cabiReturn returns v from the function b builds, of the lowered function
+// type p.
+func (b Builder) cabiReturn(p *cabiFunc, v llvm.Value) {
+	switch p.ret.kind {
+	case cabiIgnore:
+		b.impl.CreateRetVoid()
+	case cabiCoerce:
+		b.impl.CreateRet(b.coerce(v, p.ret.typ))
+	case cabiIndirect:
+		b.impl.CreateStore(v, b.Func.impl.Param(0))
+		b.impl.CreateRetVoid()
+	default:
+		b.impl.CreateRet(v)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// CFuncAddr returns the address of the function fn to be called from C: fn
+// itself if it follows the C ABI of the target, or a wrapper of fn that does.
+func (b Builder) CFuncAddr(fn Expr) Expr {
+	if fn.kind != vkFuncDecl || fn.impl.GlobalValueType() != fn.ll || b.Prog.cabiOf(fn.ll) == nil {
+		return fn
+	}
+	w := b.Pkg.cabiWrapper(fn, fn.impl.Name()+"$cabi", false)
+	w.impl.SetLinkage(llvm.LinkOnceAnyLinkage)
+	t := b.Prog.rawType(fn.raw.Type)
+	return Expr{llvm.CreatePointerCast(b.impl, w.impl, t.ll), t}
+}
+
+// CExport exports the function to C as name. If the C ABI of the target
+// passes its parameters or results differently, the function keeps its name
+// and a wrapper named name calls it.
+func (p Function) CExport(name string) {
+	if p.cabi == nil && p.impl.GlobalValueType() == p.ll && p.Prog.cabiOf(p.ll) != nil {
+		p.Pkg.cabiWrapper(p.Expr, name, false)
+		return
+	}
+	p.impl.SetName(name)
+}
+
+// CgoExport exports the function to C as name, for an //export directive of
+// cgo. Unlike CExport, it always goes through a wrapper, which attaches the
+// calling thread to the runtime first, so that C may call it from threads
+// that were not created by Go.
+func (p Function) CgoExport(name string) {
+	p.Pkg.cabiWrapper(p.Expr, name, true)
+}
+
+// cabiWrapper returns the function named name which follows the C ABI of the
+// target and calls fn. If cgo is set, it calls runtime.CgoCallback first.
+func (p Package) cabiWrapper(fn Expr, name string, cgo bool) Function {
+	w := p.fns[name]
+	if w == nil {
+		w = p.newFunc(name, fn.Type, p.Prog.cabiOf(fn.ll), false, false)
+	}
+	if w.HasBody() {
+		return w
+	}
+	b := w.MakeBody(1)
+	if cgo {
+		b.Call(p.rtFunc("CgoCallback"))
+	}
+	args := make([]Expr, len(w.params))
+	for i := range args {
+		args[i] = w.Param(i)
+	}
+	ret := b.Call(fn, args...)
+	switch n := fn.raw.Type.(*types.Signature).Results().Len(); n {
+	case 0:
+		b.Return()
+	case 1:
+		b.Return(ret)
+	default:
+		rets := make([]Expr, n)
+		for i := range rets {
+			rets[i] = b.Extract(ret, i)
+		}
+		b.Return(rets...)
+	}
+	return w
+}
+
+// -----------------------------------------------------------------------------
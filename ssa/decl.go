@@ -187,6 +187,9 @@ type aFunction struct {
 	base     int // base = 1 if hasFreeVars; base = 0 otherwise
 	hasVArg  bool
 
+	cabi    *cabiFunc    // the function type lowered for the C ABI, if it is
+	cparams []llvm.Value // the parameters rebuilt from the lowered ones
+
 	diFunc DIFunction
 }
 
@@ -204,14 +207,32 @@ func (p Package) NewFuncEx(name string, sig *types.Signature, bg Background, has
 		return v
 	}
 	t := p.Prog.FuncDecl(sig, bg)
+	var cabi *cabiFunc
+	if bg == InC {
+		cabi = p.Prog.cabiOf(t.ll)
+	}
+	return p.newFunc(name, t, cabi, hasFreeVars, instantiated)
+}
+
+// newFunc creates a new function of type t, declared with the lowered type of
+// cabi if it is not nil.
+func (p Package) newFunc(name string, t Type, cabi *cabiFunc, hasFreeVars bool, instantiated bool) Function {
 	if debugInstr {
 		log.Println("NewFunc", name, t.raw.Type, "hasFreeVars:", hasFreeVars)
 	}
-	fn := llvm.AddFunction(p.mod, name, t.ll)
+	ft := t.ll
+	if cabi != nil {
+		ft = cabi.ft
+	}
+	fn := llvm.AddFunction(p.mod, name, ft)
+	if cabi != nil {
+		cabi.setAttrs(p.Prog.ctx, fn.AddAttributeAtIndex)
+	}
 	if instantiated {
 		fn.SetLinkage(llvm.LinkOnceAnyLinkage)
 	}
 	ret := newFunction(fn, t, p, p.Prog, hasFreeVars)
+	ret.cabi = cabi
 	p.fns[name] = ret
 	return ret
 }
@@ -260,6 +281,9 @@ func (p Function) Name() string {
 // Params returns the function's ith parameter.
 func (p Function) Param(i int) Expr {
 	i += p.base // skip if hasFreeVars
+	if p.cabi != nil {
+		return Expr{p.cparams[i], p.params[i]}
+	}
 	return Expr{p.impl.Param(i), p.params[i]}
 }
 
@@ -321,6 +345,11 @@ func (p Function) MakeBlocks(nblk int) []BasicBlock {
 	for i := 0; i < nblk; i++ {
 		p.addBlock(n + i)
 	}
+	if n == 0 && p.cabi != nil {
+		b := p.NewBuilder()
+		b.impl.SetInsertPointAtEnd(p.blks[0].first)
+		p.cparams = b.cabiParams(p.cabi, p.impl.Params(), p.ll.ParamTypes())
+	}
 	return p.blks[n:]
 }
 
@@ -367,4 +396,18 @@ func (p Function) Inline(inline inlineAttr) {
 	p.impl.AddFunctionAttr(inlineAttr)
 }
 
+// WasmImport makes the function, which must have no body, an import of a
+// WebAssembly module.
+func (p Function) WasmImport(module, name string) {
+	ctx := p.Pkg.mod.Context()
+	p.impl.AddFunctionAttr(ctx.CreateStringAttribute("wasm-import-module", module))
+	p.impl.AddFunctionAttr(ctx.CreateStringAttribute("wasm-import-name", name))
+}
+
+// WasmExport exports the function from a WebAssembly module.
+func (p Function) WasmExport(name string) {
+	ctx := p.Pkg.mod.Context()
+	p.impl.AddFunctionAttr(ctx.CreateStringAttribute("wasm-export-name", name))
+}
+
 // -----------------------------------------------------------------------------
@@ -253,6 +253,22 @@ func (b Builder) DeferData() Expr {
 	return Expr{b.pthreadGetspecific(key).impl, b.Prog.DeferPtr()}
 }
 
+// ReturnAddress returns the address the current function returns to, or nil
+// on wasm, which does not expose it.
+//
+// declare ptr @llvm.returnaddress(i32 <level>)
+func (b Builder) ReturnAddress() Expr {
+	prog := b.Prog
+	if prog.target.GOARCH == "wasm" {
+		return prog.Nil(prog.VoidPtr())
+	}
+	level := types.NewParam(token.NoPos, nil, "", types.Typ[types.Int32])
+	ret := types.NewParam(token.NoPos, nil, "", types.Typ[types.UnsafePointer])
+	sig := types.NewSignatureType(nil, nil, nil, types.NewTuple(level), types.NewTuple(ret), false)
+	fn := b.Pkg.cFunc("llvm.returnaddress", sig)
+	return b.Call(fn, prog.IntVal(0, prog.Int32()))
+}
+
 // Defer emits a defer instruction.
 func (b Builder) Defer(kind DoAction, fn Expr, args ...Expr) {
 	if debugInstr {
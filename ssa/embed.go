@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"crypto/sha256"
+	"go/types"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+
+// An EmbedFile is a file of a variable declared with a //go:embed directive.
+type EmbedFile struct {
+	Name string // slash-separated path, relative to the package directory
+	Data []byte
+}
+
+// InitEmbed initializes the global variable g, of type string, []byte or
+// embed.FS, with the contents of files. A string or []byte has exactly one
+// file. The files of an embed.FS must be sorted as the embed package expects
+// and include their directories, named with a trailing slash.
+func (p Package) InitEmbed(g Global, files []EmbedFile) {
+	t := p.Prog.Elem(g.Type)
+	switch u := t.raw.Type.Underlying().(type) {
+	case *types.Basic:
+		g.impl.SetInitializer(p.constStr(string(files[0].Data)))
+	case *types.Slice:
+		g.impl.SetInitializer(p.constBytes(files[0].Data))
+	case *types.Struct: // embed.FS{files *[]file}
+		tfile := u.Field(0).Type().(*types.Pointer).Elem().(*types.Slice).Elem()
+		g.impl.SetInitializer(p.constEmbedFS(t, p.Prog.rawType(tfile), files))
+	default:
+		panic("InitEmbed: unexpected type " + t.raw.Type.String())
+	}
+}
+
+func (p Package) constStr(v string) llvm.Value {
+	prog := p.Prog
+	size := prog.IntVal(uint64(len(v)), prog.Int()).impl
+	return llvm.ConstNamedStruct(prog.rtString(), []llvm.Value{p.createGlobalStr(v), size})
+}
+
+func (p Package) constBytes(v []byte) llvm.Value {
+	prog := p.Prog
+	data := llvm.ConstNull(prog.CStr().ll)
+	if len(v) > 0 {
+		typ := llvm.ArrayType(prog.tyInt8(), len(v))
+		global := llvm.AddGlobal(p.mod, typ, "")
+		global.SetInitializer(prog.ctx.ConstString(string(v), false))
+		global.SetLinkage(llvm.PrivateLinkage)
+		global.SetAlignment(1)
+		data = llvm.ConstInBoundsGEP(typ, global, []llvm.Value{prog.Val(0).impl})
+	}
+	size := prog.IntVal(uint64(len(v)), prog.Int()).impl
+	return llvm.ConstNamedStruct(prog.rtSlice(), []llvm.Value{data, size, size})
+}
+
+func (p Package) constEmbedFS(tfs, tfile Type, files []EmbedFile) llvm.Value {
+	prog := p.Prog
+	tint8 := prog.tyInt8()
+	flds := make([]llvm.Value, len(files))
+	for i, f := range files {
+		var sum [sha256.Size]byte
+		if !isEmbedDir(f.Name) {
+			sum = sha256.Sum256(f.Data)
+		}
+		hash := make([]llvm.Value, 16) // truncated SHA256 hash
+		for j := range hash {
+			hash[j] = llvm.ConstInt(tint8, uint64(sum[j]), false)
+		}
+		flds[i] = llvm.ConstNamedStruct(tfile.ll, []llvm.Value{
+			p.constStr(f.Name), p.constStr(string(f.Data)), llvm.ConstArray(tint8, hash),
+		})
+	}
+	typ := llvm.ArrayType(tfile.ll, len(files))
+	array := llvm.AddGlobal(p.mod, typ, "")
+	array.SetInitializer(llvm.ConstArray(tfile.ll, flds))
+	array.SetLinkage(llvm.PrivateLinkage)
+	array.SetGlobalConstant(true)
+	size := prog.IntVal(uint64(len(files)), prog.Int()).impl
+	data := llvm.ConstInBoundsGEP(typ, array, []llvm.Value{prog.Val(0).impl})
+	slice := llvm.AddGlobal(p.mod, prog.rtSlice(), "")
+	slice.SetInitializer(llvm.ConstNamedStruct(prog.rtSlice(), []llvm.Value{data, size, size}))
+	slice.SetLinkage(llvm.PrivateLinkage)
+	slice.SetGlobalConstant(true)
+	return llvm.ConstNamedStruct(tfs.ll, []llvm.Value{slice})
+}
+
+func isEmbedDir(name string) bool {
+	return len(name) > 0 && name[len(name)-1] == '/'
+}
+
+// -----------------------------------------------------------------------------
@@ -129,10 +129,10 @@ func (p Program) Zero(t Type) Expr {
 			ret = llvm.ConstFloat(p.Float32().ll, 0)
 		case kind == types.Complex64:
 			v := llvm.ConstFloat(p.Float32().ll, 0)
-			ret = llvm.ConstStruct([]llvm.Value{v, v}, false)
+			ret = p.ctx.ConstStruct([]llvm.Value{v, v}, false)
 		case kind == types.Complex128:
 			v := llvm.ConstFloat(p.Float64().ll, 0)
-			ret = llvm.ConstStruct([]llvm.Value{v, v}, false)
+			ret = p.ctx.ConstStruct([]llvm.Value{v, v}, false)
 		default:
 			panic("todo")
 		}
@@ -144,7 +144,7 @@ func (p Program) Zero(t Type) Expr {
 		for i := 0; i < n; i++ {
 			flds[i] = p.Zero(p.rawType(u.Field(i).Type())).impl
 		}
-		ret = llvm.ConstStruct(flds, false)
+		ret = p.ctx.ConstStruct(flds, false)
 	case *types.Slice:
 		ret = p.Zero(p.rtType("Slice")).impl
 	case *types.Array:
@@ -198,7 +198,7 @@ func (p Program) ComplexVal(v complex128, t Type) Expr {
 	flt := p.Field(t, 0)
 	re := p.FloatVal(real(v), flt)
 	im := p.FloatVal(imag(v), flt)
-	return Expr{llvm.ConstStruct([]llvm.Value{re.impl, im.impl}, false), t}
+	return Expr{p.ctx.ConstStruct([]llvm.Value{re.impl, im.impl}, false), t}
 }
 
 // Val returns a constant expression.
@@ -307,14 +307,18 @@ func (b Builder) Str(v string) Expr {
 }
 
 func (b Builder) createGlobalStr(v string) (ret llvm.Value) {
-	if ret, ok := b.Pkg.strs[v]; ok {
+	return b.Pkg.createGlobalStr(v)
+}
+
+func (p Package) createGlobalStr(v string) (ret llvm.Value) {
+	if ret, ok := p.strs[v]; ok {
 		return ret
 	}
-	prog := b.Prog
+	prog := p.Prog
 	if v != "" {
 		typ := llvm.ArrayType(prog.tyInt8(), len(v))
-		global := llvm.AddGlobal(b.Pkg.mod, typ, "")
-		global.SetInitializer(b.Prog.ctx.ConstString(v, false))
+		global := llvm.AddGlobal(p.mod, typ, "")
+		global.SetInitializer(prog.ctx.ConstString(v, false))
 		global.SetLinkage(llvm.PrivateLinkage)
 		global.SetGlobalConstant(true)
 		global.SetUnnamedAddr(true)
@@ -323,7 +327,7 @@ func (b Builder) createGlobalStr(v string) (ret llvm.Value) {
 	} else {
 		ret = llvm.ConstNull(prog.CStr().ll)
 	}
-	b.Pkg.strs[v] = ret
+	p.strs[v] = ret
 	return
 }
 
@@ -1009,6 +1013,7 @@ func (b Builder) Call(fn Expr, args ...Expr) (ret Expr) {
 	var ll llvm.Type
 	var data Expr
 	var sig *types.Signature
+	var isC bool // the function follows the C ABI of the target
 	var raw = fn.raw.Type
 	switch kind {
 	case vkClosure:
@@ -1020,9 +1025,12 @@ func (b Builder) Call(fn Expr, args ...Expr) (ret Expr) {
 	case vkFuncPtr:
 		sig = raw.Underlying().(*types.Signature)
 		ll = b.Prog.FuncDecl(sig, InC).ll
+		isC = data.IsNil() // a C function pointer
 	case vkFuncDecl:
 		sig = raw.(*types.Signature)
 		ll = fn.ll
+		// declared with the C ABI, or a variadic C function
+		isC = fn.impl.GlobalValueType() != ll || ll.IsFunctionVarArg()
 	case vkBuiltin:
 		bi := raw.(*builtinTy)
 		return b.BuiltinCall(bi.name, args...)
@@ -1030,7 +1038,18 @@ func (b Builder) Call(fn Expr, args ...Expr) (ret Expr) {
 		log.Panicf("unreachable: %d(%T), %v\n", kind, raw, fn.RawType())
 	}
 	ret.Type = b.Prog.retType(sig)
-	ret.impl = llvm.CreateCall(b.impl, ll, fn.impl, llvmParamsEx(data, args, sig.Params(), b))
+	params := llvmParamsEx(data, args, sig.Params(), b)
+	if isC {
+		var vargs []llvm.Type
+		for _, v := range params[min(ll.ParamTypesCount(), len(params)):] {
+			vargs = append(vargs, v.Type())
+		}
+		if cabi := b.Prog.cabiOf(ll, vargs...); cabi != nil {
+			ret.impl = b.cabiCall(cabi, fn.impl, params, ll)
+			return
+		}
+	}
+	ret.impl = llvm.CreateCall(b.impl, ll, fn.impl, params)
 	return
 }
 
@@ -1342,10 +1361,15 @@ func (b Builder) PrintEx(ln bool, args ...Expr) (ret Expr) {
 // -----------------------------------------------------------------------------
 
 func checkExpr(v Expr, t types.Type, b Builder) Expr {
-	if st, ok := t.Underlying().(*types.Struct); ok && isClosure(st) {
-		if v.kind != vkClosure {
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		if isClosure(u) && v.kind != vkClosure {
 			return b.Pkg.closureStub(b, t, v)
 		}
+	case *types.Signature: // a C function pointer
+		if v.kind == vkFuncDecl {
+			return b.CFuncAddr(v)
+		}
 	}
 	return v
 }
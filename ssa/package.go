@@ -116,6 +116,9 @@ type aProgram struct {
 
 	py    *types.Package
 	pyget func() *types.Package
+	pyExc bool // Python exceptions panic
+	pyGIL bool // Python operations hold the GIL
+	cabi  bool // C functions follow the C ABI of the target
 
 	target *Target
 	td     llvm.TargetData
@@ -711,7 +714,7 @@ func (p Package) closureStub(b Builder, t types.Type, v Expr) Expr {
 		nret := sig.Results().Len()
 		ctx := types.NewParam(token.NoPos, nil, closureCtx, types.Typ[types.UnsafePointer])
 		sig = FuncAddCtx(ctx, sig)
-		fn := p.NewFunc(closureStub+name, sig, InC)
+		fn := p.newFunc(closureStub+name, prog.FuncDecl(sig, InC), nil, false, false)
 		fn.impl.SetLinkage(llvm.LinkOnceAnyLinkage)
 		args := make([]Expr, n)
 		for i := 0; i < n; i++ {
@@ -744,6 +747,11 @@ func (p Package) String() string {
 	return p.mod.String()
 }
 
+// Bitcode returns the LLVM bitcode of the package in a memory buffer.
+func (p Package) Bitcode() llvm.MemoryBuffer {
+	return llvm.WriteBitcodeToMemoryBuffer(p.mod)
+}
+
 // SetPatch sets a patch function.
 func (p Package) SetPatch(fn func(types.Type) types.Type) {
 	p.patch = fn
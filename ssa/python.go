@@ -57,6 +57,20 @@ func (p Program) python() *types.Package {
 	return p.py
 }
 
+// SetPyExceptions sets whether a Python call without an error result panics
+// when it raises an exception, instead of returning a nil object. The panic
+// value is the error a call with an error result returns.
+func (p Program) SetPyExceptions(on bool) {
+	p.pyExc = on
+}
+
+// SetPyGIL sets whether the Python operations generated by the compiler hold
+// the GIL, acquired with PyGILState_Ensure and released with
+// PyGILState_Release, so that they can run on any goroutine.
+func (p Program) SetPyGIL(on bool) {
+	p.pyGIL = on
+}
+
 // SetPython sets the Python package.
 // Its type can be *types.Package or func() *types.Package.
 func (p Program) SetPython(py any) {
@@ -250,6 +264,8 @@ func (p Package) PyNewModVar(name string, doInit bool) Global {
 // PyImportMod imports a Python module.
 func (b Builder) PyImportMod(path string) Expr {
 	fnImp := b.Pkg.pyFunc("PyImport_ImportModule", b.Prog.tyImportPyModule())
+	state := b.PyGILEnsure()
+	defer b.PyGILRelease(state)
 	return b.Call(fnImp, b.CStr(path))
 }
 
@@ -270,6 +286,8 @@ func (b Builder) PyLoadModSyms(modName string, objs ...PyObjRef) Expr {
 	}
 	prog := b.Prog
 	args = append(args, prog.Nil(prog.CStr()))
+	state := b.PyGILEnsure()
+	defer b.PyGILRelease(state)
 	return b.Call(fnLoad, args...)
 }
 
@@ -280,6 +298,17 @@ func (b Builder) pyCall(fn Expr, args []Expr) (ret Expr) {
 	sig := fn.raw.Type.(*types.Signature)
 	params := sig.Params()
 	n := params.Len()
+	objPtr := prog.PyObjectPtr()
+	fn = Expr{castPtr(b.impl, fn.impl, objPtr.ll), objPtr}
+	state := b.PyGILEnsure()
+
+	// Go values are passed as new Python objects, released after the call.
+	// Python objects are passed as is.
+	vals := args
+	args = make([]Expr, len(vals))
+	for i, val := range vals {
+		args[i] = b.PyVal(val)
+	}
 	switch n {
 	case 0:
 		call := pkg.pyFunc("PyObject_CallNoArgs", prog.tyCallNoArgs())
@@ -287,7 +316,8 @@ func (b Builder) pyCall(fn Expr, args []Expr) (ret Expr) {
 	case 1:
 		if !sig.Variadic() {
 			call := pkg.pyFunc("PyObject_CallOneArg", prog.tyCallOneArg())
-			return b.Call(call, fn, args[0])
+			ret = b.Call(call, fn, args[0])
+			break
 		}
 		fallthrough
 	default:
@@ -299,7 +329,160 @@ func (b Builder) pyCall(fn Expr, args []Expr) (ret Expr) {
 		callargs[n+1] = prog.Nil(prog.PyObjectPtr())
 		ret = b.Call(call, callargs...)
 	}
-	return
+	for i, val := range vals {
+		b.pyReleaseVal(val, args[i])
+	}
+
+	// A NULL result reports a Python exception: it is the error result of
+	// the call or, if SetPyExceptions is on, it panics. So does the
+	// exception raised by the conversion of the result to a Go value, eg. of
+	// an int out of the range of int64.
+	results := sig.Results()
+	nret := results.Len()
+	if nret == 0 || !isError(results.At(nret-1).Type()) {
+		panicIf := func(cond Expr) {
+			blks := b.Func.MakeBlocks(2)
+			b.If(cond, blks[0], blks[1])
+			b.SetBlockEx(blks[0], AtEnd, false)
+			err := b.Call(pkg.pyError())
+			b.PyGILRelease(state)
+			b.Panic(b.ChangeInterface(prog.Any(), err))
+			b.SetBlockEx(blks[1], AtEnd, false)
+			b.blk.last = blks[1].last
+		}
+		if prog.pyExc {
+			panicIf(b.BinOp(token.EQL, ret, prog.Nil(prog.PyObjectPtr())))
+		}
+		if nret == 1 {
+			t := prog.rawType(results.At(0).Type())
+			ret = b.pyResult(ret, t)
+			if prog.pyExc && !b.isPyObject(t.raw.Type) {
+				panicIf(b.pyErrOccurred())
+			}
+		}
+		b.PyGILRelease(state)
+		return
+	}
+	tret := prog.retType(sig)
+	blks := b.Func.MakeBlocks(3)
+	b.If(b.BinOp(token.EQL, ret, prog.Nil(prog.PyObjectPtr())), blks[1], blks[0])
+	b.SetBlockEx(blks[0], AtEnd, false)
+	var okRet Expr
+	if nret == 1 {
+		b.PyDecRef(ret) // the result object isn't returned
+		okRet = prog.Nil(tret)
+	} else {
+		t := prog.rawType(results.At(0).Type())
+		val := b.pyResult(ret, t)
+		okRet = b.aggregateValue(tret, val.impl, prog.Nil(prog.rawType(results.At(1).Type())).impl)
+		if !b.isPyObject(t.raw.Type) {
+			next := b.Func.MakeBlocks(1)[0]
+			b.If(b.pyErrOccurred(), blks[1], next)
+			b.SetBlockEx(next, AtEnd, false)
+			b.blk.last = next.last
+		}
+	}
+	okEnd := b.impl.GetInsertBlock()
+	b.Jump(blks[2])
+	b.SetBlockEx(blks[1], AtEnd, false)
+	failRet := b.Call(pkg.pyError())
+	if nret > 1 {
+		zero := llvm.ConstNull(prog.rawType(results.At(0).Type()).ll)
+		failRet = b.aggregateValue(tret, zero, failRet.impl)
+	}
+	failEnd := b.impl.GetInsertBlock()
+	b.Jump(blks[2])
+	b.SetBlockEx(blks[2], AtEnd, false)
+	b.blk.last = blks[2].last
+	phi := b.Phi(tret)
+	phi.impl.AddIncoming([]llvm.Value{okRet.impl, failRet.impl}, []llvm.BasicBlock{okEnd, failEnd})
+	b.PyGILRelease(state)
+	return phi.Expr
+}
+
+// pyResult converts the result obj of a Python call to the Go type t of the
+// result of its signature.
+func (b Builder) pyResult(obj Expr, t Type) Expr {
+	if !b.isPyObject(t.raw.Type) {
+		ret := b.GoVal(obj, t)
+		b.PyDecRef(obj)
+		return ret
+	}
+	if t.ll != obj.ll {
+		return Expr{castPtr(b.impl, obj.impl, t.ll), t}
+	}
+	return Expr{obj.impl, t}
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// pyError returns the function fetching and clearing the Python exception
+// being raised, which it returns as a Go error: a runtime.PyError holding
+// the name of the class of the exception, its message and its traceback.
+func (p Package) pyError() Expr {
+	const name = "__llgo_pyerr"
+	if fn := p.FuncOf(name); fn != nil {
+		return fn.Expr
+	}
+	prog := p.Prog
+	newErr := p.rtFunc("NewPyError")
+	results := newErr.raw.Type.(*types.Signature).Results()
+	fn := p.NewFunc(name, types.NewSignatureType(nil, nil, nil, nil, results, false), InGo)
+	fn.impl.SetLinkage(llvm.LinkOnceAnyLinkage)
+	b := fn.MakeBody(1)
+	objPtr := prog.PyObjectPtr()
+	nilObj := prog.Nil(objPtr)
+	newSlot := func() Expr {
+		slot := b.AllocaT(objPtr)
+		b.Store(slot, nilObj)
+		return slot
+	}
+	exc := []Expr{newSlot(), newSlot(), newSlot()} // type, value, traceback
+	b.pyCallC("PyErr_Fetch", nil, exc...)
+	b.pyCallC("PyErr_NormalizeException", nil, exc...)
+	typ, val := b.Load(exc[0]), b.Load(exc[1])
+
+	// traceback.format_exception(val)
+	strs := []Expr{newSlot(), newSlot(), newSlot()} // class name, message, traceback
+	b.IfThen(b.BinOp(token.NEQ, typ, nilObj), func() {
+		b.Store(strs[0], b.pyCallC("PyObject_GetAttrString", objPtr, typ, b.CStr("__name__")))
+		b.Store(strs[1], b.pyCallC("PyObject_Str", objPtr, val))
+		mod := b.pyCallC("PyImport_ImportModule", objPtr, b.CStr("traceback"))
+		b.IfThen(b.BinOp(token.NEQ, mod, nilObj), func() {
+			format := b.pyCallC("PyObject_GetAttrString", objPtr, mod, b.CStr("format_exception"))
+			b.IfThen(b.BinOp(token.NEQ, format, nilObj), func() {
+				call := p.pyFunc("PyObject_CallFunctionObjArgs", prog.tyCallFunctionObjArgs())
+				lines := b.Call(call, format, val, nilObj)
+				b.IfThen(b.BinOp(token.NEQ, lines, nilObj), func() {
+					sep := b.pyCallC("PyUnicode_FromString", objPtr, b.CStr(""))
+					b.Store(strs[2], b.pyCallC("PyUnicode_Join", objPtr, sep, lines))
+					b.PyDecRef(sep)
+					b.PyDecRef(lines)
+				})
+				b.PyDecRef(format)
+			})
+			b.PyDecRef(mod)
+		})
+	})
+	cstrs := make([]Expr, len(strs))
+	for i, str := range strs {
+		cstr := b.AllocaT(prog.CStr())
+		b.Store(cstr, prog.Nil(prog.CStr()))
+		obj := b.Load(str)
+		b.IfThen(b.BinOp(token.NEQ, obj, nilObj), func() {
+			b.Store(cstr, b.pyCallC("PyUnicode_AsUTF8", prog.CStr(), obj))
+		})
+		cstrs[i] = b.Load(cstr)
+	}
+	b.pyCallC("PyErr_Clear", nil)
+	err := b.Call(newErr, cstrs...)
+	for _, slot := range append(strs, exc...) {
+		b.PyDecRef(b.Load(slot))
+	}
+	b.Return(err)
+	return fn.Expr
 }
 
 // PyNewList(n uintptr) *Object
@@ -321,6 +504,8 @@ func (b Builder) PyList(args ...Expr) (ret Expr) {
 	prog := b.Prog
 	n := len(args)
 	uintPtr := prog.Uintptr()
+	state := b.PyGILEnsure()
+	defer b.PyGILRelease(state)
 	list := b.PyNewList(prog.IntVal(uint64(n), uintPtr))
 	for i, arg := range args {
 		b.PyListSetItem(list, prog.IntVal(uint64(i), uintPtr), b.PyVal(arg))
@@ -347,6 +532,8 @@ func (b Builder) PyTuple(args ...Expr) (ret Expr) {
 	prog := b.Prog
 	n := len(args)
 	uintPtr := prog.Uintptr()
+	state := b.PyGILEnsure()
+	defer b.PyGILRelease(state)
 	list := b.PyNewTuple(prog.IntVal(uint64(n), uintPtr))
 	for i, arg := range args {
 		b.PyTupleSetItem(list, prog.IntVal(uint64(i), uintPtr), b.PyVal(arg))
@@ -354,19 +541,380 @@ func (b Builder) PyTuple(args ...Expr) (ret Expr) {
 	return list
 }
 
-// PyVal(v any) *Object
+// PyVal converts the Go value v to a new Python object:
+//   - bool, integers, floats and complex numbers to bool, int, float and
+//     complex;
+//   - string to str and []byte to bytes;
+//   - other slices and arrays to list;
+//   - maps to dict;
+//   - structs to dict, keyed by the names of their exported fields.
+//
+// Python objects are returned as a *py.Object, without a new reference. It
+// panics for the other types, such as pointers, interfaces and functions.
 func (b Builder) PyVal(v Expr) (ret Expr) {
-	switch t := v.raw.Type.(type) {
+	prog := b.Prog
+	objPtr := prog.PyObjectPtr()
+	switch t := v.raw.Type.Underlying().(type) {
+	case *types.Basic:
+		info := t.Info()
+		switch {
+		case info&types.IsBoolean != 0:
+			long := prog.cLong()
+			return b.pyCallC("PyBool_FromLong", objPtr, Expr{llvm.CreateZExt(b.impl, v.impl, long.ll), long})
+		case info&types.IsUnsigned != 0:
+			return b.pyCallC("PyLong_FromUnsignedLongLong", objPtr, b.pyCast(prog.Uint64(), v))
+		case info&types.IsInteger != 0:
+			return b.pyCallC("PyLong_FromLongLong", objPtr, b.pyCast(prog.Int64(), v))
+		case info&types.IsFloat != 0:
+			return b.PyFloat(b.pyCast(prog.Float64(), v))
+		case info&types.IsComplex != 0:
+			v = b.pyCast(prog.Complex128(), v)
+			return b.pyCallC("PyComplex_FromDoubles", objPtr, b.Field(v, 0), b.Field(v, 1))
+		case info&types.IsString != 0:
+			return b.pyCallC("PyUnicode_FromStringAndSize", objPtr, b.StringData(v), b.StringLen(v))
+		}
+		panic("PyVal: unsupported type " + v.raw.Type.String())
+	case *types.Slice:
+		if isByte(t.Elem()) {
+			data := Expr{castPtr(b.impl, b.SliceData(v).impl, prog.CStr().ll), prog.CStr()}
+			return b.pyCallC("PyBytes_FromStringAndSize", objPtr, data, b.SliceLen(v))
+		}
+		n := b.SliceLen(v)
+		list := b.PyNewList(b.pyCast(prog.Uintptr(), n))
+		b.pyTimes(n, func(i Expr) {
+			item := b.pyNewVal(b.Load(b.pyElemAddr(v, i)))
+			b.PyListSetItem(list, b.pyCast(prog.Uintptr(), i), item)
+		})
+		return list
+	case *types.Array:
+		n := int(t.Len())
+		list := b.PyNewList(prog.IntVal(uint64(n), prog.Uintptr()))
+		for i := 0; i < n; i++ {
+			item := Expr{llvm.CreateExtractValue(b.impl, v.impl, i), prog.Index(v.Type)}
+			b.PyListSetItem(list, prog.IntVal(uint64(i), prog.Uintptr()), b.pyNewVal(item))
+		}
+		return list
+	case *types.Map:
+		dict := b.pyCallC("PyDict_New", objPtr)
+		b.pyRange(v, func(k, v Expr) {
+			key, item := b.PyVal(k), b.PyVal(v)
+			b.pyCallC("PyDict_SetItem", prog.CInt(), dict, key, item)
+			b.pyReleaseVal(k, key)
+			b.pyReleaseVal(v, item)
+		})
+		return dict
+	case *types.Struct:
+		dict := b.pyCallC("PyDict_New", objPtr)
+		for i := 0; i < t.NumFields(); i++ {
+			if fld := t.Field(i); fld.Exported() {
+				fv := b.Field(v, i)
+				item := b.PyVal(fv)
+				b.pyCallC("PyDict_SetItemString", prog.CInt(), dict, b.CStr(fld.Name()), item)
+				b.pyReleaseVal(fv, item)
+			}
+		}
+		return dict
+	default:
+		if !b.isPyObject(v.raw.Type) {
+			panic("PyVal: unsupported type " + v.raw.Type.String())
+		}
+		return Expr{castPtr(b.impl, v.impl, objPtr.ll), objPtr}
+	}
+}
+
+// pyNewVal is PyVal, but it returns a new reference to Python objects too,
+// for PyListSetItem which steals it.
+func (b Builder) pyNewVal(v Expr) Expr {
+	obj := b.PyVal(v)
+	if b.isPyObject(v.raw.Type) {
+		b.PyIncRef(obj)
+	}
+	return obj
+}
+
+// pyReleaseVal releases obj, the result of PyVal(v), if it is a new object.
+func (b Builder) pyReleaseVal(v, obj Expr) {
+	if !b.isPyObject(v.raw.Type) {
+		b.PyDecRef(obj)
+	}
+}
+
+// GoVal converts the Python object obj to a Go value of type t, the reverse
+// of PyVal: lists and tuples are converted to slices and arrays, and dicts
+// to maps and structs. obj is not released. A failed conversion raises a
+// Python exception, which the caller checks with PyErr_Occurred.
+func (b Builder) GoVal(obj Expr, t Type) (ret Expr) {
+	prog := b.Prog
+	objPtr := prog.PyObjectPtr()
+	switch u := t.raw.Type.Underlying().(type) {
 	case *types.Basic:
-		switch t.Kind() {
-		case types.Float64:
-			return b.PyFloat(v)
+		info := u.Info()
+		switch {
+		case info&types.IsBoolean != 0:
+			ok := b.pyCallC("PyObject_IsTrue", prog.CInt(), obj)
+			ret = b.BinOp(token.NEQ, ok, prog.IntVal(0, prog.CInt()))
+		case info&types.IsUnsigned != 0:
+			ret = b.pyCast(t, b.pyCallC("PyLong_AsUnsignedLongLongMask", prog.Uint64(), obj))
+		case info&types.IsInteger != 0:
+			ret = b.pyCast(t, b.pyCallC("PyLong_AsLongLong", prog.Int64(), obj))
+		case info&types.IsFloat != 0:
+			ret = b.pyCast(t, b.pyCallC("PyFloat_AsDouble", prog.Float64(), obj))
+		case info&types.IsComplex != 0:
+			r := b.pyCallC("PyComplex_RealAsDouble", prog.Float64(), obj)
+			i := b.pyCallC("PyComplex_ImagAsDouble", prog.Float64(), obj)
+			ret = b.pyCast(t, b.Complex(r, i))
+		case info&types.IsString != 0:
+			bytes := b.pyCallC("PyUnicode_AsUTF8String", objPtr, obj)
+			data := b.pyCallC("PyBytes_AsString", prog.CStr(), bytes)
+			ret = b.MakeString(data, b.pyCallC("PyBytes_Size", prog.Int(), bytes))
+			b.PyDecRef(bytes)
 		default:
-			panic("PyVal: todo")
+			panic("GoVal: unsupported type " + t.raw.Type.String())
+		}
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			data := b.pyCallC("PyBytes_AsString", prog.CStr(), obj)
+			ret = b.GoBytes(data, b.pyCallC("PyBytes_Size", prog.Int(), obj))
+			break
+		}
+		n := b.pyCallC("PySequence_Size", prog.Int(), obj)
+		ret = b.MakeSlice(t, n, n)
+		telem := prog.Index(t)
+		b.pyTimes(n, func(i Expr) {
+			item := b.pyCallC("PySequence_GetItem", objPtr, obj, i)
+			b.Store(b.pyElemAddr(ret, i), b.GoVal(item, telem))
+			b.PyDecRef(item)
+		})
+	case *types.Array:
+		telem := prog.Index(t)
+		ret = prog.Zero(t)
+		for i := 0; i < int(u.Len()); i++ {
+			item := b.pyCallC("PySequence_GetItem", objPtr, obj, prog.IntVal(uint64(i), prog.Int()))
+			ret.impl = b.impl.CreateInsertValue(ret.impl, b.GoVal(item, telem).impl, i, "")
+			b.PyDecRef(item)
 		}
+	case *types.Map:
+		tkey, telem := prog.rawType(u.Key()), prog.rawType(u.Elem())
+		ret = b.MakeMap(t, Expr{})
+		items := b.pyCallC("PyMapping_Items", objPtr, obj)
+		n := b.pyCallC("PyList_Size", prog.Int(), items)
+		b.pyTimes(n, func(i Expr) {
+			kv := b.pyCallC("PyList_GetItem", objPtr, items, i)
+			k := b.pyCallC("PyTuple_GetItem", objPtr, kv, prog.IntVal(0, prog.Int()))
+			v := b.pyCallC("PyTuple_GetItem", objPtr, kv, prog.IntVal(1, prog.Int()))
+			b.MapUpdate(ret, b.GoVal(k, tkey), b.GoVal(v, telem))
+		})
+		b.PyDecRef(items)
+	case *types.Struct:
+		ptr := b.Alloc(t, true)
+		for i := 0; i < u.NumFields(); i++ {
+			if fld := u.Field(i); fld.Exported() {
+				item := b.pyCallC("PyDict_GetItemString", objPtr, obj, b.CStr(fld.Name()))
+				b.IfThen(b.BinOp(token.NEQ, item, prog.Nil(objPtr)), func() {
+					b.Store(b.FieldAddr(ptr, i), b.GoVal(item, prog.Field(t, i)))
+				})
+			}
+		}
+		ret = b.Load(ptr)
 	default:
-		return v
+		if !b.isPyObject(t.raw.Type) {
+			panic("GoVal: unsupported type " + t.raw.Type.String())
+		}
+		b.PyIncRef(obj)
+		ret = Expr{castPtr(b.impl, obj.impl, t.ll), t}
+	}
+	ret.Type = t
+	return
+}
+
+// pyErrOccurred reports whether a Python exception is being raised.
+func (b Builder) pyErrOccurred() Expr {
+	objPtr := b.Prog.PyObjectPtr()
+	return b.BinOp(token.NEQ, b.pyCallC("PyErr_Occurred", objPtr), b.Prog.Nil(objPtr))
+}
+
+// PyIncRef(obj *Object)
+func (b Builder) PyIncRef(obj Expr) {
+	b.pyCallC("Py_IncRef", nil, obj)
+}
+
+// PyDecRef(obj *Object)
+func (b Builder) PyDecRef(obj Expr) {
+	b.pyCallC("Py_DecRef", nil, obj)
+}
+
+// pyCallC calls the function name of the Python C API, whose signature is
+// made of the types of args and ret (nil if it has no result).
+func (b Builder) pyCallC(name string, ret Type, args ...Expr) Expr {
+	params := make([]*types.Var, len(args))
+	for i, arg := range args {
+		params[i] = types.NewParam(token.NoPos, nil, "", arg.raw.Type)
+	}
+	var results *types.Tuple
+	if ret != nil {
+		results = types.NewTuple(types.NewParam(token.NoPos, nil, "", ret.raw.Type))
+	}
+	sig := types.NewSignatureType(nil, nil, nil, types.NewTuple(params...), results, false)
+	return b.Call(b.Pkg.pyFunc(name, sig), args...)
+}
+
+// PyGILEnsure acquires the GIL for the Python operations which follow, if
+// SetPyGIL is on, and returns the state to pass to PyGILRelease.
+func (b Builder) PyGILEnsure() (state Expr) {
+	if b.Prog.pyGIL {
+		state = b.pyCallC("PyGILState_Ensure", b.Prog.Int32())
+	}
+	return
+}
+
+// PyGILRelease releases the GIL acquired by the PyGILEnsure which returned
+// state.
+func (b Builder) PyGILRelease(state Expr) {
+	if b.Prog.pyGIL {
+		b.pyCallC("PyGILState_Release", nil, state)
+	}
+}
+
+// pyFinalize are the functions of the Python C API finalizing the interpreter.
+var pyFinalize = map[string]bool{
+	"Py_Finalize":   true,
+	"Py_FinalizeEx": true,
+}
+
+// IsPyFinalize reports whether the C function name finalizes the Python
+// interpreter. It must be called with the GIL, like the other functions of the
+// Python C API, but destroys it: the GIL is acquired before the call and never
+// released, as the main thread may have released it with LLGO_PY_GIL=release.
+func IsPyFinalize(name string) bool {
+	return pyFinalize[name]
+}
+
+// PyGILFunc returns the function calling fn, a function of the Python C API
+// of signature sig, with the GIL held, if SetPyGIL is on. It is used when fn
+// is deferred or run by a go statement, so it isn't called where the go or
+// defer statement acquires the GIL. args are the arguments of the call: the
+// function returned for a variadic fn takes them as its parameters.
+func (p Package) PyGILFunc(fn Function, sig *types.Signature, args []Expr) Function {
+	if !p.Prog.pyGIL {
+		return fn
+	}
+	name := fn.Name() + "$pygil"
+	if sig.Variadic() {
+		params := make([]*types.Var, len(args))
+		for i, arg := range args {
+			params[i] = types.NewParam(token.NoPos, nil, "", arg.raw.Type)
+		}
+		tparams := types.NewTuple(params...)
+		sig = types.NewSignatureType(nil, nil, nil, tparams, sig.Results(), false)
+		name += tparams.String()
+	}
+	if f := p.FuncOf(name); f != nil {
+		return f
+	}
+	f := p.NewFunc(name, sig, InGo)
+	f.impl.SetLinkage(llvm.LinkOnceAnyLinkage)
+	b := f.MakeBody(1)
+	params := make([]Expr, sig.Params().Len())
+	for i := range params {
+		params[i] = f.Param(i)
+	}
+	state := b.PyGILEnsure()
+	ret := b.Call(fn.Expr, params...)
+	if !IsPyFinalize(fn.Name()) {
+		b.PyGILRelease(state)
+	}
+	if sig.Results().Len() == 0 {
+		b.Return()
+	} else {
+		b.Return(ret)
+	}
+	return f
+}
+
+// isPyObject reports whether t is *py.Object, or the pointer to a struct
+// embedding py.Object as its first field, such as the Python classes bound by
+// llpyg.
+func (b Builder) isPyObject(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	objPtr := b.Prog.PyObjectPtr().raw.Type
+	if types.Identical(t, objPtr) {
+		return true
+	}
+	if st, ok := ptr.Elem().Underlying().(*types.Struct); ok && st.NumFields() > 0 {
+		fld := st.Field(0)
+		return fld.Embedded() && types.Identical(fld.Type(), objPtr.(*types.Pointer).Elem())
+	}
+	return false
+}
+
+// pyCast converts the number v to the type t, of the same kind.
+func (b Builder) pyCast(t Type, v Expr) Expr {
+	if v.ll == t.ll {
+		return Expr{v.impl, t}
+	}
+	return b.Convert(t, v)
+}
+
+// pyElemAddr returns the address of the element i of the slice v, which is
+// known to be in range.
+func (b Builder) pyElemAddr(v, i Expr) Expr {
+	telem := b.Prog.Index(v.Type)
+	ptr := llvm.CreateInBoundsGEP(b.impl, telem.ll, b.SliceData(v).impl, []llvm.Value{i.impl})
+	return Expr{ptr, b.Prog.Pointer(telem)}
+}
+
+// pyTimes emits a loop calling body(i) for i from 0 to n-1. Unlike Times,
+// body may emit blocks of its own.
+func (b Builder) pyTimes(n Expr, body func(i Expr)) {
+	prog := b.Prog
+	typ := n.Type
+	entry := b.impl.GetInsertBlock()
+	blks := b.Func.MakeBlocks(3)
+	b.Jump(blks[0])
+	b.SetBlockEx(blks[0], AtEnd, false)
+	phi := b.Phi(typ)
+	b.If(b.BinOp(token.LSS, phi.Expr, n), blks[1], blks[2])
+	b.SetBlockEx(blks[1], AtEnd, false)
+	body(phi.Expr)
+	post := b.BinOp(token.ADD, phi.Expr, prog.IntVal(1, typ))
+	latch := b.impl.GetInsertBlock()
+	b.Jump(blks[0])
+	phi.impl.AddIncoming([]llvm.Value{prog.IntVal(0, typ).impl, post.impl}, []llvm.BasicBlock{entry, latch})
+	b.SetBlockEx(blks[2], AtEnd, false)
+	b.blk.last = blks[2].last
+}
+
+// pyRange emits a loop calling body(k, v) for the entries of the map m.
+func (b Builder) pyRange(m Expr, body func(k, v Expr)) {
+	iter := b.Range(m)
+	blks := b.Func.MakeBlocks(3)
+	b.Jump(blks[0])
+	b.SetBlockEx(blks[0], AtEnd, false)
+	next := b.Next(m.Type, iter, false)
+	b.If(b.Extract(next, 0), blks[1], blks[2])
+	b.SetBlockEx(blks[1], AtEnd, false)
+	body(b.Extract(next, 1), b.Extract(next, 2))
+	b.Jump(blks[0])
+	b.SetBlockEx(blks[2], AtEnd, false)
+	b.blk.last = blks[2].last
+}
+
+func isByte(t types.Type) bool {
+	if t, ok := t.Underlying().(*types.Basic); ok {
+		return t.Kind() == types.Byte
+	}
+	return false
+}
+
+// cLong returns the type of C long on the target.
+func (p Program) cLong() Type {
+	if p.is32Bits || p.target.GOOS == "windows" {
+		return p.Int32()
 	}
+	return p.Int64()
 }
 
 // PyFloat(fltVal float64) *Object
@@ -378,6 +926,8 @@ func (b Builder) PyFloat(fltVal Expr) (ret Expr) {
 // PyStr returns a py-style string constant expression.
 func (b Builder) PyStr(v string) Expr {
 	fn := b.Pkg.pyFunc("PyUnicode_FromString", b.Prog.tyPyUnicodeFromString())
+	state := b.PyGILEnsure()
+	defer b.PyGILRelease(state)
 	return b.Call(fn, b.CStr(v))
 }
 
@@ -399,6 +949,8 @@ func (b Builder) PyNewVar(modName, name string) PyGlobal {
 func (b Builder) pyLoad(ptr Expr) Expr {
 	t := ptr.raw.Type.(*pyVarTy)
 	fn := b.Pkg.pyFunc("PyObject_GetAttrString", b.Prog.tyGetAttrString())
+	state := b.PyGILEnsure()
+	defer b.PyGILRelease(state)
 	return b.Call(fn, t.mod, b.CStr(t.name))
 }
 
@@ -24,6 +24,8 @@ import (
 	"go/token"
 	"go/types"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 	"unsafe"
 
@@ -196,6 +198,100 @@ func TestPyFunc(t *testing.T) {
 	}
 }
 
+func TestPyVal(t *testing.T) {
+	prog := NewProgram(nil)
+	prog.SetRuntime(stringSliceRuntime)
+	py := types.NewPackage("foo", "foo")
+	o := types.NewTypeName(0, py, "Object", nil)
+	types.NewNamed(o, types.Typ[types.Int], nil)
+	py.Scope().Insert(o)
+	prog.SetPython(py)
+	pkg := prog.NewPackage("bar", "foo/bar")
+
+	point := types.NewStruct([]*types.Var{
+		types.NewField(0, nil, "X", types.Typ[types.Int], false),
+		types.NewField(0, nil, "y", types.Typ[types.Int], false),
+	}, nil)
+	goTypes := []types.Type{
+		types.Typ[types.Bool], types.Typ[types.Int8], types.Typ[types.Uint],
+		types.Typ[types.Float32], types.Typ[types.Complex128],
+		types.NewSlice(types.Typ[types.Byte]), types.NewSlice(types.NewSlice(types.Typ[types.Float64])),
+		types.NewArray(types.Typ[types.Int], 2), point,
+	}
+	vars := make([]*types.Var, len(goTypes))
+	for i, t := range goTypes {
+		vars[i] = types.NewParam(0, nil, "", t)
+	}
+	sig := types.NewSignatureType(nil, nil, nil, types.NewTuple(vars...), nil, false)
+	b := pkg.NewFunc("fn", sig, InGo).MakeBody(1)
+	objPtr := prog.PyObjectPtr()
+	for i := range goTypes {
+		obj := b.PyVal(b.Func.Param(i))
+		if obj.Type != objPtr {
+			t.Fatalf("PyVal(%v) = %v", goTypes[i], obj.Type.RawType())
+		}
+		if i < 5 || i == 7 { // others call runtime functions
+			if v := b.GoVal(obj, prog.rawType(goTypes[i])); v.RawType() != goTypes[i] {
+				t.Fatalf("GoVal(%v) = %v", goTypes[i], v.RawType())
+			}
+		}
+	}
+	if obj := b.PyVal(prog.Nil(objPtr)); obj.Type != objPtr {
+		t.Fatal("PyVal(*py.Object) failed")
+	}
+	tensor := types.NewPointer(types.NewStruct([]*types.Var{
+		types.NewField(0, nil, "Object", objPtr.RawType().(*types.Pointer).Elem(), true),
+	}, nil))
+	if obj := b.PyVal(prog.Nil(prog.rawType(tensor))); obj.Type != objPtr {
+		t.Fatal("PyVal(*Tensor) failed")
+	}
+	if v := b.GoVal(prog.Nil(objPtr), prog.rawType(tensor)); v.RawType() != tensor {
+		t.Fatal("GoVal(*Tensor) failed")
+	}
+	b.Return()
+	if err := llvm.VerifyModule(pkg.mod, llvm.ReturnStatusAction); err != nil {
+		t.Fatal(err)
+	}
+	ir := pkg.String()
+	for _, fn := range []string{
+		"PyBool_FromLong", "PyLong_FromLongLong", "PyLong_FromUnsignedLongLong",
+		"PyFloat_FromDouble", "PyComplex_FromDoubles", "PyBytes_FromStringAndSize",
+		"PyList_SetItem", "PyDict_SetItemString", "PyObject_IsTrue", "PySequence_GetItem",
+	} {
+		if !strings.Contains(ir, "@"+fn+"(") {
+			t.Errorf("%s is not called", fn)
+		}
+	}
+	if strings.Contains(ir, `c"y\00"`) {
+		t.Error("unexported field is converted")
+	}
+}
+
+func TestPyValUnsupported(t *testing.T) {
+	prog := NewProgram(nil)
+	prog.SetRuntime(stringSliceRuntime)
+	py := types.NewPackage("foo", "foo")
+	o := types.NewTypeName(0, py, "Object", nil)
+	types.NewNamed(o, types.Typ[types.Int], nil)
+	py.Scope().Insert(o)
+	prog.SetPython(py)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	for _, typ := range []types.Type{
+		types.NewPointer(types.Typ[types.Int]), types.Typ[types.UnsafePointer],
+		types.NewSlice(types.NewInterfaceType(nil, nil)),
+	} {
+		b := pkg.NewFunc("fn"+typ.String(), NoArgsNoRet, InGo).MakeBody(1)
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("PyVal(%v): no panic", typ)
+				}
+			}()
+			b.PyVal(prog.Nil(prog.rawType(typ)))
+		}()
+	}
+}
+
 func TestVar(t *testing.T) {
 	prog := NewProgram(nil)
 	pkg := prog.NewPackage("bar", "foo/bar")
@@ -287,6 +383,217 @@ declare void @fn(i64)
 `)
 }
 
+func TestWasmFunc(t *testing.T) {
+	prog := NewProgram(nil)
+	pkg := prog.NewPackage("bar", "foo/bar")
+	params := types.NewTuple(types.NewVar(0, nil, "a", types.Typ[types.Int32]))
+	sig := types.NewSignatureType(nil, nil, nil, params, nil, false)
+	pkg.NewFunc("imp", sig, InGo).WasmImport("env", "log")
+	exp := pkg.NewFunc("exp", sig, InGo)
+	exp.WasmExport("run")
+	exp.MakeBody(1).Return()
+	assertPkg(t, pkg, `; ModuleID = 'foo/bar'
+source_filename = "foo/bar"
+
+declare void @imp(i32) #0
+
+define void @exp(i32 %0) #1 {
+_llgo_0:
+  ret void
+}
+
+attributes #0 = { "wasm-import-module"="env" "wasm-import-name"="log" }
+attributes #1 = { "wasm-export-name"="run" }
+`)
+}
+
+func TestPyCall(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Chdir("../runtime")
+	defer os.Chdir(wd)
+	prog := NewProgram(nil)
+	prog.SetRuntime(func() *types.Package {
+		imp := packages.NewImporter(token.NewFileSet())
+		pkg, _ := imp.Import(PkgRuntime)
+		return pkg
+	})
+	py := types.NewPackage("foo", "foo")
+	o := types.NewTypeName(0, py, "Object", nil)
+	types.NewNamed(o, types.Typ[types.Int], nil)
+	py.Scope().Insert(o)
+	prog.SetPython(py)
+	prog.SetPyExceptions(true)
+	prog.SetPyGIL(true)
+	pkg := prog.NewPackage("bar", "foo/bar")
+
+	objPtr := prog.PyObjectPtr().RawType()
+	tyErr := types.Universe.Lookup("error").Type()
+	newSig := func(results ...types.Type) *types.Signature {
+		vars := make([]*types.Var, len(results))
+		for i, t := range results {
+			vars[i] = types.NewParam(0, nil, "", t)
+		}
+		params := types.NewTuple(types.NewParam(0, nil, "", types.Typ[types.Int]))
+		return types.NewSignatureType(nil, nil, nil, params, types.NewTuple(vars...), false)
+	}
+	b := pkg.NewFunc("fn", NoArgsNoRet, InGo).MakeBody(1)
+	for i, sig := range []*types.Signature{
+		newSig(objPtr), newSig(types.Typ[types.Int], tyErr), newSig(tyErr), newSig(types.Typ[types.Int]),
+	} {
+		fn := pkg.PyNewFunc("__llgo_py.mod.f"+string(rune('a'+i)), sig, true)
+		b.Call(fn.Expr, prog.Val(1))
+	}
+	b.Return()
+	if err := llvm.VerifyModule(pkg.mod, llvm.ReturnStatusAction); err != nil {
+		t.Fatal(err)
+	}
+	ir := pkg.String()
+	for _, fn := range []string{"@__llgo_pyerr(", "@PyErr_Fetch(", "NewPyError", "runtime.Panic"} {
+		if !strings.Contains(ir, fn) {
+			t.Errorf("%s is not called", fn)
+		}
+	}
+	// the panics of the first and the last calls release the GIL too
+	if n := strings.Count(ir, "call i32 @PyGILState_Ensure("); n != 4 {
+		t.Errorf("PyGILState_Ensure is called %d times, want 4", n)
+	}
+	if n := strings.Count(ir, "call void @PyGILState_Release("); n != 7 {
+		t.Errorf("PyGILState_Release is called %d times, want 7", n)
+	}
+	body := ir[strings.Index(ir, "define void @fn("):]
+	body = body[:strings.Index(body, "\n}\n")]
+	// the conversions of the int results are checked
+	if n := strings.Count(body, "@PyErr_Occurred("); n != 2 {
+		t.Errorf("PyErr_Occurred is called %d times, want 2", n)
+	}
+	// the int arguments and the results but the one returned are released
+	if n := strings.Count(body, "@Py_DecRef("); n != 7 {
+		t.Errorf("Py_DecRef is called %d times, want 7", n)
+	}
+}
+
+func TestPyGILFunc(t *testing.T) {
+	prog := NewProgram(nil)
+	prog.SetRuntime(stringSliceRuntime)
+	py := types.NewPackage("foo", "foo")
+	o := types.NewTypeName(0, py, "Object", nil)
+	types.NewNamed(o, types.Typ[types.Int], nil)
+	py.Scope().Insert(o)
+	prog.SetPython(py)
+	pkg := prog.NewPackage("bar", "foo/bar")
+
+	params := types.NewTuple(types.NewParam(0, nil, "", prog.PyObjectPtr().RawType()))
+	sig := types.NewSignatureType(nil, nil, nil, params, nil, false)
+	fn := pkg.NewFunc("Py_DecRef", sig, InC)
+	if pkg.PyGILFunc(fn, sig, nil) != fn {
+		t.Fatal("PyGILFunc without SetPyGIL")
+	}
+	prog.SetPyGIL(true)
+	gil := pkg.PyGILFunc(fn, sig, nil)
+	if gil == fn || pkg.PyGILFunc(fn, sig, nil) != gil {
+		t.Fatal("PyGILFunc failed")
+	}
+
+	// the wrapper of a variadic function takes the arguments of the call
+	obj := prog.PyObjectPtr()
+	params = types.NewTuple(types.NewParam(0, nil, "", obj.RawType()), VArg())
+	sig = types.NewSignatureType(nil, nil, nil, params, types.NewTuple(types.NewParam(0, nil, "", obj.RawType())), true)
+	callObjArgs := pkg.NewFunc("PyObject_CallFunctionObjArgs", sig, InC)
+	b := pkg.NewFunc("call", NoArgsNoRet, InGo).MakeBody(1)
+	args := []Expr{prog.Nil(obj), prog.Nil(obj), prog.Nil(obj), prog.Nil(prog.VoidPtr())}
+	gil = pkg.PyGILFunc(callObjArgs, sig, args)
+	if pkg.PyGILFunc(callObjArgs, sig, args) != gil || pkg.PyGILFunc(callObjArgs, sig, args[:2]) == gil {
+		t.Fatal("PyGILFunc of a variadic function failed")
+	}
+	b.Call(gil.Expr, args...)
+	b.Return()
+
+	// Py_Finalize destroys the GIL
+	finalize := pkg.NewFunc("Py_Finalize", NoArgsNoRet, InC)
+	pkg.PyGILFunc(finalize, NoArgsNoRet, nil)
+
+	if err := llvm.VerifyModule(pkg.mod, llvm.ReturnStatusAction); err != nil {
+		t.Fatal(err)
+	}
+	ir := pkg.String()
+	tests := []struct {
+		fn    string
+		calls []string
+	}{
+		{`@"Py_DecRef$pygil"(`, []string{"@PyGILState_Ensure(", "@Py_DecRef(", "@PyGILState_Release("}},
+		{`@"PyObject_CallFunctionObjArgs$pygil(*foo.Object, *foo.Object, *foo.Object, unsafe.Pointer)"(`,
+			[]string{"@PyGILState_Ensure(", "@PyObject_CallFunctionObjArgs(i64* %0, i64* %1, i64* %2, void* %3)", "@PyGILState_Release("}},
+		{`@"Py_Finalize$pygil"(`, []string{"@PyGILState_Ensure(", "@Py_Finalize("}},
+	}
+	for _, tt := range tests {
+		body := regexp.MustCompile(`(?ms)^define [^\n]*` + regexp.QuoteMeta(tt.fn) + `.*?^}$`).FindString(ir)
+		if body == "" {
+			t.Fatalf("%s not defined:\n%s", tt.fn, ir)
+		}
+		for _, call := range tt.calls {
+			if !strings.Contains(body, call) {
+				t.Errorf("%s is not called:\n%s", call, body)
+			}
+		}
+		if finalize := strings.Contains(tt.fn, "Py_Finalize"); finalize == strings.Contains(body, "@PyGILState_Release(") {
+			t.Errorf("GIL released: %v:\n%s", !finalize, body)
+		}
+	}
+}
+
+// stringSliceRuntime returns a runtime package with the String and Slice
+// types only.
+func stringSliceRuntime() *types.Package {
+	ret := types.NewPackage("runtime", "runtime")
+	scope := ret.Scope()
+	ptr := types.NewField(0, ret, "data", types.Typ[types.UnsafePointer], false)
+	size := types.NewField(0, ret, "len", types.Typ[types.Int], false)
+	capacity := types.NewField(0, ret, "cap", types.Typ[types.Int], false)
+	for name, flds := range map[string][]*types.Var{"String": {ptr, size}, "Slice": {ptr, size, capacity}} {
+		tn := types.NewTypeName(0, ret, name, nil)
+		types.NewNamed(tn, types.NewStruct(flds, nil), nil)
+		scope.Insert(tn)
+	}
+	return ret
+}
+
+func TestInitEmbed(t *testing.T) {
+	prog := NewProgram(nil)
+	prog.SetRuntime(stringSliceRuntime)
+	embed := types.NewPackage("embed", "embed")
+	tfile := types.NewNamed(types.NewTypeName(0, embed, "file", nil), types.NewStruct([]*types.Var{
+		types.NewField(0, embed, "name", types.Typ[types.String], false),
+		types.NewField(0, embed, "data", types.Typ[types.String], false),
+		types.NewField(0, embed, "hash", types.NewArray(types.Typ[types.Byte], 16), false),
+	}, nil), nil)
+	tfs := types.NewNamed(types.NewTypeName(0, embed, "FS", nil), types.NewStruct([]*types.Var{
+		types.NewField(0, embed, "files", types.NewPointer(types.NewSlice(tfile)), false),
+	}, nil), nil)
+
+	pkg := prog.NewPackage("foo", "foo")
+	files := []EmbedFile{{Name: "hello.txt", Data: []byte("hello")}}
+	str := pkg.NewVar("foo.s", types.NewPointer(types.Typ[types.String]), InGo)
+	pkg.InitEmbed(str, files)
+	bytes := pkg.NewVar("foo.b", types.NewPointer(types.NewSlice(types.Typ[types.Byte])), InGo)
+	pkg.InitEmbed(bytes, files)
+	fs := pkg.NewVar("foo.fs", types.NewPointer(tfs), InGo)
+	pkg.InitEmbed(fs, []EmbedFile{{Name: "dir/"}, {Name: "dir/a.txt", Data: []byte("a")}})
+
+	if n := str.impl.Initializer().Operand(1).ZExtValue(); n != 5 {
+		t.Errorf("len(s) = %d", n)
+	}
+	if n := bytes.impl.Initializer().Operand(2).ZExtValue(); n != 5 {
+		t.Errorf("cap(b) = %d", n)
+	}
+	slice := fs.impl.Initializer().Operand(0).Initializer()
+	if n := slice.Operand(1).ZExtValue(); n != 2 {
+		t.Errorf("len(fs.files) = %d", n)
+	}
+}
+
 func TestBasicFunc(t *testing.T) {
 	prog := NewProgram(nil)
 	pkg := prog.NewPackage("bar", "foo/bar")
@@ -557,3 +864,183 @@ _llgo_0:
 }
 `)
 }
+
+func TestCABI(t *testing.T) {
+	Initialize(InitAll)
+	field := func(name string, typ types.BasicKind) *types.Var {
+		return types.NewField(0, nil, name, types.Typ[typ], false)
+	}
+	divT := types.NewStruct([]*types.Var{field("quot", types.Int32), field("rem", types.Int32)}, nil)
+	timeval := types.NewStruct([]*types.Var{field("sec", types.Int64), field("usec", types.Int64)}, nil)
+	big := types.NewStruct([]*types.Var{field("a", types.Int64), field("b", types.Int64), field("c", types.Int64)}, nil)
+	vec3 := types.NewStruct([]*types.Var{field("x", types.Float32), field("y", types.Float32), field("z", types.Float32)}, nil)
+	tuple := func(typs ...types.Type) *types.Tuple {
+		vars := make([]*types.Var, len(typs))
+		for i, typ := range typs {
+			vars[i] = types.NewParam(0, nil, "", typ)
+		}
+		return types.NewTuple(vars...)
+	}
+	sig := func(params []types.Type, results ...types.Type) *types.Signature {
+		return types.NewSignatureType(nil, nil, nil, tuple(params...), tuple(results...), false)
+	}
+	i32 := types.Typ[types.Int32]
+
+	tests := []struct {
+		target *Target
+		cabi   bool
+		want   []string
+	}{
+		{&Target{GOOS: "linux", GOARCH: "amd64"}, false, []string{
+			"declare { i32, i32 } @div(i32, i32)",
+			"declare void @settime({ i64, i64 })",
+			"@printv(i32 %0, { i64, i64 } %1, { i64, i64, i64 } %2, { float, float, float } %3)",
+		}},
+		{&Target{GOOS: "linux", GOARCH: "amd64"}, true, []string{
+			"declare i64 @div(i32, i32)",
+			"declare void @settime(i64, i64)",
+			"byval({ i64, i64, i64 }) align 8",
+			"<2 x float>, float)",
+			"define void @gofn(",
+			"gofn$cabi",
+			"@printv(i32 %0, i64 %",
+			"byval({ i64, i64, i64 }) align 8 %5, <2 x float> %",
+		}},
+		{&Target{GOOS: "windows", GOARCH: "amd64"}, true, []string{
+			"declare i64 @div(i32, i32)",
+			"sret({ i64, i64, i64 })",
+			"@printv(i32 %0, { i64, i64 }* %",
+		}},
+		{&Target{GOOS: "darwin", GOARCH: "arm64"}, true, []string{
+			"declare i64 @div(i32, i32)",
+			"declare void @settime([2 x i64])",
+			"[3 x float])",
+			"sret({ i64, i64, i64 })",
+			"@printv(i32 %0, [2 x i64] %",
+			"{ i64, i64, i64 }* %5, [3 x float] %",
+		}},
+		{&Target{GOOS: "wasip1", GOARCH: "wasm"}, true, []string{
+			"sret({ i32, i32 })",
+			"byval({ i64, i64 }) align 8",
+			"@printv(i32 %0, { i64, i64 }* byval({ i64, i64 }) align 8 %",
+		}},
+	}
+	for _, tt := range tests {
+		prog := NewProgram(tt.target)
+		prog.SetCABI(tt.cabi)
+		pkg := prog.NewPackage("foo", "foo")
+		div := pkg.NewFunc("div", sig([]types.Type{i32, i32}, divT), InC)
+		settime := pkg.NewFunc("settime", sig([]types.Type{timeval}), InC)
+		bigf := pkg.NewFunc("bigf", sig([]types.Type{big, vec3}, big), InC)
+		gofn := pkg.NewFunc("foo.gofn", sig([]types.Type{big, divT}, big), InGo)
+		gofn.MakeBody(1).Return(gofn.Param(0))
+		gofn.CExport("gofn")
+		printv := pkg.NewFunc("printv", types.NewSignatureType(nil, nil, nil, types.NewTuple(types.NewParam(0, nil, "", i32), VArg()), nil, true), InC)
+
+		fn := pkg.NewFunc("foo.run", sig([]types.Type{i32, timeval, big, vec3}, types.Typ[types.UnsafePointer]), InGo)
+		b := fn.MakeBody(1)
+		qr := b.Call(div.Expr, fn.Param(0), fn.Param(0))
+		b.Call(settime.Expr, fn.Param(1))
+		b.Call(bigf.Expr, fn.Param(2), fn.Param(3))
+		cfn := b.CFuncAddr(gofn.Expr)
+		b.Call(cfn, fn.Param(2), qr)
+		// the variadic arguments are lowered as the named ones
+		b.Call(printv.Expr, fn.Param(0), fn.Param(1), fn.Param(2), fn.Param(3))
+		b.Return(b.Convert(prog.VoidPtr(), cfn))
+
+		if err := llvm.VerifyModule(pkg.mod, llvm.ReturnStatusAction); err != nil {
+			t.Fatal(tt.target, err)
+		}
+		ir := pkg.String()
+		for _, want := range tt.want {
+			if !strings.Contains(ir, want) {
+				t.Fatalf("%v: %q not found in\n%s", tt.target, want, ir)
+			}
+		}
+		machine := tt.target.CreateTargetMachine()
+		buf, err := machine.EmitToMemoryBuffer(pkg.mod, llvm.ObjectFile)
+		machine.Dispose()
+		if err != nil {
+			t.Fatal(tt.target, err)
+		}
+		buf.Dispose()
+	}
+}
+
+func TestCABIAlign16(t *testing.T) {
+	Initialize(InitAll)
+	prog := NewProgram(&Target{GOOS: "linux", GOARCH: "arm64"})
+	ctx := prog.ctx
+	i64, i128 := ctx.Int64Type(), ctx.IntType(128)
+	abi := aapcs64ABI{prog}
+	tests := []struct {
+		typ  llvm.Type
+		want llvm.Type
+	}{
+		{ctx.StructType([]llvm.Type{i64, i64}, false), llvm.ArrayType(i64, 2)},
+		{ctx.StructType([]llvm.Type{i128}, false), i128},
+		{ctx.StructType([]llvm.Type{i64, ctx.Int32Type()}, false), llvm.ArrayType(i64, 2)},
+	}
+	for _, tt := range tests {
+		for _, arg := range []cabiArg{abi.param(tt.typ), abi.ret(tt.typ)} {
+			if arg.kind != cabiCoerce || arg.typ != tt.want {
+				t.Errorf("%v: passed as %v, want %v", tt.typ, arg.typ, tt.want)
+			}
+		}
+	}
+}
+
+func TestReturnAddress(t *testing.T) {
+	for _, goarch := range []string{"amd64", "wasm"} {
+		prog := NewProgram(&Target{GOOS: "linux", GOARCH: goarch})
+		pkg := prog.NewPackage("foo", "foo")
+		ret := types.NewTuple(types.NewParam(0, nil, "", types.Typ[types.UnsafePointer]))
+		sig := types.NewSignatureType(nil, nil, nil, nil, ret, false)
+		b := pkg.NewFunc("fn", sig, InGo).MakeBody(1)
+		b.Return(b.ReturnAddress())
+		if call := strings.Contains(pkg.String(), "@llvm.returnaddress(i32 0)"); call != (goarch != "wasm") {
+			t.Fatalf("%s: llvm.returnaddress called: %v\n%s", goarch, call, pkg.String())
+		}
+	}
+}
+
+func TestRelocMode(t *testing.T) {
+	tests := []struct {
+		target *Target
+		want   llvm.RelocMode
+	}{
+		{&Target{GOOS: "linux", GOARCH: "amd64"}, llvm.RelocPIC},
+		{&Target{GOOS: "wasip1", GOARCH: "wasm"}, llvm.RelocDefault},
+		{&Target{GOOS: "linux", GOARCH: "arm", RelocModel: "static"}, llvm.RelocStatic},
+		{&Target{GOOS: "linux", GOARCH: "arm", RelocModel: "pic"}, llvm.RelocPIC},
+		{&Target{GOOS: "linux", GOARCH: "amd64", RelocModel: "dynamic-no-pic"}, llvm.RelocDynamicNoPic},
+	}
+	for _, tt := range tests {
+		if got := tt.target.relocMode(); got != tt.want {
+			t.Errorf("%+v: relocMode() = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestEmitPasses(t *testing.T) {
+	tests := []struct {
+		opts EmitOptions
+		want string
+	}{
+		{EmitOptions{}, ""},
+		{EmitOptions{OptLevel: "0"}, "default<O0>"},
+		{EmitOptions{OptLevel: "2"}, "default<O2>"},
+		{EmitOptions{OptLevel: "z"}, "default<Oz>"},
+		{EmitOptions{LTO: "thin"}, "thinlto-pre-link<O2>"},
+		{EmitOptions{OptLevel: "3", LTO: "thin"}, "thinlto-pre-link<O3>"},
+		{EmitOptions{OptLevel: "s", LTO: "full"}, "lto-pre-link<Os>"},
+		{EmitOptions{PGOInstrument: true}, "pgo-instr-gen,instrprof"},
+		{EmitOptions{OptLevel: "1", PGOInstrument: true}, "pgo-instr-gen,instrprof,default<O1>"},
+		{EmitOptions{LTO: "full", PGOInstrument: true}, "pgo-instr-gen,instrprof,lto-pre-link<O2>"},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.passes(); got != tt.want {
+			t.Errorf("%+v: passes() = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
@@ -183,7 +183,7 @@ func (b Builder) Return(results ...Expr) {
 	case 1:
 		raw := b.Func.raw.Type.(*types.Signature).Results().At(0).Type()
 		ret := checkExpr(results[0], raw, b)
-		b.impl.CreateRet(ret.impl)
+		b.ret(ret.impl)
 	default:
 		tret := b.Func.raw.Type.(*types.Signature).Results()
 		n := tret.Len()
@@ -193,10 +193,18 @@ func (b Builder) Return(results ...Expr) {
 		}
 		typ := b.Prog.Struct(typs...)
 		expr := b.aggregateValue(typ, llvmParams(0, results, tret, b)...)
-		b.impl.CreateRet(expr.impl)
+		b.ret(expr.impl)
 	}
 }
 
+func (b Builder) ret(v llvm.Value) {
+	if fn := b.Func.cabi; fn != nil {
+		b.cabiReturn(fn, v)
+		return
+	}
+	b.impl.CreateRet(v)
+}
+
 // The Extract instruction yields component Index of Tuple.
 //
 // This is used to access the results of instructions with multiple
@@ -17,6 +17,7 @@
 package ssa
 
 import (
+	"os"
 	"runtime"
 
 	"github.com/goplus/llvm"
@@ -28,9 +29,31 @@ type Target struct {
 	GOOS   string
 	GOARCH string
 	GOARM  string // "5", "6", "7" (default)
+
+	// Triple, CPU and Features override the ones derived from GOOS and
+	// GOARCH if they are not empty.
+	Triple   string
+	CPU      string
+	Features string
+
+	// RelocModel is "static", "pic" or "dynamic-no-pic". If it is empty,
+	// position independent code is generated, except for wasm.
+	RelocModel string
 }
 
 func (p *Target) targetData() llvm.TargetData {
+	machine := p.CreateTargetMachine()
+	defer machine.Dispose()
+	return machine.CreateTargetData()
+}
+
+// CreateTargetMachine creates a target machine generating code with the
+// relocation model of the target. The caller must dispose it.
+func (p *Target) CreateTargetMachine() llvm.TargetMachine {
+	return p.createTargetMachine(llvm.CodeGenLevelDefault)
+}
+
+func (p *Target) createTargetMachine(level llvm.CodeGenOptLevel) llvm.TargetMachine {
 	spec := p.Spec()
 	if spec.Triple == "" {
 		spec.Triple = llvm.DefaultTargetTriple()
@@ -39,38 +62,161 @@ func (p *Target) targetData() llvm.TargetData {
 	if err != nil {
 		panic(err)
 	}
-	machine := t.CreateTargetMachine(spec.Triple, spec.CPU, spec.Features, llvm.CodeGenLevelDefault, llvm.RelocDefault, llvm.CodeModelDefault)
-	return machine.CreateTargetData()
+	return t.CreateTargetMachine(spec.Triple, spec.CPU, spec.Features, level, p.relocMode(), llvm.CodeModelDefault)
 }
 
-/*
-func (p *Program) targetMachine() llvm.TargetMachine {
-	if p.tm.C == nil {
-		spec := p.target.toSpec()
-		target, err := llvm.GetTargetFromTriple(spec.triple)
-		if err != nil {
-			panic(err)
+func (p *Target) relocMode() llvm.RelocMode {
+	switch p.RelocModel {
+	case "static":
+		return llvm.RelocStatic
+	case "dynamic-no-pic":
+		return llvm.RelocDynamicNoPic
+	case "pic":
+		return llvm.RelocPIC
+	}
+	if p.GOARCH == "wasm" {
+		return llvm.RelocDefault
+	}
+	return llvm.RelocPIC
+}
+
+// EmitOptions controls how a module is optimized and emitted.
+type EmitOptions struct {
+	// OptLevel is the optimization level: "0", "1", "2", "3", "s" or "z".
+	// If it is empty, no optimization pipeline is run.
+	OptLevel string
+
+	// LTO is "thin" or "full" to emit bitcode prepared for link time
+	// optimization instead of an object file.
+	LTO string
+
+	// PGOInstrument instruments the code to write an LLVM profile when the
+	// program exits.
+	PGOInstrument bool
+
+	// FuncHotness reports whether a function is hot or cold according to
+	// a sample profile. It is called with the symbol names of functions.
+	FuncHotness func(name string) (hot, cold bool)
+}
+
+// passes returns the new pass manager pipeline to run, or "" for none.
+func (p *EmitOptions) passes() string {
+	var pgo string
+	if p.PGOInstrument {
+		pgo = "pgo-instr-gen,instrprof"
+	}
+	level := p.OptLevel
+	if p.LTO != "" && level == "" {
+		level = "2"
+	}
+	var pipeline string
+	switch {
+	case p.LTO == "thin":
+		pipeline = "thinlto-pre-link<O" + level + ">"
+	case p.LTO == "full":
+		pipeline = "lto-pre-link<O" + level + ">"
+	case level != "":
+		pipeline = "default<O" + level + ">"
+	}
+	if pgo == "" || pipeline == "" {
+		return pgo + pipeline
+	}
+	return pgo + "," + pipeline
+}
+
+// annotateHotness marks the functions of mod hot or cold.
+func annotateHotness(ctx llvm.Context, mod llvm.Module, hotness func(name string) (hot, cold bool)) {
+	hotAttr := ctx.CreateEnumAttribute(llvm.AttributeKindID("hot"), 0)
+	coldAttr := ctx.CreateEnumAttribute(llvm.AttributeKindID("cold"), 0)
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if fn.IsDeclaration() {
+			continue
+		}
+		switch hot, cold := hotness(fn.Name()); {
+		case hot:
+			fn.AddFunctionAttr(hotAttr)
+		case cold:
+			fn.AddFunctionAttr(coldAttr)
+		}
+	}
+}
+
+func (p *EmitOptions) codeGenLevel() llvm.CodeGenOptLevel {
+	switch p.OptLevel {
+	case "0":
+		return llvm.CodeGenLevelNone
+	case "1":
+		return llvm.CodeGenLevelLess
+	case "3":
+		return llvm.CodeGenLevelAggressive
+	}
+	return llvm.CodeGenLevelDefault
+}
+
+// EmitObject optimizes the LLVM bitcode in the memory buffer bitcode and
+// compiles it to the object file outFile, or to a bitcode file if opts.LTO is
+// set. It takes the ownership of bitcode. It uses its own LLVM context and
+// target machine, so that modules can be emitted concurrently.
+func (p *Target) EmitObject(bitcode llvm.MemoryBuffer, outFile string, opts EmitOptions) error {
+	ctx := llvm.NewContext()
+	defer ctx.Dispose()
+	mod, err := ctx.ParseIR(bitcode)
+	if err != nil {
+		return err
+	}
+	defer mod.Dispose()
+	machine := p.createTargetMachine(opts.codeGenLevel())
+	defer machine.Dispose()
+	if opts.FuncHotness != nil {
+		annotateHotness(ctx, mod, opts.FuncHotness)
+	}
+	if passes := opts.passes(); passes != "" {
+		pbo := llvm.NewPassBuilderOptions()
+		defer pbo.Dispose()
+		if err = mod.RunPasses(passes, machine, pbo); err != nil {
+			return err
+		}
+	}
+	var buf llvm.MemoryBuffer
+	switch opts.LTO {
+	case "thin":
+		buf = llvm.WriteThinLTOBitcodeToMemoryBuffer(mod)
+	case "full":
+		buf = llvm.WriteBitcodeToMemoryBuffer(mod)
+	default:
+		if buf, err = machine.EmitToMemoryBuffer(mod, llvm.ObjectFile); err != nil {
+			return err
 		}
-		p.tm = target.CreateTargetMachine(
-			spec.triple,
-			spec.cpu,
-			spec.features,
-			llvm.CodeGenLevelDefault,
-			llvm.RelocDefault,
-			llvm.CodeModelDefault,
-		)
-	}
-	return p.tm
+	}
+	defer buf.Dispose()
+	return os.WriteFile(outFile, buf.Bytes(), 0644)
 }
-*/
 
 type TargetSpec struct {
 	Triple   string
 	CPU      string
 	Features string
+
+	// RelocModel is "static", "pic" or "dynamic-no-pic". If it is empty,
+	// position independent code is generated, except for wasm.
+	RelocModel string
 }
 
 func (p *Target) Spec() (spec TargetSpec) {
+	spec = p.defaultSpec()
+	if p.Triple != "" {
+		spec.Triple = p.Triple
+	}
+	if p.CPU != "" {
+		spec.CPU = p.CPU
+	}
+	if p.Features != "" {
+		spec.Features = p.Features
+	}
+	return
+}
+
+func (p *Target) defaultSpec() (spec TargetSpec) {
 	// Configure based on GOOS/GOARCH environment variables (falling back to
 	// runtime.GOOS/runtime.GOARCH), and generate a LLVM target based on it.
 	var llvmarch string
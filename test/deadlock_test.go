@@ -0,0 +1,55 @@
+//go:build llgo
+// +build llgo
+
+package test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+//go:linkname goBlock github.com/goplus/llgo/runtime/internal/runtime.GoBlock
+func goBlock(reason uint8, obj unsafe.Pointer)
+
+//go:linkname goUnblock github.com/goplus/llgo/runtime/internal/runtime.GoUnblock
+func goUnblock()
+
+//go:linkname goReady github.com/goplus/llgo/runtime/internal/runtime.GoReady
+func goReady(obj unsafe.Pointer)
+
+const waitReasonChanReceive = 1
+
+func TestDeadlock(t *testing.T) {
+	if os.Getenv("LLGO_TEST_DEADLOCK") == "1" {
+		<-make(chan int)
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDeadlock$", "-test.timeout=0")
+	cmd.Env = append(os.Environ(), "LLGO_TEST_DEADLOCK=1")
+	out, err := cmd.CombinedOutput()
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 2 {
+		t.Fatalf("deadlocked program: err = %v, output:\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "fatal error: all goroutines are asleep - deadlock!") {
+		t.Fatalf("deadlock not reported:\n%s", out)
+	}
+}
+
+// A goroutine woken up is not blocked anymore, even if it takes long to
+// run: every other goroutine being blocked is no deadlock.
+func TestSlowWakeup(t *testing.T) {
+	var obj int
+	done := make(chan bool)
+	go func() {
+		goBlock(waitReasonChanReceive, unsafe.Pointer(&obj))
+		goReady(unsafe.Pointer(&obj))
+		time.Sleep(500 * time.Millisecond) // woken up, but not scheduled yet
+		goUnblock()
+		done <- true
+	}()
+	<-done
+}
@@ -0,0 +1,107 @@
+//go:build llgo
+// +build llgo
+
+package test
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+//go:noinline
+func stackOf(all bool) string {
+	buf := make([]byte, 8192)
+	return string(buf[:runtime.Stack(buf, all)])
+}
+
+func TestStack(t *testing.T) {
+	s := stackOf(false)
+	if !strings.HasPrefix(s, "goroutine ") || !strings.Contains(s, " [running]:\n") {
+		t.Fatalf("bad stack header:\n%s", s)
+	}
+	for _, fn := range []string{"stackOf", "TestStack"} {
+		if !strings.Contains(s, fn) {
+			t.Fatalf("%s not in stack:\n%s", fn, s)
+		}
+	}
+	if strings.Contains(s, "\n\ngoroutine ") {
+		t.Fatalf("other goroutines in stack:\n%s", s)
+	}
+}
+
+func TestStackAll(t *testing.T) {
+	ch := make(chan int)
+	go func() { <-ch }()
+	defer close(ch)
+	waitGoroutines(t, func(n int) bool { return n > 1 })
+	s := stackOf(true)
+	if !strings.Contains(s, "[chan receive]:\n") || !strings.Contains(s, "created by ") {
+		t.Fatalf("blocked goroutine not in stack:\n%s", s)
+	}
+}
+
+func TestNumGoroutine(t *testing.T) {
+	n := runtime.NumGoroutine()
+	if n < 1 {
+		t.Fatalf("NumGoroutine() = %d", n)
+	}
+	ch := make(chan int)
+	done := make(chan bool)
+	go func() {
+		<-ch
+		done <- true
+	}()
+	waitGoroutines(t, func(m int) bool { return m == n+1 })
+	close(ch)
+	<-done
+	waitGoroutines(t, func(m int) bool { return m == n })
+}
+
+// waitGoroutines waits until ok returns true for the number of goroutines.
+func waitGoroutines(t *testing.T, ok func(n int) bool) {
+	for i := 0; i < 500; i++ {
+		if ok(runtime.NumGoroutine()) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("NumGoroutine() = %d", runtime.NumGoroutine())
+}
+
+func TestSigquit(t *testing.T) {
+	if os.Getenv("LLGO_TEST_SIGQUIT") == "1" {
+		go func() { <-make(chan int) }()
+		os.Stdout.WriteString("ready\n")
+		time.Sleep(time.Minute)
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSigquit$", "-test.timeout=0")
+	cmd.Env = append(os.Environ(), "LLGO_TEST_SIGQUIT=1")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 6)
+	if _, err := stdout.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	cmd.Process.Signal(syscall.SIGQUIT)
+	err = cmd.Wait()
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 2 {
+		t.Fatalf("quit program: err = %v, output:\n%s", err, stderr.String())
+	}
+	out := stderr.String()
+	if !strings.HasPrefix(out, "SIGQUIT: quit\n") || !strings.Contains(out, "TestSigquit") {
+		t.Fatalf("goroutines not dumped:\n%s", out)
+	}
+}
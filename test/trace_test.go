@@ -0,0 +1,60 @@
+//go:build llgo
+// +build llgo
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"runtime/trace"
+	"testing"
+)
+
+type traceEvent struct {
+	Ph   string `json:"ph"`
+	Name string `json:"name"`
+	Tid  int64  `json:"tid"`
+}
+
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan int)
+	go func() {
+		w.Write([]byte("hello"))
+		ch <- 1
+	}()
+	<-ch
+	b := make([]byte, 5)
+	r.Read(b)
+	r.Close()
+	w.Close()
+	trace.Stop()
+
+	var data struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Fatalf("bad trace: %v\n%s", err, buf.Bytes())
+	}
+	names := make(map[string]int)
+	for _, ev := range data.TraceEvents {
+		names[ev.Name]++
+	}
+	for _, name := range []string{"syscall", "go", "chan send", "chan recv"} {
+		if names[name] == 0 {
+			t.Errorf("no %q event in trace:\n%s", name, buf.Bytes())
+		}
+	}
+	// the write and the read on the pipe
+	if names["syscall"] < 2 {
+		t.Errorf("%d syscall events, want at least 2", names["syscall"])
+	}
+}
@@ -31,10 +31,14 @@ type Loc struct {
 	Col          int           `json:"col,omitempty"`
 	TokLen       int           `json:"tokLen,omitempty"`
 	IncludedFrom *IncludedFrom `json:"includedFrom,omitempty"` // "sqlite3.c"
+	SpellingLoc  *Loc          `json:"spellingLoc,omitempty"`
+	ExpansionLoc *Loc          `json:"expansionLoc,omitempty"`
 }
 
 type Pos struct {
 	Offset       int64         `json:"offset,omitempty"`
+	File         string        `json:"file,omitempty"`
+	Line         int           `json:"line,omitempty"`
 	Col          int           `json:"col,omitempty"`
 	TokLen       int           `json:"tokLen,omitempty"`
 	IncludedFrom *IncludedFrom `json:"includedFrom,omitempty"` // "sqlite3.c"
@@ -145,6 +149,17 @@ const (
 	AllocAlignAttr           Kind = "AllocAlignAttr"
 	DisableTailCallsAttr     Kind = "DisableTailCallsAttr"
 	StaticAssertDecl         Kind = "StaticAssertDecl"
+	FullComment              Kind = "FullComment"
+	ParagraphComment         Kind = "ParagraphComment"
+	TextComment              Kind = "TextComment"
+	CXXRecordDecl            Kind = "CXXRecordDecl"
+	CXXMethodDecl            Kind = "CXXMethodDecl"
+	CXXConstructorDecl       Kind = "CXXConstructorDecl"
+	CXXDestructorDecl        Kind = "CXXDestructorDecl"
+	AccessSpecDecl           Kind = "AccessSpecDecl"
+	LinkageSpecDecl          Kind = "LinkageSpecDecl"
+	NamespaceDecl            Kind = "NamespaceDecl"
+	OverrideAttr             Kind = "OverrideAttr"
 )
 
 type ValueCategory string
@@ -219,6 +234,20 @@ type Type struct {
 	TypeAliasDeclID   ID     `json:"typeAliasDeclId,omitempty"`
 }
 
+// Base is a base class of a C++ class.
+type Base struct {
+	Access    string `json:"access,omitempty"` // public | protected | private
+	IsVirtual bool   `json:"isVirtual,omitempty"`
+	Type      *Type  `json:"type,omitempty"`
+}
+
+// DefData describes the definition of a C++ class.
+type DefData struct {
+	IsPolymorphic bool `json:"isPolymorphic,omitempty"` // has virtual methods
+	IsAbstract    bool `json:"isAbstract,omitempty"`    // has pure virtual methods
+	IsEmpty       bool `json:"isEmpty,omitempty"`
+}
+
 type Node struct {
 	ID                   ID            `json:"id,omitempty"`
 	Kind                 Kind          `json:"kind,omitempty"`
@@ -236,7 +265,15 @@ type Node struct {
 	IsBitfield           bool          `json:"isBitfield,omitempty"`
 	Inline               bool          `json:"inline,omitempty"`
 	StorageClass         StorageClass  `json:"storageClass,omitempty"`
-	TagUsed              string        `json:"tagUsed,omitempty"` // struct | union
+	TagUsed              string        `json:"tagUsed,omitempty"` // struct | union | class
+	Access               string        `json:"access,omitempty"`  // public | protected | private
+	Virtual              bool          `json:"virtual,omitempty"`
+	Pure                 bool          `json:"pure,omitempty"` // pure virtual
+	ExplicitlyDeleted    bool          `json:"explicitlyDeleted,omitempty"`
+	Bases                []*Base       `json:"bases,omitempty"`
+	DefinitionData       *DefData      `json:"definitionData,omitempty"`
+	FixedUnderlyingType  *Type         `json:"fixedUnderlyingType,omitempty"`
+	ScopedEnumTag        string        `json:"scopedEnumTag,omitempty"` // class | struct
 	HasElse              bool          `json:"hasElse,omitempty"`
 	CompleteDefinition   bool          `json:"completeDefinition,omitempty"`
 	Complicated          bool          `json:"-"` // complicated statement
@@ -253,6 +290,7 @@ type Node struct {
 	Init                 string        `json:"init,omitempty"`
 	ValueCategory        ValueCategory `json:"valueCategory,omitempty"`
 	Value                interface{}   `json:"value,omitempty"`
+	Text                 string        `json:"text,omitempty"` // text of a comment
 	CastKind             CastKind      `json:"castKind,omitempty"`
 	Size                 int           `json:"size,omitempty"` // array size
 	Inner                []*Node       `json:"inner,omitempty"`
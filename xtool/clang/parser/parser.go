@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package parser parses C sources into the AST clang dumps as JSON.
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/goplus/llgo/xtool/clang"
+	"github.com/goplus/llgo/xtool/clang/ast"
+)
+
+// -----------------------------------------------------------------------------
+
+// ParseError is the error of a clang command which failed.
+type ParseError struct {
+	Err    error
+	Stderr []byte
+}
+
+func (p *ParseError) Error() string {
+	if len(p.Stderr) > 0 {
+		return string(p.Stderr)
+	}
+	return p.Err.Error()
+}
+
+// -----------------------------------------------------------------------------
+
+// Config is the configuration of DumpAST and ParseFile.
+type Config struct {
+	Clang *clang.Cmd // clang command, clang in PATH if nil
+	Flags []string   // flags of clang, such as -I, -D or -x
+}
+
+func (conf *Config) exec(args ...string) ([]byte, error) {
+	var cmd clang.Cmd
+	if conf.Clang != nil {
+		cmd = *conf.Clang
+	} else {
+		cmd = *clang.New("")
+	}
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Exec(append(conf.Flags[:len(conf.Flags):len(conf.Flags)], args...)...); err != nil {
+		return nil, &ParseError{Err: err, Stderr: stderr.Bytes()}
+	}
+	return stdout.Bytes(), nil
+}
+
+// DumpAST returns the AST of filename as JSON, with its comments.
+func DumpAST(filename string, conf *Config) ([]byte, error) {
+	if conf == nil {
+		conf = new(Config)
+	}
+	return conf.exec("-Xclang", "-ast-dump=json", "-fsyntax-only", "-fparse-all-comments", filename)
+}
+
+// DumpMacros returns filename preprocessed with its #define and #undef
+// directives kept, and the line markers of the files it includes.
+func DumpMacros(filename string, conf *Config) ([]byte, error) {
+	if conf == nil {
+		conf = new(Config)
+	}
+	return conf.exec("-E", "-dD", filename)
+}
+
+// ParseFile returns the AST of filename, a TranslationUnitDecl node.
+func ParseFile(filename string, conf *Config) (file *ast.Node, err error) {
+	out, err := DumpAST(filename, conf)
+	if err != nil {
+		return
+	}
+	file = new(ast.Node)
+	if err = json.Unmarshal(out, file); err != nil {
+		err = &ParseError{Err: err}
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------